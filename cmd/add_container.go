@@ -155,8 +155,8 @@ func generateWorkflow(c *ContainerCmdArgs, containerName, image string, overwrit
 	} else {
 		pipeline := unstructured.Unstructured{
 			Object: map[string]interface{}{
-				"apiVersion": "platform.kratix.io/v1alpha1",
-				"kind":       "Pipeline",
+				"apiVersion": kratixGroupVersion(),
+				"kind":       kratixPipelineKind,
 				"metadata": map[string]interface{}{
 					"name": c.Pipeline,
 				},
@@ -256,8 +256,8 @@ func pipelinesToUnstructured(pipelines []v1alpha1.Pipeline) ([]unstructured.Unst
 		if err != nil {
 			return nil, err
 		}
-		pMap["kind"] = "Pipeline"
-		pMap["apiVersion"] = "platform.kratix.io/v1alpha1"
+		pMap["kind"] = kratixPipelineKind
+		pMap["apiVersion"] = kratixGroupVersion()
 		pUnstructured := unstructured.Unstructured{Object: pMap}
 		pipelinesUnstructured = append(pipelinesUnstructured, pUnstructured)
 	}
@@ -165,8 +165,14 @@ func LoadWorkflows(dir string) (v1alpha1.Workflows, error) {
 	missingWorkflows := 0
 	for _, lifecycle := range []string{"promise", "resource"} {
 		for _, action := range []string{"configure", "delete"} {
-			if fileExists(filepath.Join(dir, "workflows", lifecycle, action, "workflow.yaml")) {
-				workflowBytes, err := os.ReadFile(filepath.Join(dir, "workflows", lifecycle, action, "workflow.yaml"))
+			// the workflows of the flat layout of init operator-promise
+			// are at the root of the directory
+			workflowPath := filepath.Join(dir, "workflows", lifecycle, action, "workflow.yaml")
+			if !fileExists(workflowPath) {
+				workflowPath = filepath.Join(dir, flatWorkflowFileName(lifecycle, action))
+			}
+			if fileExists(workflowPath) {
+				workflowBytes, err := os.ReadFile(workflowPath)
 				if err != nil {
 					return workflows, err
 				}
@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+//go:embed templates/helm/*
+var helmChartTemplates embed.FS
+
+var buildHelmChartCmd = &cobra.Command{
+	Use:   "helm-chart [PROMISE-NAME]",
+	Short: "Command to wrap a Kratix Promise in a Helm chart",
+	Long: `Command to wrap a Kratix Promise in a minimal Helm chart, so the Promise can be
+delivered by Helm.
+
+The Promise is built from promise.yaml, or from the api, dependencies,
+required promises and workflow files of a Promise initialized with --split,
+and written to templates/promise.yaml of the chart. The image of each workflow
+container is templated in, and can be overridden in values.yaml by the
+container name:
+
+  images:
+    configure-resource:
+      repository: registry.example.com/configure-resource
+      tag: v1.0.0
+
+The Promise name, and the name of the chart, defaults to the name in
+promise.yaml, or otherwise to the name of the Promise directory.`,
+	Example: `  # wrap the promise in the current directory in a chart
+  kratix build helm-chart --out path/to/chart
+
+  # wrap a promise initialized with --split in a chart
+  kratix build helm-chart postgresql --dir path/to/promise --out path/to/chart`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: BuildHelmChart,
+}
+
+var helmChartOutputDir string
+
+func init() {
+	buildCmd.AddCommand(buildHelmChartCmd)
+	buildHelmChartCmd.Flags().StringVarP(&inputDir, "dir", "d", ".", "Directory to build promise from. Default to the current working directory")
+	buildHelmChartCmd.Flags().StringVar(&helmChartOutputDir, "out", "", "Directory to write the Helm chart to")
+	buildHelmChartCmd.MarkFlagRequired("out")
+}
+
+const helmChartHelpers = `{{/*
+The image of a workflow container, from its entry in .Values.images.
+*/}}
+{{- define "promise.image" -}}
+{{ .repository }}{{ with .tag }}:{{ . }}{{ end }}
+{{- end -}}
+`
+
+const helmChartImagePlaceholder = "KRATIX_HELM_CHART_IMAGE_"
+
+type helmChartImage struct {
+	Repository string `json:"repository"`
+	Tag        string `json:"tag,omitempty"`
+}
+
+type helmChartValues struct {
+	Images map[string]helmChartImage `json:"images"`
+}
+
+func BuildHelmChart(cmd *cobra.Command, args []string) error {
+	promise, err := assemblePromise(inputDir)
+	if err != nil {
+		return err
+	}
+
+	if len(args) > 0 {
+		promise.Name = args[0]
+	}
+	if promise.Name == "" {
+		absDir, err := filepath.Abs(inputDir)
+		if err != nil {
+			return err
+		}
+		promise.Name = filepath.Base(absDir)
+	}
+
+	values := helmChartValues{Images: map[string]helmChartImage{}}
+	for _, pipelines := range [][]unstructured.Unstructured{
+		promise.Spec.Workflows.Promise.Configure,
+		promise.Spec.Workflows.Promise.Delete,
+		promise.Spec.Workflows.Resource.Configure,
+		promise.Spec.Workflows.Resource.Delete,
+	} {
+		for _, pipeline := range pipelines {
+			if err := templatePipelineImages(pipeline, values.Images); err != nil {
+				return err
+			}
+		}
+	}
+
+	promiseBytes, err := yaml.Marshal(promise)
+	if err != nil {
+		return err
+	}
+	promiseTemplate := templateHelmChartImages(escapeHelmTemplate(string(promiseBytes)), values.Images)
+
+	valuesBytes, err := yaml.Marshal(values)
+	if err != nil {
+		return err
+	}
+
+	appVersion := promise.GetLabels()["kratix.io/promise-version"]
+	if appVersion == "" {
+		appVersion = "v0.0.1"
+	}
+	templateValues := struct{ Name, AppVersion string }{promise.Name, appVersion}
+	if err := templateFiles(helmChartTemplates, helmChartOutputDir, map[string]string{"Chart.yaml": "templates/helm/Chart.yaml.tpl"}, templateValues); err != nil {
+		return err
+	}
+
+	templatesDir := filepath.Join(helmChartOutputDir, "templates")
+	if err := os.MkdirAll(templatesDir, os.ModePerm); err != nil {
+		return err
+	}
+	files := map[string]string{
+		filepath.Join(helmChartOutputDir, "values.yaml"): string(valuesBytes),
+		filepath.Join(templatesDir, "_helpers.tpl"):      helmChartHelpers,
+		filepath.Join(templatesDir, "promise.yaml"):      promiseTemplate,
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), filePerm); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Helm chart for the %s Promise written to %s\n", promise.Name, helmChartOutputDir)
+	return nil
+}
+
+// templatePipelineImages swaps the image of each container in the pipeline for
+// a placeholder, and records the image in images by the container name. A
+// container name used with a different image in another pipeline is prefixed
+// with the pipeline name.
+func templatePipelineImages(pipeline unstructured.Unstructured, images map[string]helmChartImage) error {
+	containers, found, err := unstructured.NestedSlice(pipeline.Object, "spec", "containers")
+	if err != nil || !found {
+		return err
+	}
+
+	for i, c := range containers {
+		container, ok := c.(map[string]any)
+		if !ok {
+			continue
+		}
+		name, _ := container["name"].(string)
+		image, _ := container["image"].(string)
+		if image == "" {
+			continue
+		}
+
+		chartImage := splitImage(image)
+		key := name
+		if existing, ok := images[key]; ok && existing != chartImage {
+			key = pipeline.GetName() + "-" + name
+		}
+		images[key] = chartImage
+		container["image"] = helmChartImagePlaceholder + key
+		containers[i] = container
+	}
+	return unstructured.SetNestedSlice(pipeline.Object, containers, "spec", "containers")
+}
+
+// splitImage splits the tag from an image; an image with a digest is kept whole
+// as the repository.
+func splitImage(image string) helmChartImage {
+	if strings.Contains(image, "@") {
+		return helmChartImage{Repository: image}
+	}
+	lastSlash := strings.LastIndex(image, "/")
+	if i := strings.LastIndex(image, ":"); i > lastSlash {
+		return helmChartImage{Repository: image[:i], Tag: image[i+1:]}
+	}
+	return helmChartImage{Repository: image}
+}
+
+// escapeHelmTemplate escapes template actions already in the Promise, e.g. in
+// dependencies, so Helm renders them as is.
+func escapeHelmTemplate(manifest string) string {
+	return strings.ReplaceAll(manifest, "{{", `{{ "{{" }}`)
+}
+
+func templateHelmChartImages(manifest string, images map[string]helmChartImage) string {
+	keys := make([]string, 0, len(images))
+	for key := range images {
+		keys = append(keys, key)
+	}
+	// longest first, so a key is not replaced within a longer key it prefixes
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+
+	for _, key := range keys {
+		manifest = strings.ReplaceAll(manifest, helmChartImagePlaceholder+key,
+			fmt.Sprintf(`{{ include "promise.image" (index .Values.images %q) | quote }}`, key))
+	}
+	return manifest
+}
@@ -0,0 +1,20 @@
+package cmd_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/syntasso/kratix-cli/cmd"
+)
+
+var _ = Describe("BuildHelmChart", func() {
+	DescribeTable("SplitImage",
+		func(image string, expected HelmChartImage) {
+			Expect(SplitImage(image)).To(Equal(expected))
+		},
+		Entry("with a tag", "ghcr.io/syntasso/configure:v1.0.0", HelmChartImage{Repository: "ghcr.io/syntasso/configure", Tag: "v1.0.0"}),
+		Entry("without a tag", "ghcr.io/syntasso/configure", HelmChartImage{Repository: "ghcr.io/syntasso/configure"}),
+		Entry("with a registry port and no tag", "localhost:5000/configure", HelmChartImage{Repository: "localhost:5000/configure"}),
+		Entry("with a registry port and a tag", "localhost:5000/configure:dev", HelmChartImage{Repository: "localhost:5000/configure", Tag: "dev"}),
+		Entry("with a digest", "ghcr.io/syntasso/configure@sha256:abc", HelmChartImage{Repository: "ghcr.io/syntasso/configure@sha256:abc"}),
+	)
+})
@@ -17,7 +17,7 @@ import (
 var buildPromiseCmd = &cobra.Command{
 	Use:   "promise PROMISE-NAME",
 	Short: "Command to build a Kratix Promise",
-	Long:  "Command to build a Kratix Promise from given api, dependencies and workflow files in a directory. Use this command if you initialized your Promise with `--split`.",
+	Long:  "Command to build a Kratix Promise from given api, dependencies, required promises and workflow files in a directory. Use this command if you initialized your Promise with `--split`.",
 	Example: `  # build a promise from path
   kratix build promise postgresql --dir ~/path/to/promise-bundle/`,
 	Args: cobra.ExactArgs(1),
@@ -33,72 +33,114 @@ func init() {
 }
 
 func BuildPromise(cmd *cobra.Command, args []string) error {
-	promiseName := args[0]
-	promise, err := LoadPromiseWithWorkflows(inputDir)
+	promise, err := assemblePromise(inputDir)
 	if err != nil {
 		return err
 	}
-	promise.Kind = "Promise"
-	promise.APIVersion = v1alpha1.GroupVersion.Group + "/" + v1alpha1.GroupVersion.Version
-	promise.Name = promiseName
+	promise.Name = args[0]
 
-	if _, err := os.Stat(filepath.Join(inputDir, apiFileName)); err == nil {
+	promiseBytes, err := yaml.Marshal(promise)
+	if err != nil {
+		return err
+	}
+
+	if outputPath != "" {
+		return os.WriteFile(outputPath, promiseBytes, filePerm)
+	}
+
+	fmt.Println(string(promiseBytes))
+	return nil
+}
+
+// assemblePromise reads the Promise in dir, combining the api, dependencies,
+// required promises and workflow files of a Promise initialized with --split.
+// Dependencies split by scope are combined cluster-scoped first.
+func assemblePromise(dir string) (*v1alpha1.Promise, error) {
+	promise, err := LoadPromiseWithWorkflows(dir)
+	if err != nil {
+		return nil, err
+	}
+	promise.Kind = kratixPromiseKind
+	promise.APIVersion = kratixGroupVersion()
+
+	if _, err := os.Stat(filepath.Join(dir, apiFileName)); err == nil {
 		var apiBytes []byte
-		apiBytes, err = os.ReadFile(filepath.Join(inputDir, apiFileName))
+		apiBytes, err = os.ReadFile(filepath.Join(dir, apiFileName))
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		if len(apiBytes) > 0 {
 			var crd apiextensionsv1.CustomResourceDefinition
 			err = yaml.Unmarshal(apiBytes, &crd)
 			if err != nil {
-				return err
+				return nil, err
 			}
 
 			var crdBytes []byte
 			crdBytes, err = json.Marshal(crd)
 			if err != nil {
-				return err
+				return nil, err
 			}
 
 			promise.Spec.API = &runtime.RawExtension{Raw: crdBytes}
 		}
 	}
 
-	if _, err := os.Stat(filepath.Join(inputDir, dependenciesFileName)); err == nil {
+	if _, err := os.Stat(filepath.Join(dir, dependenciesFileName)); err == nil {
 		var dependencyBytes []byte
-		dependencyBytes, err = os.ReadFile(filepath.Join(inputDir, dependenciesFileName))
+		dependencyBytes, err = os.ReadFile(filepath.Join(dir, dependenciesFileName))
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		var dependencies v1alpha1.Dependencies
 		err = yaml.Unmarshal(dependencyBytes, &dependencies)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		promise.Spec.Dependencies = dependencies
 	}
 
-	promiseBytes, err := yaml.Marshal(promise)
-	if err != nil {
-		return err
+	for _, fileName := range []string{clusterDependenciesFileName, namespacedDependenciesFileName} {
+		if _, err := os.Stat(filepath.Join(dir, fileName)); err != nil {
+			continue
+		}
+		dependencyBytes, err := os.ReadFile(filepath.Join(dir, fileName))
+		if err != nil {
+			return nil, err
+		}
+
+		var dependencies v1alpha1.Dependencies
+		if err := yaml.Unmarshal(dependencyBytes, &dependencies); err != nil {
+			return nil, err
+		}
+		promise.Spec.Dependencies = append(promise.Spec.Dependencies, dependencies...)
 	}
 
-	if outputPath != "" {
-		return os.WriteFile(outputPath, promiseBytes, filePerm)
+	if _, err := os.Stat(filepath.Join(dir, requiredPromisesFileName)); err == nil {
+		var requiredPromisesBytes []byte
+		requiredPromisesBytes, err = os.ReadFile(filepath.Join(dir, requiredPromisesFileName))
+		if err != nil {
+			return nil, err
+		}
+
+		var requiredPromises []v1alpha1.RequiredPromise
+		err = yaml.Unmarshal(requiredPromisesBytes, &requiredPromises)
+		if err != nil {
+			return nil, err
+		}
+		promise.Spec.RequiredPromises = requiredPromises
 	}
 
-	fmt.Println(string(promiseBytes))
-	return nil
+	return promise, nil
 }
 
 func newPromise(promiseName string) v1alpha1.Promise {
 	return v1alpha1.Promise{
 		TypeMeta: metav1.TypeMeta{
-			Kind:       "Promise",
-			APIVersion: v1alpha1.GroupVersion.Group + "/" + v1alpha1.GroupVersion.Version,
+			Kind:       kratixPromiseKind,
+			APIVersion: kratixGroupVersion(),
 		},
 		ObjectMeta: metav1.ObjectMeta{
 			Name: promiseName,
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/syntasso/kratix/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+)
+
+var buildPromiseReleaseCmd = &cobra.Command{
+	Use:   "promise-release PROMISE-NAME",
+	Short: "Command to build a Kratix PromiseRelease",
+	Long: `Command to build a Kratix PromiseRelease, installing the Promise hosted at --url.
+
+The version must match the kratix.io/promise-version label of the hosted
+Promise, or Kratix refuses to install it. --type sets the type of the source
+of the Promise; the Kratix PromiseRelease API only accepts http sources.`,
+	Example: `  # build a PromiseRelease for a Promise hosted on GitHub
+  kratix build promise-release postgresql --url https://github.com/myorg/promises/releases/download/v1.0.0/promise.yaml --version v1.0.0`,
+	Args: cobra.ExactArgs(1),
+	RunE: BuildPromiseRelease,
+}
+
+// supportedSourceRefTypes lists the values of --type, as accepted by the
+// sourceRef of the Kratix PromiseRelease API.
+var supportedSourceRefTypes = []string{v1alpha1.TypeHTTP}
+
+var releaseURL, releaseVersion, releaseSourceType string
+
+func init() {
+	buildCmd.AddCommand(buildPromiseReleaseCmd)
+	buildPromiseReleaseCmd.Flags().StringVar(&releaseURL, "url", "", "URL the Promise is hosted at")
+	buildPromiseReleaseCmd.Flags().StringVar(&releaseVersion, "version", "", "Version of the Promise, matching its kratix.io/promise-version label")
+	buildPromiseReleaseCmd.Flags().StringVar(&releaseSourceType, "type", v1alpha1.TypeHTTP, "Type of the source of the Promise. One of: "+strings.Join(supportedSourceRefTypes, ", "))
+	buildPromiseReleaseCmd.Flags().StringVarP(&outputPath, "output", "o", "", "File path to write the PromiseRelease to. Default to output to stdout")
+	buildPromiseReleaseCmd.MarkFlagRequired("url")
+	buildPromiseReleaseCmd.MarkFlagRequired("version")
+}
+
+func BuildPromiseRelease(cmd *cobra.Command, args []string) error {
+	release, err := promiseRelease(args[0], releaseURL, releaseVersion, releaseSourceType)
+	if err != nil {
+		return err
+	}
+
+	releaseBytes, err := yaml.Marshal(release.Object)
+	if err != nil {
+		return err
+	}
+
+	if outputPath != "" {
+		return os.WriteFile(outputPath, releaseBytes, filePerm)
+	}
+
+	fmt.Println(string(releaseBytes))
+	return nil
+}
+
+// promiseRelease generates a PromiseRelease installing version of the Promise
+// hosted at sourceURL.
+func promiseRelease(name, sourceURL, version, sourceType string) (*unstructured.Unstructured, error) {
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid PromiseRelease name %q: %s", name, strings.Join(errs, ", "))
+	}
+	if !slices.Contains(supportedSourceRefTypes, sourceType) {
+		return nil, fmt.Errorf("invalid --type %q: must be one of %s", sourceType, strings.Join(supportedSourceRefTypes, ", "))
+	}
+	parsedURL, err := url.Parse(sourceURL)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+		return nil, fmt.Errorf("invalid --url %q: must be an http or https URL", sourceURL)
+	}
+	if version == "" {
+		return nil, fmt.Errorf("invalid --version: must not be empty")
+	}
+	if errs := validation.IsValidLabelValue(version); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid --version %q: %s", version, strings.Join(errs, ", "))
+	}
+
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": kratixGroupVersion(),
+		"kind":       kratixPromiseReleaseKind,
+		"metadata": map[string]any{
+			"name": name,
+		},
+		"spec": map[string]any{
+			"version": version,
+			"sourceRef": map[string]any{
+				"type": sourceType,
+				"url":  sourceURL,
+			},
+		},
+	}}, nil
+}
@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/syntasso/kratix/api/v1alpha1"
+	"gopkg.in/yaml.v3"
+	yamlsig "sigs.k8s.io/yaml"
+)
+
+// readManifestComments parses the manifests a second time, keeping the YAML
+// nodes, so their comments can be transplanted onto the generated
+// dependencies. The nodes are indexed by manifestKey. A manifest found under
+// several paths keeps the node of the first one, as buildDependencies does.
+func readManifestComments(paths ...string) (map[string]*yaml.Node, error) {
+	nodes := map[string]*yaml.Node{}
+	for _, path := range paths {
+		pathNodes := map[string]*yaml.Node{}
+		err := filepath.WalkDir(path, func(filePath string, entry os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() || !isYAML(filePath) {
+				return nil
+			}
+			return readFileComments(filePath, pathNodes)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the manifest comments: %w", err)
+		}
+		for key, node := range pathNodes {
+			if _, found := nodes[key]; !found {
+				nodes[key] = node
+			}
+		}
+	}
+	return nodes, nil
+}
+
+func readFileComments(filePath string, nodes map[string]*yaml.Node) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	for {
+		var document yaml.Node
+		err := decoder.Decode(&document)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to decode %s: %w", filePath, err)
+		}
+		if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+			continue
+		}
+
+		root := document.Content[0]
+		if root.HeadComment == "" {
+			root.HeadComment = document.HeadComment
+		}
+		nodes[manifestKey(
+			scalarValue(root, "kind"),
+			scalarValue(mappingValue(root, "metadata"), "namespace"),
+			scalarValue(mappingValue(root, "metadata"), "name"),
+		)] = root
+	}
+}
+
+// manifestKey identifies a manifest. Manifests without a namespace are
+// ingested in the default namespace, so both share the same key. The
+// apiVersion is left out, as --rewrite-apiversion may have changed it.
+func manifestKey(kind, namespace, name string) string {
+	if namespace == "" {
+		namespace = "default"
+	}
+	return fmt.Sprintf("%s/%s/%s", kind, namespace, name)
+}
+
+// marshalDependenciesWithComments marshals the dependencies like
+// writePromiseFiles does, then copies the comments of the original manifests
+// onto the matching nodes. Dependencies added by the CLI have no comments.
+func marshalDependenciesWithComments(dependencies []v1alpha1.Dependency, comments map[string]*yaml.Node) ([]byte, error) {
+	depBytes, err := yamlsig.Marshal(dependencies)
+	if err != nil {
+		return nil, err
+	}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(depBytes, &document); err != nil {
+		return nil, err
+	}
+
+	if len(document.Content) == 1 && document.Content[0].Kind == yaml.SequenceNode {
+		for i, item := range document.Content[0].Content {
+			dep := dependencies[i]
+			if original, found := comments[manifestKey(dep.GetKind(), dep.GetNamespace(), dep.GetName())]; found {
+				copyComments(original, item)
+			}
+		}
+	}
+
+	output := bytes.NewBuffer(nil)
+	encoder := yaml.NewEncoder(output)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&document); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return output.Bytes(), nil
+}
+
+// copyComments copies the comments of src onto dst, recursing into the
+// mapping values with the same key and the sequence items at the same index.
+func copyComments(src, dst *yaml.Node) {
+	if src.Kind == yaml.AliasNode {
+		src = src.Alias
+	}
+
+	dst.HeadComment = src.HeadComment
+	dst.LineComment = src.LineComment
+	dst.FootComment = src.FootComment
+
+	switch {
+	case src.Kind == yaml.MappingNode && dst.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(dst.Content); i += 2 {
+			for j := 0; j+1 < len(src.Content); j += 2 {
+				if src.Content[j].Value == dst.Content[i].Value {
+					copyComments(src.Content[j], dst.Content[i])
+					copyComments(src.Content[j+1], dst.Content[i+1])
+					break
+				}
+			}
+		}
+	case src.Kind == yaml.SequenceNode && dst.Kind == yaml.SequenceNode:
+		for i := 0; i < len(dst.Content) && i < len(src.Content); i++ {
+			copyComments(src.Content[i], dst.Content[i])
+		}
+	}
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func scalarValue(node *yaml.Node, key string) string {
+	if value := mappingValue(node, key); value != nil {
+		return value.Value
+	}
+	return ""
+}
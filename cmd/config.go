@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	yamlsig "sigs.k8s.io/yaml"
+)
+
+const configFileName = "kratix.yaml"
+
+var configFile string
+
+// loadConfig reads the defaults of the flags from the --config file, or from
+// kratix.yaml in the current directory when it exists. The keys are flag
+// names, e.g. group or operator-namespace.
+func loadConfig(path string) (map[string]any, error) {
+	if path == "" {
+		if _, err := os.Stat(configFileName); err != nil {
+			return nil, nil
+		}
+		path = configFileName
+	}
+
+	configBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var config map[string]any
+	if err := yamlsig.Unmarshal(configBytes, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: expected a mapping of flag names to values", path)
+	}
+	return config, nil
+}
+
+// applyConfig sets the flags of cmd not set on the command line to their value
+// in config, so flags take precedence over the config file, and the config file
+// over the built-in defaults. A list sets a repeatable flag once per item, and
+// a mapping once per KEY=VALUE. Keys that are not flags of cmd are ignored, as
+// the config file is shared by every command.
+func applyConfig(cmd *cobra.Command, config map[string]any) error {
+	keys := make([]string, 0, len(config))
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		flag := cmd.Flags().Lookup(key)
+		if flag == nil {
+			logVerbose("Ignoring %s in the config file: not a flag of %s", key, cmd.CommandPath())
+			continue
+		}
+		if flag.Changed {
+			continue
+		}
+		for _, value := range configValues(config[key]) {
+			if err := setConfigFlag(cmd.Flags(), flag, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func configValues(value any) []string {
+	switch typed := value.(type) {
+	case []any:
+		var values []string
+		for _, item := range typed {
+			values = append(values, configScalar(item))
+		}
+		return values
+	case map[string]any:
+		var values []string
+		for key, item := range typed {
+			values = append(values, fmt.Sprintf("%s=%s", key, configScalar(item)))
+		}
+		sort.Strings(values)
+		return values
+	default:
+		return []string{configScalar(typed)}
+	}
+}
+
+// configScalar formats a scalar of the config file as a flag value. Numbers
+// are decoded as float64, which fmt prints in exponent form from 1e6 up,
+// e.g. 2e+06, so they are formatted without an exponent.
+func configScalar(value any) string {
+	if number, ok := value.(float64); ok {
+		return strconv.FormatFloat(number, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+func setConfigFlag(flags *pflag.FlagSet, flag *pflag.Flag, value string) error {
+	if err := flags.Set(flag.Name, value); err != nil {
+		return fmt.Errorf("invalid %s %q in the config file: %s", flag.Name, value, strings.TrimPrefix(err.Error(), flag.Name+": "))
+	}
+	return nil
+}
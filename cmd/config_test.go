@@ -0,0 +1,101 @@
+package cmd_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/cobra"
+	. "github.com/syntasso/kratix-cli/cmd"
+)
+
+var _ = Describe("Config", func() {
+	var (
+		cmd                  *cobra.Command
+		group, kind          string
+		force                bool
+		maxBytes             int
+		selectors, setValues []string
+	)
+
+	BeforeEach(func() {
+		cmd = &cobra.Command{Use: "test"}
+		cmd.Flags().StringVar(&group, "group", "default.com", "")
+		cmd.Flags().StringVar(&kind, "kind", "", "")
+		cmd.Flags().BoolVar(&force, "force", false, "")
+		cmd.Flags().StringArrayVar(&selectors, "destination-selector", nil, "")
+		cmd.Flags().StringArrayVar(&setValues, "set", []string{"built-in=default"}, "")
+		cmd.Flags().IntVar(&maxBytes, "max-dependencies-bytes", 0, "")
+	})
+
+	Describe("ApplyConfig", func() {
+		It("takes the built-in defaults without a config", func() {
+			Expect(cmd.ParseFlags(nil)).To(Succeed())
+			Expect(ApplyConfig(cmd, nil)).To(Succeed())
+			Expect(group).To(Equal("default.com"))
+			Expect(setValues).To(Equal([]string{"built-in=default"}))
+		})
+
+		It("takes the config over the built-in defaults", func() {
+			Expect(cmd.ParseFlags(nil)).To(Succeed())
+			Expect(ApplyConfig(cmd, map[string]any{
+				"group":                "config.com",
+				"force":                true,
+				"destination-selector": map[string]any{"zone": "eu", "env": "dev"},
+				"set":                  []any{"a=1", "b=2"},
+			})).To(Succeed())
+			Expect(group).To(Equal("config.com"))
+			Expect(force).To(BeTrue())
+			Expect(selectors).To(Equal([]string{"env=dev", "zone=eu"}))
+			Expect(setValues).To(Equal([]string{"a=1", "b=2"}))
+		})
+
+		It("takes the flags over the config", func() {
+			Expect(cmd.ParseFlags([]string{"--group", "flag.com", "--set", "c=3"})).To(Succeed())
+			Expect(ApplyConfig(cmd, map[string]any{
+				"group": "config.com",
+				"kind":  "Database",
+				"set":   []any{"a=1"},
+			})).To(Succeed())
+			Expect(group).To(Equal("flag.com"))
+			Expect(kind).To(Equal("Database"))
+			Expect(setValues).To(Equal([]string{"c=3"}))
+		})
+
+		It("takes the large integers of the config", func() {
+			Expect(cmd.ParseFlags(nil)).To(Succeed())
+			Expect(ApplyConfig(cmd, map[string]any{
+				"max-dependencies-bytes": float64(2000000),
+				"set":                    []any{float64(3000000)},
+			})).To(Succeed())
+			Expect(maxBytes).To(Equal(2000000))
+			Expect(setValues).To(Equal([]string{"3000000"}))
+		})
+
+		It("ignores the keys that are not flags of the command", func() {
+			Expect(cmd.ParseFlags(nil)).To(Succeed())
+			Expect(ApplyConfig(cmd, map[string]any{"image": "myorg/image:v1"})).To(Succeed())
+		})
+
+		It("errors on an invalid value", func() {
+			Expect(cmd.ParseFlags(nil)).To(Succeed())
+			Expect(ApplyConfig(cmd, map[string]any{"force": "sometimes"})).To(MatchError(
+				ContainSubstring(`invalid force "sometimes" in the config file`)))
+		})
+	})
+
+	Describe("LoadConfig", func() {
+		It("errors when the config file is not a mapping", func() {
+			path := filepath.Join(GinkgoT().TempDir(), "kratix.yaml")
+			Expect(os.WriteFile(path, []byte("- group\n"), 0644)).To(Succeed())
+			_, err := LoadConfig(path)
+			Expect(err).To(MatchError(ContainSubstring("expected a mapping of flag names to values")))
+		})
+
+		It("errors when the given config file does not exist", func() {
+			_, err := LoadConfig(filepath.Join(GinkgoT().TempDir(), "missing.yaml"))
+			Expect(err).To(MatchError(ContainSubstring("failed to read config file")))
+		})
+	})
+})
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"embed"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+//go:embed templates/controller/*
+var controllerTemplates embed.FS
+
+const controllerScaffoldDir = "controller"
+
+// The versions required by the go.mod of the controller scaffold, matching the
+// ones the CLI is built with.
+const (
+	scaffoldAPIMachineryVersion      = "v0.31.2"
+	scaffoldControllerRuntimeVersion = "v0.19.0"
+)
+
+type controllerScaffoldValues struct {
+	Module                   string
+	Promise                  string
+	CRDName                  string
+	Group                    string
+	Version                  string
+	Kind                     string
+	Plural                   string
+	TypeName                 string
+	APIMachineryVersion      string
+	ControllerRuntimeVersion string
+}
+
+// writeControllerScaffold writes a minimal controller reconciling the
+// resources of the CRD to the controller directory of the output directory,
+// apart from the Promise files.
+func writeControllerScaffold(promiseName, crdName, crdGroup, crdVersion, crdKind, crdPlural string) error {
+	typeName := []rune(crdKind)
+	typeName[0] = unicode.ToUpper(typeName[0])
+
+	values := controllerScaffoldValues{
+		Module:                   "example.com/" + strings.ToLower(promiseName) + "-controller",
+		Promise:                  promiseName,
+		CRDName:                  crdName,
+		Group:                    crdGroup,
+		Version:                  crdVersion,
+		Kind:                     crdKind,
+		Plural:                   crdPlural,
+		TypeName:                 string(typeName),
+		APIMachineryVersion:      scaffoldAPIMachineryVersion,
+		ControllerRuntimeVersion: scaffoldControllerRuntimeVersion,
+	}
+
+	templates := map[string]string{
+		"main.go":    "templates/controller/main.go.tpl",
+		"go.mod":     "templates/controller/go.mod.tpl",
+		"Dockerfile": "templates/controller/Dockerfile.tpl",
+		"README.md":  "templates/controller/README.md.tpl",
+	}
+	return templateFiles(controllerTemplates, filepath.Join(outputDir, controllerScaffoldDir), templates, values)
+}
@@ -0,0 +1,29 @@
+package cmd
+
+// DependencyOptions are the flags rewriting the dependencies read from a
+// directory of manifests. Each command reading dependencies binds its flags to
+// an instance of its own.
+type DependencyOptions struct {
+	DropWebhookConfigs      bool
+	APIVersionRewrites      []string
+	AnnotationsToStrip      []string
+	PreserveHelmAnnotations bool
+	MaxBytes                int
+	NormalizeImages         bool
+	Patches                 string
+	Namespace               string
+	RedactSecrets           bool
+	Transform               string
+	Verify                  bool
+	OrderByKind             bool
+	SyncWavesFile           string
+}
+
+// namespace is the namespace of the dependencies without one: the
+// --operator-namespace, or default.
+func (o *DependencyOptions) namespace() string {
+	if o.Namespace != "" {
+		return o.Namespace
+	}
+	return "default"
+}
@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/syntasso/kratix/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/kubernetes/scheme"
+	yamlsig "sigs.k8s.io/yaml"
+)
+
+// dependencyPatch is an entry of the --dependency-patch file. The patch is
+// either a JSON6902 list of operations or a strategic merge patch, and is
+// applied to every dependency matching the target.
+type dependencyPatch struct {
+	Target dependencyPatchTarget `json:"target"`
+	Patch  string                `json:"patch"`
+
+	operations   jsonpatch.Patch
+	mergePatch   []byte
+	matchedCount int
+}
+
+type dependencyPatchTarget struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+func (t dependencyPatchTarget) String() string {
+	target := t.Kind
+	if t.Name != "" {
+		target += " " + t.Name
+	}
+	if t.Namespace != "" {
+		target += " in " + t.Namespace
+	}
+	return target
+}
+
+func (t dependencyPatchTarget) matches(dep v1alpha1.Dependency) bool {
+	return dep.GetKind() == t.Kind &&
+		(t.Name == "" || dep.GetName() == t.Name) &&
+		(t.Namespace == "" || dep.GetNamespace() == t.Namespace)
+}
+
+// loadDependencyPatches reads the patches from path. A patch holding a list is
+// a JSON6902 patch; a patch holding a mapping is a strategic merge patch.
+func loadDependencyPatches(path string) ([]*dependencyPatch, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	patchesBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dependency patches: %w", err)
+	}
+
+	var patches []*dependencyPatch
+	if err := yamlsig.UnmarshalStrict(patchesBytes, &patches); err != nil {
+		return nil, fmt.Errorf("failed to parse dependency patches %s: %w", path, err)
+	}
+
+	for i, patch := range patches {
+		if patch.Target.Kind == "" {
+			return nil, fmt.Errorf("invalid dependency patch %d in %s: target.kind is required", i, path)
+		}
+
+		patchJSON, err := yamlsig.YAMLToJSON([]byte(patch.Patch))
+		if err != nil {
+			return nil, fmt.Errorf("invalid dependency patch %d in %s: %w", i, path, err)
+		}
+		var parsed any
+		if err := json.Unmarshal(patchJSON, &parsed); err != nil {
+			return nil, fmt.Errorf("invalid dependency patch %d in %s: %w", i, path, err)
+		}
+
+		switch parsed.(type) {
+		case []any:
+			if patch.operations, err = jsonpatch.DecodePatch(patchJSON); err != nil {
+				return nil, fmt.Errorf("invalid dependency patch %d in %s: %w", i, path, err)
+			}
+		case map[string]any:
+			patch.mergePatch = patchJSON
+		default:
+			return nil, fmt.Errorf("invalid dependency patch %d in %s: patch must be a list of JSON6902 operations or a strategic merge patch", i, path)
+		}
+	}
+	return patches, nil
+}
+
+// applyDependencyPatches applies the patches to the matching dependencies, in
+// order. It warns about the patches matching no dependency.
+func applyDependencyPatches(dependencies []v1alpha1.Dependency, patches []*dependencyPatch) error {
+	for i := range dependencies {
+		for _, patch := range patches {
+			if !patch.Target.matches(dependencies[i]) {
+				continue
+			}
+			patch.matchedCount++
+			if err := patch.apply(&dependencies[i]); err != nil {
+				return fmt.Errorf("failed to patch %s %s: %w", dependencies[i].GetKind(), dependencies[i].GetName(), err)
+			}
+		}
+	}
+
+	for _, patch := range patches {
+		if patch.matchedCount == 0 {
+			fmt.Fprintf(os.Stderr, "Warning: no dependency matches the patch targeting %s\n", patch.Target)
+		}
+	}
+	return nil
+}
+
+// apply patches the dependency. A strategic merge patch of a kind unknown to
+// Kubernetes, such as a custom resource, is applied as a JSON merge patch, as
+// kubectl does.
+func (p *dependencyPatch) apply(dep *v1alpha1.Dependency) error {
+	original, err := json.Marshal(dep.Object)
+	if err != nil {
+		return err
+	}
+
+	var patched []byte
+	switch {
+	case p.operations != nil:
+		patched, err = p.operations.Apply(original)
+	default:
+		if typed, schemeErr := scheme.Scheme.New(dep.GroupVersionKind()); schemeErr == nil {
+			patched, err = strategicpatch.StrategicMergePatch(original, p.mergePatch, typed)
+		} else {
+			patched, err = jsonpatch.MergePatch(original, p.mergePatch)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	var object map[string]any
+	if err := json.Unmarshal(patched, &object); err != nil {
+		return err
+	}
+	dep.Object = object
+	return nil
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/syntasso/kratix/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	yamlsig "sigs.k8s.io/yaml"
+)
+
+// transformDependencies pipes the dependencies, as a YAML stream, through the
+// stdin of the transform command, and reads the transformed dependencies from
+// its stdout.
+func transformDependencies(dependencies []v1alpha1.Dependency, transform string) ([]v1alpha1.Dependency, error) {
+	transformArgs := strings.Fields(transform)
+	if len(transformArgs) == 0 {
+		return nil, fmt.Errorf("invalid --transform: %q", transform)
+	}
+
+	var input bytes.Buffer
+	for i, dep := range dependencies {
+		depBytes, err := yamlsig.Marshal(dep.Object)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			input.WriteString("---\n")
+		}
+		input.Write(depBytes)
+	}
+
+	var output bytes.Buffer
+	transformCmd := exec.Command(transformArgs[0], transformArgs[1:]...)
+	transformCmd.Stdin = &input
+	transformCmd.Stdout = &output
+	transformCmd.Stderr = os.Stderr
+	if err := transformCmd.Run(); err != nil {
+		return nil, fmt.Errorf("--transform %q failed: %w", transform, err)
+	}
+
+	var transformed []v1alpha1.Dependency
+	decoder := yaml.NewYAMLOrJSONDecoder(&output, 2048)
+	for {
+		var obj *unstructured.Unstructured
+		err := decoder.Decode(&obj)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the output of --transform %q: %s", transform, err)
+		}
+		if obj == nil {
+			continue
+		}
+		if obj.GetAPIVersion() == "" || obj.GetKind() == "" || obj.GetName() == "" {
+			return nil, fmt.Errorf("failed to parse the output of --transform %q: document %d is not a Kubernetes object with an apiVersion, a kind and a name", transform, len(transformed))
+		}
+		transformed = append(transformed, v1alpha1.Dependency{Unstructured: *obj})
+	}
+	if len(transformed) == 0 {
+		return nil, fmt.Errorf("the output of --transform %q has no dependencies", transform)
+	}
+	return transformed, nil
+}
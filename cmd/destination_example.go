@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/syntasso/kratix/api/v1alpha1"
+	yamlsig "sigs.k8s.io/yaml"
+)
+
+const (
+	destinationExampleDir      = "examples"
+	destinationExampleFileName = "destination.yaml"
+
+	destinationStoreGit    = "git"
+	destinationStoreBucket = "bucket"
+)
+
+var supportedDestinationStores = []string{destinationStoreGit, destinationStoreBucket}
+
+const destinationExampleHeader = `# EXAMPLE ONLY: this file is not part of the Promise and is never applied
+# by kratix. It is a scaffold of a %s and of a Destination writing to
+# it, to register a cluster with Kratix: replace the example values and apply
+# it to the platform cluster with kubectl apply -f.
+#
+# The Destination has the labels of the destination selectors of the Promise,
+# so the Promise schedules its workloads to it.
+`
+
+// destinationExample generates an example Destination, labelled to match the
+// destination selectors of the Promise, and the state store of storeType it
+// writes to, as a YAML stream preceded by a comment marking it as an example.
+func destinationExample(promiseName, storeType string, selectors []v1alpha1.PromiseScheduling) ([]byte, error) {
+	storeName := promiseName + "-state-store"
+	secretRef := map[string]any{"name": storeName + "-credentials", "namespace": "default"}
+
+	var storeKind string
+	var storeSpec map[string]any
+	switch storeType {
+	case destinationStoreGit:
+		storeKind = "GitStateStore"
+		storeSpec = map[string]any{
+			"url":        "https://github.com/myorg/kratix-state.git",
+			"branch":     "main",
+			"authMethod": "basicAuth",
+			"secretRef":  secretRef,
+		}
+	case destinationStoreBucket:
+		storeKind = "BucketStateStore"
+		storeSpec = map[string]any{
+			"endpoint":   "s3.amazonaws.com",
+			"bucketName": "kratix",
+			"authMethod": "accessKey",
+			"secretRef":  secretRef,
+		}
+	default:
+		return nil, fmt.Errorf("invalid --destination-example-store %q: must be one of %s", storeType, strings.Join(supportedDestinationStores, ", "))
+	}
+
+	destinationMetadata := map[string]any{"name": "worker-1"}
+	labels := map[string]any{}
+	for _, selector := range selectors {
+		for key, value := range selector.MatchLabels {
+			labels[key] = value
+		}
+	}
+	if len(labels) > 0 {
+		destinationMetadata["labels"] = labels
+	}
+
+	objects := []map[string]any{
+		{
+			"apiVersion": kratixGroupVersion(),
+			"kind":       storeKind,
+			"metadata":   map[string]any{"name": storeName},
+			"spec":       storeSpec,
+		},
+		{
+			"apiVersion": kratixGroupVersion(),
+			"kind":       "Destination",
+			"metadata":   destinationMetadata,
+			"spec": map[string]any{
+				"path":          "worker-1",
+				"stateStoreRef": map[string]any{"kind": storeKind, "name": storeName},
+			},
+		},
+	}
+
+	var example bytes.Buffer
+	fmt.Fprintf(&example, destinationExampleHeader, storeKind)
+	for i, obj := range objects {
+		objBytes, err := yamlsig.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			example.WriteString("---\n")
+		}
+		example.Write(objBytes)
+	}
+	return example.Bytes(), nil
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	errorFormatText = "text"
+	errorFormatJSON = "json"
+)
+
+// Error codes reported with --error-format json. Tools match on them, so
+// existing codes must not be renamed.
+const (
+	errCodeUnknown            = "Unknown"
+	errCodeCRDNotFound        = "CRDNotFound"
+	errCodeCRDAmbiguous       = "CRDAmbiguous"
+	errCodeCRDNoVersions      = "CRDNoVersions"
+	errCodeInvalidStatusField = "InvalidStatusField"
+	errCodeInvalidProperty    = "InvalidProperty"
+	errCodeInvalidSetValue    = "InvalidSetValue"
+)
+
+// cliError is an error with a stable code and optional details, printed as is
+// when errors are requested in JSON.
+type cliError struct {
+	Code    string         `json:"code"`
+	Message string         `json:"message"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+func (e *cliError) Error() string {
+	return e.Message
+}
+
+func newCLIError(code string, details map[string]any, format string, a ...any) *cliError {
+	return &cliError{
+		Code:    code,
+		Message: fmt.Sprintf(format, a...),
+		Details: details,
+	}
+}
+
+func validateErrorFormat(format string) error {
+	if format != errorFormatText && format != errorFormatJSON {
+		return fmt.Errorf("invalid --error-format %q: must be one of %s, %s", format, errorFormatText, errorFormatJSON)
+	}
+	return nil
+}
+
+// errorFormatFromArgs finds the --error-format in args, for the errors
+// returned before the flags are parsed, such as an unknown flag.
+func errorFormatFromArgs(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if format, found := strings.CutPrefix(arg, "--error-format="); found {
+			return format
+		}
+		if arg == "--error-format" && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return errorFormatText
+}
+
+// printError prints the error of cmd once, as JSON with the json format and
+// otherwise as cobra does: followed by the usage of the command, or by a hint
+// when the command could not be found.
+func printError(cmd *cobra.Command, err error, format string) {
+	if format == errorFormatJSON {
+		printJSONError(cmd.ErrOrStderr(), err)
+		return
+	}
+	cmd.PrintErrln(cmd.ErrPrefix(), err.Error())
+	if cmd.CalledAs() == "" {
+		cmd.PrintErrf("Run '%v --help' for usage.\n", cmd.CommandPath())
+		return
+	}
+	cmd.Println(cmd.UsageString())
+}
+
+func printJSONError(w io.Writer, err error) {
+	output := cliError{Code: errCodeUnknown, Message: err.Error()}
+	var cliErr *cliError
+	if errors.As(err, &cliErr) {
+		output.Code = cliErr.Code
+		output.Details = cliErr.Details
+	}
+
+	data, marshalErr := json.Marshal(output)
+	if marshalErr != nil {
+		fmt.Fprintf(w, "Error: %s\n", err)
+		return
+	}
+	fmt.Fprintln(w, string(data))
+}
@@ -0,0 +1,57 @@
+package cmd
+
+var FindTargetCRD = findTargetCRD
+
+var WritePromiseFiles = writePromiseFiles
+
+var ApplySetValues = applySetValues
+
+var BuildDependencies = buildDependencies
+
+var FindTargetCRDs = findTargetCRDs
+
+var PruneSchemaDepth = pruneSchemaDepth
+
+var NormaliseKindCase = normaliseKindCase
+
+var WritePromiseFilesConcurrently = writePromiseFilesConcurrently
+
+var ToJSONSchema = toJSONSchema
+
+var ConfirmOverwrite = confirmOverwrite
+
+var FormSchema = formSchema
+
+type FormField = formField
+
+var ValidateCRDName = validateCRDName
+
+type CLIError = cliError
+
+var SplitImage = splitImage
+
+type HelmChartImage = helmChartImage
+
+var SplitDependenciesByScope = splitDependenciesByScope
+
+var NormalizeImage = normalizeImage
+
+var LoadConfig = loadConfig
+
+var ApplyConfig = applyConfig
+
+var EnsureMetadataSchema = ensureMetadataSchema
+
+var ValidateStructuralSchemas = validateStructuralSchemas
+
+var ApplyEnums = applyEnums
+
+var ApplyPropertiesFrom = applyPropertiesFrom
+
+var UnifiedDiff = unifiedDiff
+
+var WrapSpec = wrapSpec
+
+var PruneUnexposedReferences = pruneUnexposedReferences
+
+var OperatorNetworkPolicy = operatorNetworkPolicy
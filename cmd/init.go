@@ -1,6 +1,10 @@
 package cmd
 
 import (
+	"fmt"
+	"slices"
+	"strings"
+
 	"github.com/spf13/cobra"
 )
 
@@ -12,6 +16,7 @@ var initCmd = &cobra.Command{
 
 var (
 	group, kind, version, plural, outputDir string
+	gvk                                     string
 	split                                   bool
 )
 
@@ -23,8 +28,31 @@ func init() {
 	initCmd.PersistentFlags().StringVarP(&version, "version", "v", "", "The group version for the Promise. Defaults to v1alpha1")
 	initCmd.PersistentFlags().StringVar(&plural, "plural", "", "The plural form of the kind. Defaults to the kind name with an additional 's' at the end.")
 	initCmd.PersistentFlags().StringVarP(&outputDir, "dir", "d", ".", "The output directory to write the Promise structure to; defaults to '.'")
+	initCmd.PersistentFlags().StringVar(&gvk, "gvk", "", "The GROUP/VERSION/KIND of the Promise API, e.g. myorg.com/v1/Database, setting --group, --version and --kind at once. Each of these flags takes precedence over its part")
 	initCmd.PersistentFlags().BoolVar(&split, "split", false, "Split promise.yaml file into multiple files.")
 
 	initCmd.MarkPersistentFlagRequired("group")
 	initCmd.MarkPersistentFlagRequired("kind")
 }
+
+// applyGVK sets the --group, --version and --kind flags not set yet from the
+// parts of --gvk.
+func applyGVK(cmd *cobra.Command) error {
+	if cmd.Flags().Lookup("gvk") == nil || gvk == "" {
+		return nil
+	}
+
+	parts := strings.Split(gvk, "/")
+	if len(parts) != 3 || slices.Contains(parts, "") {
+		return fmt.Errorf("invalid --gvk %q: expected GROUP/VERSION/KIND, e.g. myorg.com/v1/Database", gvk)
+	}
+	for i, name := range []string{"group", "version", "kind"} {
+		if cmd.Flags().Changed(name) {
+			continue
+		}
+		if err := cmd.Flags().Set(name, parts[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
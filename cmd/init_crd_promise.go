@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/syntasso/kratix/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+var crdPromiseCmd = &cobra.Command{
+	Use:   "crd-promise PROMISE-NAME --crd CRD-FILE --image PIPELINE-IMAGE --group PROMISE-API-GROUP --kind PROMISE-API-KIND",
+	Short: "Initialize a new Promise from a single CRD",
+	Long: `Initialize a new Promise from a single CRD.
+
+A leaner path than operator-promise when only the CRD of a controller is
+available, rather than the full operator bundle: the CRD in --crd is the only
+dependency of the Promise, and its stored version is the Promise API, renamed
+to --group and --kind as operator-promise does.
+
+The resource configure workflow runs --image with the OPERATOR_GROUP,
+OPERATOR_VERSION and OPERATOR_KIND of the CRD, as the pipeline generated by
+operator-promise. Install the controller reconciling the CRD separately.
+
+The --with-controller-scaffold flag writes a minimal kubebuilder-style
+controller reconciling the resources of the CRD to the controller directory,
+for a CRD without a controller yet: a main.go, a go.mod and a Dockerfile. It is
+not part of the Promise: run "go mod tidy" in the directory, implement the
+reconciler, then build and deploy the controller yourself.`,
+	Example: `  # initialize a new promise from the CRD of a controller
+  kratix init crd-promise postgresql --crd postgres-crd.yaml --image myorg/postgres-pipeline:v1.0.0 --group myorg.com --kind database`,
+	RunE: InitPromiseFromCRD,
+	Args: cobra.ExactArgs(1),
+}
+
+var (
+	crdFile, crdPipelineImage string
+	withControllerScaffold    bool
+)
+
+func init() {
+	initCmd.AddCommand(crdPromiseCmd)
+	crdPromiseCmd.Flags().StringVar(&crdFile, "crd", "", "The file of the CRD to generate the Promise API from. Must contain a single CRD")
+	crdPromiseCmd.Flags().StringVar(&crdPipelineImage, "image", "", "The image of the resource configure pipeline, translating the Promise requests into resources of the CRD")
+	crdPromiseCmd.Flags().BoolVar(&withControllerScaffold, "with-controller-scaffold", false, "Write a minimal controller reconciling the resources of the CRD, to build and deploy yourself, to the controller directory")
+	crdPromiseCmd.MarkFlagRequired("crd")
+	crdPromiseCmd.MarkFlagRequired("image")
+}
+
+func InitPromiseFromCRD(cmd *cobra.Command, args []string) error {
+	promiseName := args[0]
+
+	dependencies, err := extractDepFromFile(crdFile, "default")
+	if err != nil {
+		return err
+	}
+	if len(dependencies) != 1 || dependencies[0].GetKind() != "CustomResourceDefinition" {
+		return fmt.Errorf("%s must contain a single CustomResourceDefinition, found %d documents", crdFile, len(dependencies))
+	}
+
+	crd, err := newCRDIndex(dependencies).find(dependencies[0].GetName())
+	if err != nil {
+		return err
+	}
+
+	if plural == "" {
+		plural = pluralFor(kind, nil)
+	}
+	names := apiextensionsv1.CustomResourceDefinitionNames{
+		Plural:   plural,
+		Singular: strings.ToLower(kind),
+		Kind:     kind,
+	}
+
+	storedVersionIdx := findStoredVersionIdx(crd)
+	crdName, crdNames := crd.Name, crd.Spec.Names
+	envs := []corev1.EnvVar{
+		{
+			Name:  operatorGroupEnv,
+			Value: crd.Spec.Group,
+		},
+		{
+			Name:  operatorVersionEnv,
+			Value: crd.Spec.Versions[storedVersionIdx].Name,
+		},
+		{
+			Name:  operatorKindEnv,
+			Value: crd.Spec.Names.Kind,
+		},
+	}
+	if err := updateOperatorCrd(crd, storedVersionIdx, group, names, version); err != nil {
+		return err
+	}
+	crd.APIVersion = apiextensionsv1.SchemeGroupVersion.String()
+	crd.Kind = "CustomResourceDefinition"
+
+	steps := []v1alpha1.Container{{Name: operatorContainerName, Image: crdPipelineImage}}
+	pipelines := generateResourceConfigurePipelineSteps(steps, envs, nil)
+
+	flags := fmt.Sprintf("--crd %s --image %s", crdFile, crdPipelineImage)
+	filesToWrite, err := getFilesToWrite(promiseName, split, workflowDirectory, flags, nil, dependencies, crd, pipelines, generateExampleResource(crd))
+	if err != nil {
+		return err
+	}
+	if filesToWrite["README.md"], err = generatePromiseReadme("crd-promise", promiseName, flags, crd); err != nil {
+		return err
+	}
+
+	if err := writePromiseFiles(outputDir, filesToWrite, false); err != nil {
+		return err
+	}
+
+	if withControllerScaffold {
+		if err := writeControllerScaffold(promiseName, crdName, envs[0].Value, envs[1].Value, crdNames.Kind, crdNames.Plural); err != nil {
+			return err
+		}
+		fmt.Printf("Controller scaffold for %s generated in %s: run go mod tidy in it before building it.\n", crdName, filepath.Join(outputDir, controllerScaffoldDir))
+	}
+
+	fmt.Println("Promise generated successfully.")
+	fmt.Printf("The CRD %s is the only dependency of the Promise: install the controller reconciling it separately.\n", dependencies[0].GetName())
+	return nil
+}
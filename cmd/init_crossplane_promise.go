@@ -122,7 +122,7 @@ func InitCrossplanePromise(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	err = writePromiseFiles(outputDir, filesToWrite)
+	err = writePromiseFiles(outputDir, filesToWrite, false)
 	if err != nil {
 		return err
 	}
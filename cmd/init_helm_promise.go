@@ -95,8 +95,8 @@ func generateHelmResourceConfigurePipeline() (string, error) {
 	pipelines := []unstructured.Unstructured{
 		{
 			Object: map[string]interface{}{
-				"apiVersion": "platform.kratix.io/v1alpha1",
-				"kind":       "Pipeline",
+				"apiVersion": kratixGroupVersion(),
+				"kind":       kratixPipelineKind,
 				"metadata": map[string]interface{}{
 					"name": "instance-configure",
 				},
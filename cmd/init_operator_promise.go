@@ -1,20 +1,36 @@
 package cmd
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"os/exec"
+	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
 	"text/template"
+	"unicode"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/syntasso/kratix-cli/internal"
 	"github.com/syntasso/kratix/api/v1alpha1"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/term"
 	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/util/jsonpath"
 	yamlsig "sigs.k8s.io/yaml"
 )
 
@@ -23,47 +39,472 @@ const (
 	operatorContainerImage = "ghcr.io/syntasso/kratix-cli/from-api-to-operator:v0.1.0"
 )
 
+// The contract of the operator container: the env injected in each container of
+// the resource configure pipeline, and the files the container reads the
+// resource request from and writes the operator object to. "kratix inspect
+// pipeline-contract" prints it.
+const (
+	operatorGroupEnv   = "OPERATOR_GROUP"
+	operatorVersionEnv = "OPERATOR_VERSION"
+	operatorKindEnv    = "OPERATOR_KIND"
+
+	// operatorBaseSpecEnv is only set with --operator-base-spec.
+	operatorBaseSpecEnv = "OPERATOR_BASE_SPEC"
+
+	// operatorRootFieldsEnv is only set with --wrap-spec, to the
+	// comma-separated properties moved from the root to spec.
+	operatorRootFieldsEnv = "OPERATOR_ROOT_FIELDS"
+
+	// workflowActionEnv is only set with --full-lifecycle, to the
+	// LIFECYCLE/ACTION of the workflow, e.g. resource/delete.
+	workflowActionEnv = "KRATIX_WORKFLOW_ACTION"
+
+	operatorInputFileEnv  = "KRATIX_INPUT_FILE"
+	operatorInputFile     = "/kratix/input/object.yaml"
+	operatorOutputFileEnv = "KRATIX_OUTPUT_FILE"
+	operatorOutputFile    = "/kratix/output/object.yaml"
+)
+
+const operatorPromiseLongHelp = `Generate a Promise from a given Kubernetes Operator.
+
+The Promise API is generated from the operator CRD selected with
+--api-schema-from, and the operator manifests become the dependencies of the
+Promise: embedded in promise.yaml, or written to dependencies.yaml with --split.
+The resource configure pipeline maps each request to the operator CRD, reading
+it from the OPERATOR_* env of its containers. The flags are detailed in
+docs/operator-promise.md of the kratix-cli repository.`
+
+const operatorPromiseExample = `# generate a Promise from the operator manifests of a local directory
+kratix init operator-promise postgresql --group myorg.com --kind Database --version v1 --operator-manifests operator/ --api-schema-from postgresqls.acid.zalan.do
+
+# generate the Promise files separately, from the operator manifests of a git repository
+kratix init operator-promise postgresql --group myorg.com --kind Database --version v1 --operator-manifests git::https://github.com/org/operator//config?ref=v1.2.3 --api-schema-from postgresqls.acid.zalan.do --split`
+
 var operatorPromiseCmd = &cobra.Command{
-	Use:   "operator-promise PROMISE-NAME --group PROMISE-API-GROUP --version PROMISE-API-VERSION --kind PROMISE-API-KIND --operator-manifests OPERATOR-MANIFESTS-DIR --api-schema-from CRD-NAME",
-	Short: "Generate a Promise from a given Kubernetes Operator.",
-	Long:  `Generate a Promise from a given Kubernetes Operator.`,
-	Args:  cobra.ExactArgs(1),
-	RunE:  InitPromiseFromOperator,
+	Use:     "operator-promise PROMISE-NAME --group PROMISE-API-GROUP --version PROMISE-API-VERSION --kind PROMISE-API-KIND --operator-manifests OPERATOR-MANIFESTS-DIR --api-schema-from CRD-NAME",
+	Short:   "Generate a Promise from a given Kubernetes Operator.",
+	Long:    operatorPromiseLongHelp,
+	Example: operatorPromiseExample,
+	Args:    cobra.ExactArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if splitDependenciesScope {
+			if !split && !dependenciesOnly {
+				return fmt.Errorf("--split-dependencies-by-scope requires --split or --dependencies-only")
+			}
+			if preserveComments {
+				return fmt.Errorf("--split-dependencies-by-scope and --preserve-comments cannot be used together")
+			}
+		}
+
+		if err := operatorPodOpts.validate(cmd.Flags()); err != nil {
+			return err
+		}
+		if err := operatorObservabilityOpts.validate(cmd.Flags()); err != nil {
+			return err
+		}
+		if err := operatorWebhookOpts.validate(cmd.Flags()); err != nil {
+			return err
+		}
+
+		var optionalFlags []string
+		if kindFromCRD {
+			if cmd.Flags().Changed("kind") {
+				return fmt.Errorf("--kind and --kind-from-crd cannot be used together")
+			}
+			optionalFlags = append(optionalFlags, "kind")
+		}
+		if allOwnedCRDs {
+			for _, flag := range []string{"api-schema-from", "kind", "kind-from-crd", "plural", "crd-only", "dependencies-only"} {
+				if cmd.Flags().Changed(flag) {
+					return fmt.Errorf("--all-owned-crds and --%s cannot be used together", flag)
+				}
+			}
+			optionalFlags = append(optionalFlags, "kind", "api-schema-from")
+		}
+		if groupFromCRD {
+			if cmd.Flags().Changed("group") {
+				return fmt.Errorf("--group and --group-from-crd cannot be used together")
+			}
+			optionalFlags = append(optionalFlags, "group")
+		}
+		if dependenciesOnly {
+			if crdOnly {
+				return fmt.Errorf("--crd-only and --dependencies-only cannot be used together")
+			}
+			optionalFlags = append(optionalFlags, "kind", "group", "api-schema-from")
+		}
+		var missingFlags []string
+		for _, flag := range operatorPromiseRequiredFlags {
+			if !slices.Contains(optionalFlags, flag) && !cmd.Flags().Changed(flag) {
+				missingFlags = append(missingFlags, flag)
+			}
+		}
+		if len(missingFlags) > 0 {
+			return fmt.Errorf(`required flag(s) "%s" not set`, strings.Join(missingFlags, `", "`))
+		}
+		return nil
+	},
+	RunE: InitPromiseFromOperator,
 }
 
+// requireFlag errors on the first of subFlags set without their parent flag,
+// which they only apply to.
+func requireFlag(flags *pflag.FlagSet, parent string, parentSet bool, subFlags ...string) error {
+	if parentSet {
+		return nil
+	}
+	for _, flag := range subFlags {
+		if flags.Changed(flag) {
+			return fmt.Errorf("--%s requires --%s", flag, parent)
+		}
+	}
+	return nil
+}
+
+// operatorPromiseRequiredFlags are the flags checked in PreRunE, unless made
+// optional by another flag.
+var operatorPromiseRequiredFlags = []string{"api-schema-from", "group", "kind", "operator-manifests"}
+
+var (
+	operatorDependencyOpts    = &DependencyOptions{}
+	operatorSchemaOpts        = &SchemaOptions{}
+	operatorWebhookOpts       = &WebhookOptions{}
+	operatorPodOpts           = &OperatorPodOptions{}
+	operatorObservabilityOpts = &ObservabilityOptions{}
+)
+
 var (
-	operatorManifestsDir, targetCrdName string
+	targetCrdName, postHook         string
+	operatorManifestsDirs           []string
+	fillMissing, force, kindFromCRD bool
+	dependenciesOnly, crdOnly       bool
+	owner                           string
+	deriveSelectors                 bool
+	destinationSelectors            []string
+	destinationSelectorsFile        string
+	verifyApplyFlag, requireCluster bool
+	preserveComments                bool
+	splitDependenciesScope          bool
+	printConfig                     bool
+	validateOnly                    bool
+	kindCase                        string
+	pipelineSteps                   []string
+	emitTestResource                bool
+	operatorBaseSpec                string
+	writeConcurrency                int
+	crdAPIVersion                   string
+	imagePullPolicy                 string
+	imagePullSecrets                []string
+	formSchemaFile                  string
+	requires                        []string
+	pluralDictionary                string
+	requireScope                    string
+	strictCRDName                   bool
+	workflowImages                  []string
+	preserveCRDAnnotations          bool
+	pipelineCommand                 string
+	pipelineArgs                    []string
+	fullLifecycle                   bool
+	printCRDDiff                    bool
+	allOwnedCRDs                    bool
+	signingKey                      string
+	groupFromCRD                    bool
+	layout                          string
+	withDestinationExample          bool
+	destinationExampleStore         string
+	pipelineConfigMapFile           string
+	pipelineConfigMapMountPath      string
+	emitValidationTests             bool
 )
 
+// supportedCRDAPIVersions maps the values of --crd-api-version to the
+// apiVersion of the generated CRD.
+var supportedCRDAPIVersions = map[string]string{
+	"v1": apiextensionsv1.SchemeGroupVersion.String(),
+}
+
+var statusFieldSegment = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_-]*$`)
+
 func init() {
 	initCmd.AddCommand(operatorPromiseCmd)
 
-	operatorPromiseCmd.Flags().StringVarP(&operatorManifestsDir, "operator-manifests", "m", "", "The path to the directory containing the operator manifests.")
-	operatorPromiseCmd.Flags().StringVarP(&targetCrdName, "api-schema-from", "a", "", "The name of the CRD which the Promise API schema should be generated from.")
+	operatorPromiseCmd.Flags().StringArrayVarP(&operatorManifestsDirs, "operator-manifests", "m", nil, "The path to the directory containing the operator manifests, or a git source such as git::https://github.com/org/operator//config/crd?ref=v1.2.3. Can be specified multiple times to combine bundles.")
+	operatorPromiseCmd.Flags().StringVarP(&targetCrdName, "api-schema-from", "a", "", "The name of the CRD which the Promise API schema should be generated from. Accepts either the CRD name or the KIND.GROUP form.")
+	operatorPromiseCmd.Flags().StringArrayVar(&pipelineSteps, "pipeline-step", nil, "A NAME=IMAGE container to run in the resource configure pipeline. Can be specified multiple times; the steps run in order. Defaults to the operator container.")
+	operatorPromiseCmd.Flags().StringVar(&pipelineConfigMapFile, "pipeline-configmap", "", "A configuration file for the resource configure pipeline, shipped as a ConfigMap dependency and mounted read-only into its containers. The ConfigMap must also exist in the namespaces of the requests. Cannot be used with --dependencies-only.")
+	operatorPromiseCmd.Flags().StringVar(&pipelineConfigMapMountPath, "pipeline-configmap-mount-path", "/etc/pipeline-config", "The directory the file of --pipeline-configmap is mounted in, outside of /kratix.")
+	operatorPromiseCmd.Flags().BoolVar(&fullLifecycle, "full-lifecycle", false, "Generate the configure and delete workflows of both the promise and resource lifecycles, running the default container unless set with --workflow. Each container gets its LIFECYCLE/ACTION in the KRATIX_WORKFLOW_ACTION env.")
+	operatorPromiseCmd.Flags().StringArrayVar(&workflowImages, "workflow", nil, "A LIFECYCLE/ACTION=IMAGE workflow to generate, e.g. resource/delete=myorg/cleanup:v1. Can be specified multiple times. The resource/configure workflow is always generated.")
+	operatorPromiseCmd.Flags().StringVar(&operatorBaseSpec, "operator-base-spec", "", "A YAML or JSON file with the default spec of the operator object, passed to the pipeline as JSON in the OPERATOR_BASE_SPEC env.")
+	operatorPromiseCmd.Flags().StringVar(&pipelineCommand, "pipeline-command", "", "The command of the resource configure container, replacing the entrypoint of its image.")
+	operatorPromiseCmd.Flags().StringArrayVar(&pipelineArgs, "pipeline-arg", nil, "An argument of the resource configure container. Can be specified multiple times. Passed to the entrypoint of the image without --pipeline-command.")
+	operatorPromiseCmd.Flags().StringVar(&imagePullPolicy, "image-pull-policy", "", "The imagePullPolicy of the generated pipeline containers. One of: Always, IfNotPresent, Never. Defaults to the cluster behaviour.")
+	operatorPromiseCmd.Flags().StringArrayVar(&imagePullSecrets, "image-pull-secret", nil, "The name of a Secret to pull the generated pipeline images with. Can be specified multiple times. The Secret must exist in the namespace the workflow runs in.")
+	operatorPromiseCmd.Flags().BoolVar(&strictCRDName, "strict-crd-name", false, "Fail when the CRD name is not a valid lowercase <plural>.<group>, instead of warning.")
+	operatorPromiseCmd.Flags().StringVar(&requireScope, "require-scope", "", "Fail unless the operator CRD has this scope. One of: Namespaced, Cluster. Defaults to keeping the scope of the CRD.")
+	operatorPromiseCmd.Flags().StringVar(&pluralDictionary, "plural-dictionary", "", "The path to a YAML file mapping kinds to their plural, consulted when --plural is not set.")
+	operatorPromiseCmd.Flags().StringVar(&kindCase, "kind-case", "", "Normalise the casing of the kind. One of: pascal, camel, lower. Defaults to the kind as given.")
+	operatorPromiseCmd.Flags().StringArrayVar(&requires, "requires", nil, "A NAME:VERSION Promise the generated Promise requires, e.g. cert-manager:v1.0.0. Can be specified multiple times.")
+	operatorPromiseCmd.Flags().BoolVar(&groupFromCRD, "group-from-crd", false, "Use the group of the operator CRD as the Promise API group, which then only differs from the operator CRD by its kind. Makes --group optional.")
+	operatorPromiseCmd.Flags().BoolVar(&kindFromCRD, "kind-from-crd", false, "Use the kind of the operator CRD as the Promise kind, which then only differs from the operator CRD by its group. Makes --kind optional.")
+	operatorPromiseCmd.Flags().BoolVar(&operatorDependencyOpts.DropWebhookConfigs, "drop-webhook-configs", false, "Remove ValidatingWebhookConfigurations and MutatingWebhookConfigurations from the dependencies.")
+	operatorPromiseCmd.Flags().StringArrayVar(&operatorDependencyOpts.APIVersionRewrites, "rewrite-apiversion", nil, "An OLD=NEW rewrite of the apiVersion of the dependencies, e.g. rbac.authorization.k8s.io/v1beta1=rbac.authorization.k8s.io/v1. Can be specified multiple times.")
+	operatorPromiseCmd.Flags().BoolVar(&operatorDependencyOpts.NormalizeImages, "normalize-images", false, "Fully qualify the images without a registry, in the dependencies and the pipelines, with docker.io, e.g. nginx:latest becomes docker.io/library/nginx:latest.")
+	operatorPromiseCmd.Flags().StringVar(&operatorDependencyOpts.Patches, "dependency-patch", "", "A file of JSON6902 or strategic merge patches to apply to the dependencies matching their target kind and name.")
+	operatorPromiseCmd.Flags().StringVar(&operatorDependencyOpts.Namespace, "operator-namespace", "", "The namespace of the operator: the namespace of the dependencies without one. Defaults to default.")
+	operatorPromiseCmd.Flags().StringArrayVar(&operatorDependencyOpts.AnnotationsToStrip, "strip-annotation", nil, "An annotation key, or a prefix ending in /, to remove from the dependencies, e.g. foo.operator.io/. Can be specified multiple times.")
+	operatorPromiseCmd.Flags().IntVar(&operatorDependencyOpts.MaxBytes, "max-dependencies-bytes", defaultMaxDependenciesBytes, "Fail when the serialized dependencies are larger than this many bytes. 0 disables the check.")
+	operatorPromiseCmd.Flags().StringVar(&operatorDependencyOpts.Transform, "transform", "", "A command to pipe the dependencies through, as a YAML stream on its stdin, reading the transformed dependencies from its stdout, before the dependencies of the --with-* flags are added. Only use trusted commands.")
+	operatorPromiseCmd.Flags().BoolVar(&operatorDependencyOpts.Verify, "verify-dependencies", false, "Check the dependencies client-side for a valid apiVersion, kind and name, and the required fields of known kinds, reporting all the problems at once.")
+	operatorPromiseCmd.Flags().BoolVar(&operatorDependencyOpts.OrderByKind, "order-by-kind", false, "Annotate the dependencies with the Argo CD sync wave of their kind: Namespaces and CRDs first, then RBAC, configuration, workloads and webhooks.")
+	operatorPromiseCmd.Flags().StringVar(&operatorDependencyOpts.SyncWavesFile, "sync-waves", "", "A YAML file mapping kinds to the sync waves of --order-by-kind, overriding the default ones.")
+	operatorPromiseCmd.Flags().BoolVar(&operatorDependencyOpts.RedactSecrets, "redact-secrets", false, "Blank the values of the data and stringData of the Secrets of the dependencies, keeping their keys. Without it, the values are shipped in the Promise as they are.")
+	operatorPromiseCmd.Flags().BoolVar(&operatorDependencyOpts.PreserveHelmAnnotations, "preserve-helm-annotations", false, "Keep the helm.sh/hook and meta.helm.sh/ annotations of the dependencies, which are removed by default.")
+	operatorPromiseCmd.Flags().BoolVar(&preserveCRDAnnotations, "preserve-crd-annotations", false, "Keep the kubebuilder.io annotations of the operator CRD, such as controller-gen.kubebuilder.io/version, in the generated API.")
+	operatorPromiseCmd.Flags().BoolVar(&splitDependenciesScope, "split-dependencies-by-scope", false, "Write the cluster-scoped and the namespaced dependencies to dependencies-cluster.yaml and dependencies-namespaced.yaml instead of dependencies.yaml. Requires --split or --dependencies-only.")
+	operatorPromiseCmd.Flags().BoolVar(&validateOnly, "validate-only", false, "Generate the Promise in memory and check it, printing a pass or fail summary, without writing any file.")
+	operatorPromiseCmd.Flags().BoolVar(&printCRDDiff, "print-crd-diff", false, "Print the unified diff between the operator CRD and the generated API to stderr before writing the Promise files.")
+	operatorPromiseCmd.Flags().BoolVar(&printConfig, "print-config", false, "Print the resolved configuration, once the defaults are applied, as YAML to stderr before writing the Promise files.")
+	operatorPromiseCmd.Flags().BoolVar(&preserveComments, "preserve-comments", false, "Keep the comments of the operator manifests in dependencies.yaml. Requires --split or --dependencies-only.")
+	operatorPromiseCmd.Flags().BoolVar(&allOwnedCRDs, "all-owned-crds", false, "Generate a Promise named PROMISE-NAME-<lowercase kind>, in a subdirectory of the same name, for each CRD owned by the ClusterServiceVersion of the operator manifests. Replaces --api-schema-from and --kind.")
+	operatorPromiseCmd.Flags().BoolVar(&dependenciesOnly, "dependencies-only", false, "Only generate the dependencies.yaml file from the operator manifests. Makes --api-schema-from, --group and --kind optional.")
+	operatorPromiseCmd.Flags().BoolVar(&crdOnly, "crd-only", false, "Only generate the api.yaml file with the Promise API transformed from the operator CRD. Cannot be used with --dependencies-only.")
+	operatorPromiseCmd.Flags().BoolVar(&fillMissing, "fill-missing", false, "Only write the Promise files that do not exist yet in the output directory.")
+	operatorPromiseCmd.Flags().BoolVar(&force, "force", false, "Overwrite existing files in the output directory. Takes precedence over --fill-missing.")
+	operatorPromiseCmd.Flags().BoolVar(&deriveSelectors, "derive-destination-selectors", false, "Use the nodeSelector of the operator Deployment as the Promise destination selectors.")
+	operatorPromiseCmd.Flags().StringArrayVar(&destinationSelectors, "destination-selector", nil, "A KEY=VALUE label the Destinations must match. Can be specified multiple times. Overrides --derive-destination-selectors.")
+	operatorPromiseCmd.Flags().StringVar(&destinationSelectorsFile, "destination-selectors-file", "", "A YAML file with a list of label maps, each a destination selector of the Promise. Composes with --destination-selector. Overrides --derive-destination-selectors.")
+	operatorPromiseCmd.Flags().BoolVar(&verifyApplyFlag, "verify-apply", false, "Verify the generated CRD and Promise are accepted by the cluster of the current kubeconfig, using a server-side dry-run.")
+	operatorPromiseCmd.Flags().BoolVar(&requireCluster, "require-cluster", false, "Fail --verify-apply when no kubeconfig is available, instead of skipping the verification.")
+	operatorPromiseCmd.Flags().StringVar(&owner, "owner", "", "The team or user owning the generated files, e.g. @myorg/platform. Writes a CODEOWNERS file when set.")
+	operatorPromiseCmd.Flags().StringVar(&crdAPIVersion, "crd-api-version", "v1", "The apiextensions.k8s.io version of the generated CRD. One of: v1.")
+	operatorPromiseCmd.Flags().BoolVar(&emitTestResource, "emit-test-resource", false, "Write a Chainsaw test requesting an example resource and asserting it becomes Ready to tests/chainsaw-test.yaml.")
+	operatorPromiseCmd.Flags().BoolVar(&withDestinationExample, "with-destination-example", false, "Write an example Destination and state store, to edit and apply to the platform cluster, to examples/destination.yaml.")
+	operatorPromiseCmd.Flags().StringVar(&destinationExampleStore, "destination-example-store", destinationStoreGit, "The state store of --with-destination-example. One of: "+strings.Join(supportedDestinationStores, ", ")+".")
+	operatorPromiseCmd.Flags().BoolVar(&emitValidationTests, "emit-validation-tests", false, "Write a Chainsaw test checking the Promise API accepts a valid request and rejects requests breaking its schema to tests/chainsaw-validation-test.yaml.")
+	operatorPromiseCmd.Flags().StringVar(&formSchemaFile, "emit-form-schema", "", "Write the fields of the Promise API, flattened for developer portal forms, as JSON to this file.")
+	operatorPromiseCmd.Flags().IntVar(&writeConcurrency, "write-concurrency", 1, "The number of Promise files to write in parallel.")
+	operatorPromiseCmd.Flags().StringVar(&layout, "layout", layoutNested, "The layout of the generated files. One of: "+strings.Join(supportedLayouts, ", ")+".")
+	operatorPromiseCmd.Flags().BoolVar(&recordSource, "record-source", false, "Write a .kratix-source.yaml file recording the operator manifests and the flags, for kratix regenerate.")
+	operatorPromiseCmd.Flags().StringVar(&signingKey, "sign-with", "", "A cosign key to sign the digests of the generated files with, e.g. cosign.key or awskms:///alias/promises, writing them to promise.sha256 and the signature to promise.sha256.sig. Requires the cosign CLI.")
+	operatorPromiseCmd.Flags().StringVar(&postHook, "post-hook", "", "A command to run with sh -c once the Promise files are generated, in the output directory and with it as its last argument. Only use trusted commands.")
+
+	operatorSchemaOpts.addFlags(operatorPromiseCmd.Flags())
+	operatorWebhookOpts.addFlags(operatorPromiseCmd.Flags())
+	operatorPodOpts.addFlags(operatorPromiseCmd.Flags())
+	operatorObservabilityOpts.addFlags(operatorPromiseCmd.Flags())
 
-	operatorPromiseCmd.MarkFlagRequired("operator-manifests")
-	operatorPromiseCmd.MarkFlagRequired("api-schema-from")
+	// --group and --kind shadow the required flags of init: they are optional
+	// with some of the flags of the command, so are checked in PreRunE
+	operatorPromiseCmd.Flags().StringVarP(&group, "group", "g", "", initCmd.PersistentFlags().Lookup("group").Usage)
+	operatorPromiseCmd.Flags().StringVarP(&kind, "kind", "k", "", initCmd.PersistentFlags().Lookup("kind").Usage)
 }
 
 func InitPromiseFromOperator(cmd *cobra.Command, args []string) error {
 	promiseName := args[0]
 
-	if plural == "" {
-		plural = fmt.Sprintf("%ss", strings.ToLower(kind))
+	statusFieldPaths, err := parseStatusFields(operatorSchemaOpts.StatusFields)
+	if err != nil {
+		return err
+	}
+
+	steps, err := parsePipelineSteps(pipelineSteps)
+	if err != nil {
+		return err
+	}
+
+	workflows, err := parseWorkflows(workflowImages)
+	if err != nil {
+		return err
+	}
+	var extraWorkflows []operatorWorkflow
+	for _, workflow := range workflows {
+		if workflow.lifecycle != "resource" || workflow.action != "configure" {
+			extraWorkflows = append(extraWorkflows, workflow)
+			continue
+		}
+		if len(pipelineSteps) > 0 {
+			return fmt.Errorf("--workflow resource/configure and --pipeline-step cannot be used together")
+		}
+		steps = []v1alpha1.Container{{Name: operatorContainerName, Image: workflow.image}}
+	}
+	if fullLifecycle {
+		extraWorkflows = addLifecycleWorkflows(extraWorkflows)
+	}
+
+	if err := setPipelineCommand(steps, pipelineCommand, pipelineArgs); err != nil {
+		return err
+	}
+	if err := setImagePullPolicy(steps, imagePullPolicy); err != nil {
+		return err
+	}
+	if operatorDependencyOpts.NormalizeImages {
+		normalizeStepImages(steps)
+	}
+
+	requiredPromises, err := parseRequiredPromises(requires)
+	if err != nil {
+		return err
+	}
+
+	plurals, err := loadPluralDictionary(pluralDictionary)
+	if err != nil {
+		return err
+	}
+
+	baseSpec, err := loadOperatorBaseSpec(operatorBaseSpec)
+	if err != nil {
+		return err
+	}
+
+	switch apiextensionsv1.ResourceScope(requireScope) {
+	case "", apiextensionsv1.NamespaceScoped, apiextensionsv1.ClusterScoped:
+	default:
+		return fmt.Errorf("invalid --require-scope %q: must be one of Namespaced, Cluster", requireScope)
+	}
+
+	for _, secret := range imagePullSecrets {
+		if errs := validation.IsDNS1123Label(secret); len(errs) > 0 {
+			return fmt.Errorf("invalid --image-pull-secret %q: %s", secret, strings.Join(errs, ", "))
+		}
+	}
+
+	if !slices.Contains(supportedLayouts, layout) {
+		return fmt.Errorf("invalid --layout %q: must be one of %s", layout, strings.Join(supportedLayouts, ", "))
+	}
+	var pipelineConfig *v1alpha1.Dependency
+	if pipelineConfigMapFile != "" {
+		if dependenciesOnly {
+			return fmt.Errorf("--pipeline-configmap cannot be used with --dependencies-only: there is no pipeline to mount it in")
+		}
+		mountPath := path.Clean(pipelineConfigMapMountPath)
+		if !path.IsAbs(mountPath) || mountPath == "/kratix" || strings.HasPrefix(mountPath, "/kratix/") {
+			return fmt.Errorf("invalid --pipeline-configmap-mount-path %q: must be an absolute path outside of /kratix", pipelineConfigMapMountPath)
+		}
+		configMap, err := pipelineConfigMap(promiseName, pipelineConfigMapFile, operatorDependencyOpts.namespace())
+		if err != nil {
+			return err
+		}
+		pipelineConfig = &configMap
+	}
+	if !slices.Contains(supportedDestinationStores, destinationExampleStore) {
+		return fmt.Errorf("invalid --destination-example-store %q: must be one of %s", destinationExampleStore, strings.Join(supportedDestinationStores, ", "))
+	}
+
+	if operatorSchemaOpts.DepthLimit < 0 {
+		return fmt.Errorf("invalid --schema-depth-limit %d: must not be negative", operatorSchemaOpts.DepthLimit)
+	}
+
+	crdTypeMeta, err := crdTypeMetaFor(crdAPIVersion)
+	if err != nil {
+		return err
+	}
+
+	if writeConcurrency < 1 {
+		return fmt.Errorf("invalid --write-concurrency %d: must be at least 1", writeConcurrency)
+	}
+
+	if !force && !fillMissing && !validateOnly {
+		if err := confirmOverwrite(outputDir, term.IsTerminal(int(os.Stdin.Fd())), os.Stdin, os.Stdout); err != nil {
+			return err
+		}
+	}
+
+	var source *promiseSource
+	if recordSource {
+		if source, err = newPromiseSource(cmd, promiseName, outputDir); err != nil {
+			return err
+		}
+	}
+
+	var manifestsDirs []string
+	for _, manifestsDir := range operatorManifestsDirs {
+		if internal.IsGitSource(manifestsDir) {
+			var cleanup func()
+			manifestsDir, cleanup, err = internal.FetchGitManifests(manifestsDir)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+		}
+		manifestsDirs = append(manifestsDirs, manifestsDir)
+	}
+
+	dependencies, err := buildDependencies(operatorDependencyOpts, manifestsDirs...)
+	if err != nil {
+		return err
+	}
+
+	if allOwnedCRDs {
+		crds, err := findOwnedCRDs(promiseName, dependencies)
+		if err != nil {
+			return err
+		}
+		return initPromisesFromOwnedCRDs(cmd, crds)
 	}
 
-	dependencies, err := buildDependencies(operatorManifestsDir)
+	selectors, err := operatorDestinationSelectors(dependencies)
 	if err != nil {
 		return err
 	}
 
+	podDependencies, err := operatorPodOpts.dependencies(dependencies)
+	if err != nil {
+		return err
+	}
+	dependencies = append(dependencies, podDependencies...)
+
+	observabilityDependencies, err := operatorObservabilityOpts.dependencies(dependencies, promiseName, operatorDependencyOpts.namespace())
+	if err != nil {
+		return err
+	}
+	dependencies = append(dependencies, observabilityDependencies...)
+
+	if pipelineConfig != nil {
+		dependencies = append(dependencies, *pipelineConfig)
+	}
+
+	if dependenciesOnly {
+		dependenciesFile, err := finishDependencies(manifestsDirs, dependencies)
+		if err != nil {
+			return err
+		}
+		if err := printOperatorPromiseConfig(cmd, newOperatorPromiseConfig(promiseName, nil, nil, nil, nil)); err != nil {
+			return err
+		}
+		if validateOnly {
+			fmt.Printf("Validation passed: %d dependencies; no files written\n", len(dependencies))
+			return nil
+		}
+		if err := writeOperatorPromiseFiles(dependenciesFiles(dependencies, dependenciesFile), source); err != nil {
+			return err
+		}
+		fmt.Println("Dependencies generated successfully.")
+		fmt.Println("You can add them to an existing Promise by running:")
+		fmt.Printf("\tkratix update dependencies %s\n", updateDependenciesPath())
+		return nil
+	}
+
 	crd, err := findTargetCRD(targetCrdName, dependencies)
 	if err != nil {
 		return err
 	}
+	operatorCRD := crd.DeepCopy()
 
-	if len(crd.Spec.Versions) == 0 {
-		return fmt.Errorf("no versions found in CRD")
+	if kindFromCRD {
+		kind = crd.Spec.Names.Kind
+	}
+	if groupFromCRD {
+		group = crd.Spec.Group
+	}
+
+	kind, err = normaliseKindCase(kind, kindCase)
+	if err != nil {
+		return err
+	}
+
+	if plural == "" {
+		plural = pluralFor(kind, plurals)
 	}
 
 	names := apiextensionsv1.CustomResourceDefinitionNames{
@@ -71,24 +512,79 @@ func InitPromiseFromOperator(cmd *cobra.Command, args []string) error {
 		Singular: strings.ToLower(kind),
 		Kind:     kind,
 	}
+	if groupFromCRD && fmt.Sprintf("%s.%s", names.Plural, group) == crd.Name {
+		return fmt.Errorf("--group-from-crd generates an API with the same name as the operator CRD %s: set a different --kind or --plural", crd.Name)
+	}
 
 	storedVersionIdx := findStoredVersionIdx(crd)
 	operatorVersion := crd.Spec.Versions[storedVersionIdx].Name
 	envs := []corev1.EnvVar{
 		{
-			Name:  "OPERATOR_GROUP",
+			Name:  operatorGroupEnv,
 			Value: crd.Spec.Group,
 		},
 		{
-			Name:  "OPERATOR_VERSION",
+			Name:  operatorVersionEnv,
 			Value: operatorVersion,
 		},
 		{
-			Name:  "OPERATOR_KIND",
+			Name:  operatorKindEnv,
 			Value: crd.Spec.Names.Kind,
 		},
 	}
-	updateOperatorCrd(crd, storedVersionIdx, group, names, version)
+	if baseSpec != "" {
+		envs = append(envs, corev1.EnvVar{Name: operatorBaseSpecEnv, Value: baseSpec})
+	}
+	if err := updateOperatorCrd(crd, storedVersionIdx, group, names, version); err != nil {
+		return err
+	}
+	crd.TypeMeta = crdTypeMeta
+
+	wrapped, err := operatorSchemaOpts.apply(crd, statusFieldPaths)
+	if err != nil {
+		return err
+	}
+	if len(wrapped) > 0 {
+		envs = append(envs, corev1.EnvVar{Name: operatorRootFieldsEnv, Value: strings.Join(wrapped, ",")})
+	}
+
+	if printCRDDiff {
+		if err := printGeneratedCRDDiff(operatorCRD, crd); err != nil {
+			return err
+		}
+	}
+
+	if crdOnly {
+		if err := printOperatorPromiseConfig(cmd, newOperatorPromiseConfig(promiseName, crd, envs, nil, nil)); err != nil {
+			return err
+		}
+		if verifyApplyFlag {
+			if err := verifyApply(cmd.Context(), requireCluster, crdWithTypeMeta(crd)); err != nil {
+				return err
+			}
+		}
+		if validateOnly {
+			return reportValidation(crd, nil)
+		}
+		if err := writeOperatorPromiseFiles(map[string]any{apiFileName: crd}, source); err != nil {
+			return err
+		}
+		fmt.Println("CRD generated successfully.")
+		return nil
+	}
+
+	if operatorWebhookOpts.Enabled {
+		webhook, err := promiseValidatingWebhook(crd, operatorWebhookOpts)
+		if err != nil {
+			return err
+		}
+		dependencies = append(dependencies, webhook)
+	}
+
+	dependenciesFile, err := finishDependencies(manifestsDirs, dependencies)
+	if err != nil {
+		return err
+	}
 
 	exampleResource := &unstructured.Unstructured{
 		Object: map[string]any{
@@ -102,45 +598,566 @@ func InitPromiseFromOperator(cmd *cobra.Command, args []string) error {
 		},
 	}
 
-	pipelines := generateResourceConfigurePipelines(operatorContainerName, operatorContainerImage, envs)
+	resourceConfigureEnvs := envs
+	if fullLifecycle {
+		resourceConfigureEnvs = workflowEnvs(envs, operatorWorkflow{lifecycle: "resource", action: "configure"})
+	}
+	pipelines := generateResourceConfigurePipelineSteps(steps, resourceConfigureEnvs, imagePullSecrets)
+	if pipelineConfig != nil {
+		mountPipelineConfigMap(pipelines, pipelineConfig.GetName(), path.Clean(pipelineConfigMapMountPath))
+	}
+	extraPipelines := map[operatorWorkflow][]unstructured.Unstructured{}
+	for _, workflow := range extraWorkflows {
+		steps := []v1alpha1.Container{{Name: operatorContainerName, Image: workflow.image}}
+		if err := setImagePullPolicy(steps, imagePullPolicy); err != nil {
+			return err
+		}
+		if operatorDependencyOpts.NormalizeImages {
+			normalizeStepImages(steps)
+		}
+		pipelineEnvs := envs
+		if fullLifecycle {
+			pipelineEnvs = workflowEnvs(envs, workflow)
+		}
+		extraPipelines[workflow] = generatePipelineSteps(workflow.pipelineName(), steps, pipelineEnvs, imagePullSecrets)
+	}
+
+	if err := printOperatorPromiseConfig(cmd, newOperatorPromiseConfig(promiseName, crd, envs, steps, extraWorkflows)); err != nil {
+		return err
+	}
+
+	if verifyApplyFlag {
+		promise, err := generatePromise(promiseName, selectors, dependencies, crd, pipelines)
+		if err != nil {
+			return err
+		}
+		promise.Spec.RequiredPromises = requiredPromises
+		for workflow, workflowPipelines := range extraPipelines {
+			setPromiseWorkflow(&promise, workflow.lifecycle, workflow.action, workflowPipelines)
+		}
+		if err := verifyApply(cmd.Context(), requireCluster, crdWithTypeMeta(crd), promise); err != nil {
+			return err
+		}
+	}
+
+	var flags string
+	for _, manifestsDir := range operatorManifestsDirs {
+		flags += fmt.Sprintf("--operator-manifests %s ", manifestsDir)
+	}
+	flags += fmt.Sprintf("--api-schema-from %s", targetCrdName)
+	filesToWrite, err := getFilesToWrite(promiseName, split, workflowDirectory, flags, selectors, dependencies, crd, pipelines, exampleResource)
+	if err != nil {
+		return err
+	}
+
+	if emitTestResource || emitValidationTests {
+		tests := map[string]any{}
+		if emitTestResource {
+			tests[testScaffoldFileName] = chainsawTest(promiseName, crd)
+		}
+		if emitValidationTests {
+			tests[validationTestFileName] = chainsawValidationTest(promiseName, crd)
+		}
+		filesToWrite[testScaffoldDir] = tests
+	}
+
+	if withDestinationExample {
+		example, err := destinationExample(promiseName, destinationExampleStore, selectors)
+		if err != nil {
+			return err
+		}
+		filesToWrite[destinationExampleDir] = map[string]any{destinationExampleFileName: example}
+	}
+
+	if split {
+		delete(filesToWrite, dependenciesFileName)
+		for fileName, content := range dependenciesFiles(dependencies, dependenciesFile) {
+			filesToWrite[fileName] = content
+		}
+	}
+
+	for _, workflow := range extraWorkflows {
+		if split {
+			filesToWrite[workflow.directory()] = map[string]any{"workflow.yaml": extraPipelines[workflow]}
+		} else {
+			promise := filesToWrite[promiseFileName].(v1alpha1.Promise)
+			setPromiseWorkflow(&promise, workflow.lifecycle, workflow.action, extraPipelines[workflow])
+			filesToWrite[promiseFileName] = promise
+		}
+	}
+
+	if len(requiredPromises) > 0 {
+		if split {
+			filesToWrite[requiredPromisesFileName] = requiredPromises
+		} else {
+			promise := filesToWrite[promiseFileName].(v1alpha1.Promise)
+			promise.Spec.RequiredPromises = requiredPromises
+			filesToWrite[promiseFileName] = promise
+		}
+	}
+
+	if validateOnly {
+		return reportValidation(crd, dependencies)
+	}
+
+	if formSchemaFile != "" {
+		if err := writeFormSchema(formSchemaFile, crd); err != nil {
+			return err
+		}
+	}
+
+	if err := writeOperatorPromiseFiles(filesToWrite, source); err != nil {
+		return err
+	}
+
+	fmt.Println("Promise generated successfully.")
+	fmt.Println("The Operator documents were added as inline dependencies in the Promise Spec.")
+	fmt.Println("You can move them to a workflow by running:")
+	fmt.Printf("\tkratix update dependencies %s --image yourorg/your-image:tag\n", updateDependenciesPath())
+	return nil
+}
+
+func operatorDestinationSelectors(dependencies []v1alpha1.Dependency) ([]v1alpha1.PromiseScheduling, error) {
+	var selectors []v1alpha1.PromiseScheduling
+	var err error
+	switch {
+	case len(destinationSelectors) > 0 || destinationSelectorsFile != "":
+		selectors, err = loadDestinationSelectors(destinationSelectorsFile)
+		if err != nil {
+			return nil, err
+		}
+		var inlineSelectors []v1alpha1.PromiseScheduling
+		inlineSelectors, err = parseDestinationSelectors(destinationSelectors)
+		selectors = append(selectors, inlineSelectors...)
+	case deriveSelectors:
+		selectors, err = deriveDestinationSelectors(dependencies)
+		if err == nil {
+			fmt.Fprintln(os.Stderr, "Warning: the destination selectors are derived from the operator nodeSelector on a best-effort basis; check they match the labels of your Destinations or set them with --destination-selector")
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(selectors) > 0 && split {
+		fmt.Fprintln(os.Stderr, "Warning: destination selectors are not written with --split; add them to the Promise spec.destinationSelectors")
+	}
+	return selectors, nil
+}
+
+// updateDependenciesPath is the path suggested to "kratix update
+// dependencies", which reads a single directory.
+func updateDependenciesPath() string {
+	if len(operatorManifestsDirs) == 1 {
+		return operatorManifestsDirs[0]
+	}
+	return "COMBINED-OPERATOR-MANIFESTS-DIR"
+}
+
+// finishDependencies orders the dependencies by kind and checks their size,
+// once every generated dependency is appended, and returns the content of the
+// dependencies file.
+func finishDependencies(manifestsDirs []string, dependencies []v1alpha1.Dependency) (any, error) {
+	if err := orderDependenciesByKind(dependencies, operatorDependencyOpts); err != nil {
+		return nil, err
+	}
+	if err := checkDependenciesSize(dependencies, operatorDependencyOpts.MaxBytes); err != nil {
+		return nil, err
+	}
+	if preserveComments {
+		return dependenciesWithComments(manifestsDirs, dependencies)
+	}
+	return dependencies, nil
+}
+
+func dependenciesWithComments(manifestsDirs []string, dependencies []v1alpha1.Dependency) ([]byte, error) {
+	if !split && !dependenciesOnly {
+		return nil, fmt.Errorf("--preserve-comments requires --split or --dependencies-only: comments cannot be kept in promise.yaml")
+	}
+
+	comments, err := readManifestComments(manifestsDirs...)
+	if err != nil {
+		return nil, err
+	}
+	return marshalDependenciesWithComments(dependencies, comments)
+}
+
+func normaliseKindCase(kind, kindCase string) (string, error) {
+	switch kindCase {
+	case "":
+		return kind, nil
+	case "pascal", "camel", "lower":
+	default:
+		return "", fmt.Errorf("invalid --kind-case %q: must be one of pascal, camel, lower", kindCase)
+	}
+
+	var normalised strings.Builder
+	for i, word := range splitKindWords(kind) {
+		runes := []rune(strings.ToLower(word))
+		if kindCase == "pascal" || (kindCase == "camel" && i > 0) {
+			runes[0] = unicode.ToUpper(runes[0])
+		}
+		normalised.WriteString(string(runes))
+	}
+	return normalised.String(), nil
+}
+
+// splitKindWords splits a kind on dashes, underscores and case changes, keeping
+// acronyms together: "my-app", "myApp" and "MyApp" are all "my" and "app",
+// while "HTTPRoute" is "HTTP" and "Route".
+func splitKindWords(kind string) []string {
+	var words []string
+	var word []rune
+	runes := []rune(kind)
+	for i, r := range runes {
+		if r == '-' || r == '_' {
+			if len(word) > 0 {
+				words = append(words, string(word))
+			}
+			word = nil
+			continue
+		}
+		startsWord := unicode.IsUpper(r) && len(word) > 0 &&
+			(!unicode.IsUpper(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1])))
+		if startsWord {
+			words = append(words, string(word))
+			word = nil
+		}
+		word = append(word, r)
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+	return words
+}
+
+func writeOperatorPromiseFiles(filesToWrite map[string]any, source *promiseSource) error {
+	if layout == layoutFlat {
+		var err error
+		if filesToWrite, err = flattenPromiseFiles(filesToWrite); err != nil {
+			return err
+		}
+	}
+
+	if source != nil {
+		filesToWrite[sourceRecordFileName] = source
+	}
+
+	if owner != "" {
+		codeOwners, err := generateCodeOwners(outputDir, owner, filesToWrite)
+		if err != nil {
+			return err
+		}
+		filesToWrite[codeOwnersFileName] = codeOwners
+	}
+
+	if err := writePromiseFilesConcurrently(context.Background(), outputDir, filesToWrite, fillMissing && !force, writeConcurrency); err != nil {
+		return err
+	}
+
+	if postHook != "" {
+		if err := runPostHook(postHook, outputDir); err != nil {
+			return err
+		}
+	}
+
+	if signingKey != "" {
+		return signPromiseFiles(outputDir, filesToWrite, signingKey)
+	}
+	return nil
+}
+
+// reportValidation checks the generated API with --validate-only, once the
+// Promise is generated in memory, and prints a summary.
+func reportValidation(crd *apiextensionsv1.CustomResourceDefinition, dependencies []v1alpha1.Dependency) error {
+	if err := validateStructuralSchemas(crd); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+	fmt.Printf("Validation passed: API %s and %d dependencies; no files written\n", crd.Name, len(dependencies))
+	return nil
+}
+
+// operatorPromiseConfig is the configuration printed with --print-config, once
+// the defaults, such as the plural and the stored version, are applied.
+type operatorPromiseConfig struct {
+	PromiseName       string            `json:"promiseName"`
+	OperatorManifests []string          `json:"operatorManifests"`
+	OutputDir         string            `json:"outputDir"`
+	CRD               string            `json:"crd,omitempty"`
+	OperatorGroup     string            `json:"operatorGroup,omitempty"`
+	OperatorVersion   string            `json:"operatorVersion,omitempty"`
+	OperatorKind      string            `json:"operatorKind,omitempty"`
+	Group             string            `json:"group,omitempty"`
+	Version           string            `json:"version,omitempty"`
+	Kind              string            `json:"kind,omitempty"`
+	Plural            string            `json:"plural,omitempty"`
+	Scope             string            `json:"scope,omitempty"`
+	Images            map[string]string `json:"images,omitempty"`
+	Toggles           map[string]bool   `json:"toggles"`
+}
+
+// newOperatorPromiseConfig collects the configuration of the Promise generated
+// from crd, which is nil with --dependencies-only. The operator CRD is read from
+// the pipeline envs, as crd is already updated. The images are keyed by
+// LIFECYCLE/ACTION/CONTAINER.
+func newOperatorPromiseConfig(promiseName string, crd *apiextensionsv1.CustomResourceDefinition, envs []corev1.EnvVar, steps []v1alpha1.Container, extraWorkflows []operatorWorkflow) operatorPromiseConfig {
+	config := operatorPromiseConfig{
+		PromiseName:       promiseName,
+		OperatorManifests: operatorManifestsDirs,
+		OutputDir:         outputDir,
+	}
+	if crd != nil {
+		config.CRD = targetCrdName
+		config.Group = crd.Spec.Group
+		config.Version = crd.Spec.Versions[0].Name
+		config.Kind = crd.Spec.Names.Kind
+		config.Plural = crd.Spec.Names.Plural
+		config.Scope = string(crd.Spec.Scope)
+		for _, env := range envs {
+			switch env.Name {
+			case operatorGroupEnv:
+				config.OperatorGroup = env.Value
+			case operatorVersionEnv:
+				config.OperatorVersion = env.Value
+			case operatorKindEnv:
+				config.OperatorKind = env.Value
+			}
+		}
+	}
+	if len(steps) > 0 || len(extraWorkflows) > 0 {
+		config.Images = map[string]string{}
+	}
+	for _, step := range steps {
+		config.Images["resource/configure/"+step.Name] = step.Image
+	}
+	for _, workflow := range extraWorkflows {
+		config.Images[workflow.lifecycle+"/"+workflow.action+"/"+operatorContainerName] = workflow.image
+	}
+	return config
+}
+
+// printOperatorPromiseConfig prints the configuration and the value of every
+// boolean flag to stderr, with --print-config.
+func printOperatorPromiseConfig(cmd *cobra.Command, config operatorPromiseConfig) error {
+	if !printConfig {
+		return nil
+	}
+
+	config.Toggles = map[string]bool{}
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if flag.Value.Type() != "bool" || flag.Name == "help" || flag.Name == "print-config" {
+			return
+		}
+		config.Toggles[flag.Name] = flag.Value.String() == "true"
+	})
+
+	configBytes, err := yamlsig.Marshal(config)
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(os.Stderr, string(configBytes))
+	return nil
+}
+
+// printGeneratedCRDDiff prints the unified diff between the operator CRD and
+// the generated API to stderr, with --print-crd-diff.
+func printGeneratedCRDDiff(operatorCRD, crd *apiextensionsv1.CustomResourceDefinition) error {
+	operatorBytes, err := yamlsig.Marshal(operatorCRD)
+	if err != nil {
+		return err
+	}
+	crdBytes, err := yamlsig.Marshal(crd)
+	if err != nil {
+		return err
+	}
 
-	flags := fmt.Sprintf("--operator-manifests %s --api-schema-from %s", operatorManifestsDir, targetCrdName)
-	filesToWrite, err := getFilesToWrite(promiseName, split, workflowDirectory, flags, nil, dependencies, crd, pipelines, exampleResource)
+	diff := unifiedDiff(operatorCRD.Name, apiFileName, string(operatorBytes), string(crdBytes))
+	if diff == "" {
+		fmt.Fprintf(os.Stderr, "The generated API is identical to CRD %s\n", operatorCRD.Name)
+		return nil
+	}
+	fmt.Fprint(os.Stderr, diff)
+	return nil
+}
+
+// writeFormSchema writes the fields of the API, flattened for developer portal
+// forms, to path.
+func writeFormSchema(path string, crd *apiextensionsv1.CustomResourceDefinition) error {
+	formBytes, err := json.MarshalIndent(map[string]any{
+		"kind":   crd.Spec.Names.Kind,
+		"fields": formSchema(crd.Spec.Versions[0].Schema.OpenAPIV3Schema),
+	}, "", "  ")
 	if err != nil {
 		return err
 	}
-
-	err = writePromiseFiles(outputDir, filesToWrite)
-	if err != nil {
-		return err
+	if err := os.WriteFile(path, append(formBytes, '\n'), filePerm); err != nil {
+		return fmt.Errorf("failed to write form schema: %w", err)
+	}
+	return nil
+}
+
+// confirmOverwrite asks for confirmation before generating the Promise in a
+// non-empty directory. It never prompts when not interactive, so scripts keep
+// overwriting the files.
+func confirmOverwrite(dir string, interactive bool, in io.Reader, out io.Writer) error {
+	if !interactive {
+		return nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(out, "Directory %s not empty, overwrite? [y/N] ", dir)
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+		return fmt.Errorf("aborted: %s is not empty; use --force to overwrite it or --fill-missing to only write the missing files", dir)
+	}
+	return nil
+}
+
+// generateCodeOwners assigns every generated file, and the CODEOWNERS file
+// itself, to owner.
+func generateCodeOwners(outputDir, owner string, filesToWrite map[string]any) ([]byte, error) {
+	if strings.ContainsAny(owner, " \t\n") {
+		return nil, fmt.Errorf("invalid owner %q: must not contain whitespace", owner)
+	}
+	if !strings.Contains(owner, "@") {
+		owner = "@" + owner
+	}
+
+	prefix := "/"
+	if cleanDir := filepath.ToSlash(filepath.Clean(outputDir)); cleanDir != "." && !filepath.IsAbs(outputDir) {
+		prefix = "/" + strings.TrimPrefix(cleanDir, "./") + "/"
+	}
+
+	paths := []string{codeOwnersFileName}
+	for key, value := range filesToWrite {
+		if _, isDir := value.(map[string]any); isDir {
+			key += "/"
+		}
+		paths = append(paths, key)
+	}
+	sort.Strings(paths)
+
+	codeOwners := bytes.NewBufferString("# Generated by kratix init operator-promise\n")
+	for _, path := range paths {
+		fmt.Fprintf(codeOwners, "%s%s %s\n", prefix, path, owner)
+	}
+	return codeOwners.Bytes(), nil
+}
+
+func findTargetCRD(crdName string, dependencies []v1alpha1.Dependency) (*apiextensionsv1.CustomResourceDefinition, error) {
+	return newCRDIndex(dependencies).find(crdName)
+}
+
+// findTargetCRDs looks up several CRDs, indexing the dependencies only once.
+func findTargetCRDs(crdNames []string, dependencies []v1alpha1.Dependency) ([]*apiextensionsv1.CustomResourceDefinition, error) {
+	index := newCRDIndex(dependencies)
+	var crds []*apiextensionsv1.CustomResourceDefinition
+	for _, crdName := range crdNames {
+		crd, err := index.find(crdName)
+		if err != nil {
+			return nil, err
+		}
+		crds = append(crds, crd)
+	}
+	return crds, nil
+}
+
+// crdIndex indexes the CRDs in the dependencies both by name and by the
+// KIND.GROUP form, e.g. "Cluster.postgresql.cnpg.io", so that CRDs sharing a
+// kind can be told apart.
+type crdIndex struct {
+	names          []string
+	byName         map[string]v1alpha1.Dependency
+	byKindAndGroup map[string][]v1alpha1.Dependency
+}
+
+func newCRDIndex(dependencies []v1alpha1.Dependency) *crdIndex {
+	index := &crdIndex{
+		names:          []string{},
+		byName:         map[string]v1alpha1.Dependency{},
+		byKindAndGroup: map[string][]v1alpha1.Dependency{},
+	}
+	for _, dep := range dependencies {
+		if dep.GetKind() != "CustomResourceDefinition" {
+			continue
+		}
+		index.names = append(index.names, dep.GetName())
+		if _, found := index.byName[dep.GetName()]; !found {
+			index.byName[dep.GetName()] = dep
+		}
+		kindAndGroup := crdKindAndGroup(dep.Object)
+		index.byKindAndGroup[kindAndGroup] = append(index.byKindAndGroup[kindAndGroup], dep)
+	}
+	return index
+}
+
+func (i *crdIndex) find(crdName string) (*apiextensionsv1.CustomResourceDefinition, error) {
+	var candidates []v1alpha1.Dependency
+	if dep, found := i.byName[crdName]; found {
+		candidates = []v1alpha1.Dependency{dep}
+	} else if strings.Contains(crdName, ".") {
+		candidates = i.byKindAndGroup[crdName]
+	}
+
+	switch len(candidates) {
+	case 0:
+		return nil, newCLIError(errCodeCRDNotFound, map[string]any{"availableCRDs": i.names}, "no CRD found matching name: %s", crdName)
+	case 1:
+	default:
+		var qualifiedNames []string
+		for _, candidate := range candidates {
+			qualifiedNames = append(qualifiedNames, fmt.Sprintf("%s (%s)", crdKindAndGroup(candidate.Object), candidate.GetName()))
+		}
+		return nil, newCLIError(errCodeCRDAmbiguous, map[string]any{"candidates": qualifiedNames}, "more than one CRD found matching %s: %s", crdName, strings.Join(qualifiedNames, ", "))
+	}
+
+	crdAsBytes, err := json.Marshal(candidates[0].Object)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal CRD: %w", err)
+	}
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	if err := json.Unmarshal(crdAsBytes, crd); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal CRD: %w", err)
+	}
+	if err := validateCRDStructure(crd); err != nil {
+		return nil, err
+	}
+	return crd, nil
+}
+
+// validateCRDStructure checks the fields the Promise API is generated from, so a
+// malformed CRD errors here rather than when its versions are indexed.
+func validateCRDStructure(crd *apiextensionsv1.CustomResourceDefinition) error {
+	if crd.Spec.Group == "" {
+		return fmt.Errorf("CRD %s has an empty group", crd.Name)
+	}
+	if crd.Spec.Names.Kind == "" {
+		return fmt.Errorf("CRD %s has an empty kind", crd.Name)
+	}
+	if len(crd.Spec.Versions) == 0 {
+		return newCLIError(errCodeCRDNoVersions, map[string]any{"crd": crd.Name}, "CRD %s has no versions", crd.Name)
+	}
+	for _, crdVersion := range crd.Spec.Versions {
+		if crdVersion.Name == "" {
+			return fmt.Errorf("CRD %s has a version with empty name", crd.Name)
+		}
+		if crdVersion.Schema == nil || crdVersion.Schema.OpenAPIV3Schema == nil {
+			return fmt.Errorf("version %s of CRD %s has no schema", crdVersion.Name, crd.Name)
+		}
 	}
-
-	fmt.Println("Promise generated successfully.")
-	fmt.Println("The Operator documents were added as inline dependencies in the Promise Spec.")
-	fmt.Println("You can move them to a workflow by running:")
-	fmt.Printf("\tkratix update dependencies %s --image yourorg/your-image:tag\n", operatorManifestsDir)
 	return nil
 }
 
-func findTargetCRD(crdName string, dependencies []v1alpha1.Dependency) (*apiextensionsv1.CustomResourceDefinition, error) {
-	var crd *apiextensionsv1.CustomResourceDefinition
-	for _, dep := range dependencies {
-		if dep.GetKind() == "CustomResourceDefinition" && dep.GetName() == crdName {
-			crdAsBytes, err := json.Marshal(dep.Object)
-			if err != nil {
-				return nil, fmt.Errorf("failed to marshal CRD: %w", err)
-			}
-			crd = &apiextensionsv1.CustomResourceDefinition{}
-			if err := json.Unmarshal(crdAsBytes, crd); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal CRD: %w", err)
-			}
-			break
-		}
-	}
-	if crd == nil {
-		return nil, fmt.Errorf("no CRD found matching name: %s", targetCrdName)
-	}
-	return crd, nil
+func crdKindAndGroup(crd map[string]any) string {
+	crdKind, _, _ := unstructured.NestedString(crd, "spec", "names", "kind")
+	crdGroup, _, _ := unstructured.NestedString(crd, "spec", "group")
+	return fmt.Sprintf("%s.%s", crdKind, crdGroup)
 }
 
 func findStoredVersionIdx(crd *apiextensionsv1.CustomResourceDefinition) int {
@@ -155,10 +1172,70 @@ func findStoredVersionIdx(crd *apiextensionsv1.CustomResourceDefinition) int {
 	return storedVersionIdx
 }
 
-func updateOperatorCrd(crd *apiextensionsv1.CustomResourceDefinition, storedVersionIdx int, group string, names apiextensionsv1.CustomResourceDefinitionNames, version string) {
+func crdTypeMetaFor(version string) (metav1.TypeMeta, error) {
+	apiVersion, found := supportedCRDAPIVersions[version]
+	if !found {
+		var versions []string
+		for supported := range supportedCRDAPIVersions {
+			versions = append(versions, supported)
+		}
+		sort.Strings(versions)
+		return metav1.TypeMeta{}, fmt.Errorf("invalid --crd-api-version %q: must be one of %s", version, strings.Join(versions, ", "))
+	}
+	return metav1.TypeMeta{APIVersion: apiVersion, Kind: "CustomResourceDefinition"}, nil
+}
+
+// stripKubebuilderAnnotations removes the annotations in the kubebuilder.io
+// domain and its subdomains, such as controller-gen.kubebuilder.io/version:
+// they describe how the operator CRD was generated, not the Promise API.
+func stripKubebuilderAnnotations(crd *apiextensionsv1.CustomResourceDefinition) {
+	annotations := crd.GetAnnotations()
+	for key := range annotations {
+		domain, _, found := strings.Cut(key, "/")
+		if found && (domain == "kubebuilder.io" || strings.HasSuffix(domain, ".kubebuilder.io")) {
+			delete(annotations, key)
+		}
+	}
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+	crd.SetAnnotations(annotations)
+}
+
+// validateCRDName checks the CRD name is the lowercase <plural>.<group>, as
+// required by Kubernetes.
+func validateCRDName(name, plural, group string) error {
+	expected := strings.ToLower(fmt.Sprintf("%s.%s", plural, group))
+	if name != expected {
+		return fmt.Errorf("invalid CRD name %s: must be the lowercase <plural>.<group>; set --plural %s and --group %s", name, strings.ToLower(plural), strings.ToLower(group))
+	}
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return fmt.Errorf("invalid CRD name %s: %s; change --plural or --group", name, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+func updateOperatorCrd(crd *apiextensionsv1.CustomResourceDefinition, storedVersionIdx int, group string, names apiextensionsv1.CustomResourceDefinitionNames, version string) error {
+	logVerbose("CRD %s is %s-scoped; the generated API keeps the same scope", crd.Name, crd.Spec.Scope)
+	if requireScope != "" && string(crd.Spec.Scope) != requireScope {
+		return fmt.Errorf("CRD %s is %s-scoped but --require-scope is %s: use the CRD of a %s-scoped resource, or remove --require-scope to keep its scope",
+			crd.Name, crd.Spec.Scope, requireScope, requireScope)
+	}
+
+	crdName := fmt.Sprintf("%s.%s", names.Plural, group)
+	if err := validateCRDName(crdName, names.Plural, group); err != nil {
+		if strictCRDName {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", err)
+	}
+
 	crd.Spec.Names = names
-	crd.Name = fmt.Sprintf("%s.%s", names.Plural, group)
+	crd.Name = crdName
 	crd.Spec.Group = group
+	if !preserveCRDAnnotations {
+		stripKubebuilderAnnotations(crd)
+	}
 
 	storedVersion := crd.Spec.Versions[storedVersionIdx]
 
@@ -169,6 +1246,7 @@ func updateOperatorCrd(crd *apiextensionsv1.CustomResourceDefinition, storedVers
 	storedVersion.Name = version
 	storedVersion.Storage = true
 	storedVersion.Served = true
+	ensureMetadataSchema(storedVersion.Schema.OpenAPIV3Schema)
 	storedVersion.Schema.OpenAPIV3Schema.Properties["kind"] = apiextensionsv1.JSONSchemaProps{
 		Type: "string",
 		Enum: []apiextensionsv1.JSON{{Raw: []byte(fmt.Sprintf("%q", kind))}},
@@ -180,61 +1258,549 @@ func updateOperatorCrd(crd *apiextensionsv1.CustomResourceDefinition, storedVers
 	crd.Spec.Versions = []apiextensionsv1.CustomResourceDefinitionVersion{
 		storedVersion,
 	}
+	return nil
+}
+
+func parseStatusFields(fields []string) ([][]string, error) {
+	var paths [][]string
+	for _, field := range fields {
+		segments := strings.Split(strings.TrimPrefix(field, "status."), ".")
+		for _, segment := range segments {
+			if !statusFieldSegment.MatchString(segment) {
+				return nil, newCLIError(errCodeInvalidStatusField, map[string]any{"field": field}, "invalid status field %q: %q is not a valid property name", field, segment)
+			}
+		}
+		paths = append(paths, segments)
+	}
+	return paths, nil
+}
+
+// addStatusFields makes sure every path exists under the status property of
+// the version schema and enables the status subresource.
+func addStatusFields(crdVersion *apiextensionsv1.CustomResourceDefinitionVersion, paths [][]string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+
+	rootSchema := crdVersion.Schema.OpenAPIV3Schema
+	status := rootSchema.Properties["status"]
+	for _, path := range paths {
+		var err error
+		status, err = ensureSchemaPath(status, path, strings.Join(path, "."))
+		if err != nil {
+			return err
+		}
+	}
+	rootSchema.Properties["status"] = status
+	crdVersion.Subresources = &apiextensionsv1.CustomResourceSubresources{
+		Status: &apiextensionsv1.CustomResourceSubresourceStatus{},
+	}
+	return nil
+}
+
+// deprecateVersions marks the versions named by the NAME[=MESSAGE] values as
+// deprecated, with MESSAGE as their deprecation warning when set.
+func deprecateVersions(crd *apiextensionsv1.CustomResourceDefinition, values []string) error {
+	for _, value := range values {
+		name, message, hasMessage := strings.Cut(value, "=")
+		if name == "" {
+			return fmt.Errorf("invalid --deprecate-version %q: expected NAME[=MESSAGE]", value)
+		}
+
+		idx := slices.IndexFunc(crd.Spec.Versions, func(crdVersion apiextensionsv1.CustomResourceDefinitionVersion) bool {
+			return crdVersion.Name == name
+		})
+		if idx == -1 {
+			var kept []string
+			for _, crdVersion := range crd.Spec.Versions {
+				kept = append(kept, crdVersion.Name)
+			}
+			return fmt.Errorf("invalid --deprecate-version %q: version %s is not kept in the generated CRD, which has versions %s", value, name, strings.Join(kept, ", "))
+		}
+
+		crd.Spec.Versions[idx].Deprecated = true
+		if hasMessage {
+			crd.Spec.Versions[idx].DeprecationWarning = &message
+		}
+	}
+	return nil
+}
+
+// printerColumnTypes are the types of the additionalPrinterColumns accepted by
+// Kubernetes.
+var printerColumnTypes = []string{"integer", "number", "string", "boolean", "date"}
+
+// addPrinterColumns appends the printer columns, given as NAME:JSONPATH:TYPE,
+// to the columns of the version kept from the operator CRD.
+func addPrinterColumns(crdVersion *apiextensionsv1.CustomResourceDefinitionVersion, values []string) error {
+	for _, value := range values {
+		name, rest, _ := strings.Cut(value, ":")
+		jsonPath, columnType, found := cutLast(rest, ":")
+		if name == "" || !found || jsonPath == "" || columnType == "" {
+			return fmt.Errorf("invalid --printer-column %q: expected NAME:JSONPATH:TYPE", value)
+		}
+		if !slices.Contains(printerColumnTypes, columnType) {
+			return fmt.Errorf("invalid --printer-column %q: type must be one of %s", value, strings.Join(printerColumnTypes, ", "))
+		}
+		if _, err := jsonpath.Parse(name, "{"+jsonPath+"}"); err != nil {
+			return fmt.Errorf("invalid --printer-column %q: invalid JSONPath: %s", value, err)
+		}
+		if slices.ContainsFunc(crdVersion.AdditionalPrinterColumns, func(column apiextensionsv1.CustomResourceColumnDefinition) bool {
+			return column.Name == name
+		}) {
+			return fmt.Errorf("invalid --printer-column %q: the generated CRD already has a column named %s", value, name)
+		}
+
+		crdVersion.AdditionalPrinterColumns = append(crdVersion.AdditionalPrinterColumns, apiextensionsv1.CustomResourceColumnDefinition{
+			Name:     name,
+			Type:     columnType,
+			JSONPath: jsonPath,
+		})
+	}
+	return nil
+}
+
+// cutLast slices s around the last instance of sep.
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
 }
 
-func writePromiseFiles(outputDir string, filesToWrite map[string]any) error {
+func ensureSchemaPath(schema apiextensionsv1.JSONSchemaProps, segments []string, fullPath string) (apiextensionsv1.JSONSchemaProps, error) {
+	if len(segments) == 0 {
+		return schema, nil
+	}
+
+	if schema.Type != "" && schema.Type != "object" {
+		return schema, newCLIError(errCodeInvalidStatusField, map[string]any{"field": fullPath}, "invalid status field %q: cannot add %q to a property of type %s", fullPath, segments[0], schema.Type)
+	}
+	schema.Type = "object"
+
+	child, found := schema.Properties[segments[0]]
+	if !found && schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil {
+		// maps already accept any key, the value schema only needs to cover
+		// the rest of the path
+		_, err := ensureSchemaPath(*schema.AdditionalProperties.Schema, segments[1:], fullPath)
+		return schema, err
+	}
+
+	if !found {
+		child = apiextensionsv1.JSONSchemaProps{Type: "string"}
+		if len(segments) > 1 {
+			child.Type = "object"
+		}
+	}
+
+	child, err := ensureSchemaPath(child, segments[1:], fullPath)
+	if err != nil {
+		return schema, err
+	}
+
+	if schema.Properties == nil {
+		schema.Properties = map[string]apiextensionsv1.JSONSchemaProps{}
+	}
+	schema.Properties[segments[0]] = child
+	return schema, nil
+}
+
+func writePromiseFiles(outputDir string, filesToWrite map[string]any, skipExisting bool) error {
 	if _, err := os.Stat(outputDir); os.IsNotExist(err) {
 		if err := os.MkdirAll(outputDir, os.ModePerm); err != nil {
 			return err
 		}
 	}
 
-	for key, value := range filesToWrite {
-		switch v := value.(type) {
+	var keys []string
+	for key := range filesToWrite {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		switch v := filesToWrite[key].(type) {
 		case map[string]any:
 			subdir := filepath.Join(outputDir, key)
 			if err := os.MkdirAll(subdir, os.ModePerm); err != nil {
 				return err
 			}
-			if err := writePromiseFiles(subdir, v); err != nil {
+			if err := writePromiseFiles(subdir, v, skipExisting); err != nil {
+				return err
+			}
+		case []byte:
+			if err := writePromiseFile(filepath.Join(outputDir, key), v, skipExisting); err != nil {
 				return err
 			}
 		default:
+			// yamlsig goes through encoding/json, which sorts map keys such
+			// as the CRD properties, so the output is reproducible
 			fileContentBytes, err := yamlsig.Marshal(v)
 			if err != nil {
 				return err
 			}
-			if err = os.WriteFile(filepath.Join(outputDir, key), fileContentBytes, filePerm); err != nil {
+			if err := writePromiseFile(filepath.Join(outputDir, key), fileContentBytes, skipExisting); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type promiseFile struct {
+	path    string
+	content []byte
+}
+
+// writePromiseFilesConcurrently writes the files with up to concurrency
+// workers. All the directories are created before any file is written. The
+// first error cancels the files not yet written.
+func writePromiseFilesConcurrently(ctx context.Context, outputDir string, filesToWrite map[string]any, skipExisting bool, concurrency int) error {
+	if concurrency <= 1 {
+		return writePromiseFiles(outputDir, filesToWrite, skipExisting)
+	}
+
+	files, err := collectPromiseFiles(outputDir, filesToWrite)
+	if err != nil {
+		return err
+	}
+
+	group, ctx := errgroup.WithContext(ctx)
+	group.SetLimit(concurrency)
+	for _, file := range files {
+		group.Go(func() error {
+			if err := ctx.Err(); err != nil {
 				return err
 			}
+			return writePromiseFile(file.path, file.content, skipExisting)
+		})
+	}
+	return group.Wait()
+}
+
+// collectPromiseFiles creates the directories of filesToWrite and returns the
+// files to write in them, in the order writePromiseFiles writes them.
+func collectPromiseFiles(outputDir string, filesToWrite map[string]any) ([]promiseFile, error) {
+	if err := os.MkdirAll(outputDir, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for key := range filesToWrite {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var files []promiseFile
+	for _, key := range keys {
+		switch v := filesToWrite[key].(type) {
+		case map[string]any:
+			subdirFiles, err := collectPromiseFiles(filepath.Join(outputDir, key), v)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, subdirFiles...)
+		case []byte:
+			files = append(files, promiseFile{path: filepath.Join(outputDir, key), content: v})
+		default:
+			fileContentBytes, err := yamlsig.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, promiseFile{path: filepath.Join(outputDir, key), content: fileContentBytes})
+		}
+	}
+	return files, nil
+}
+
+func writePromiseFile(filePath string, content []byte, skipExisting bool) error {
+	if skipExisting {
+		if _, err := os.Stat(filePath); err == nil {
+			fmt.Printf("Skipped %s: file already exists\n", filePath)
+			return nil
 		}
 	}
+	return os.WriteFile(filePath, content, filePerm)
+}
+
+// runPostHook runs the hook with sh -c, so its arguments can be quoted, with
+// the output directory appended as its last argument.
+func runPostHook(hook, dir string) error {
+	if strings.TrimSpace(hook) == "" {
+		return fmt.Errorf("invalid post-hook: %q", hook)
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+
+	hookCmd := exec.Command("sh", "-c", hook+` "$@"`, "sh", absDir)
+	hookCmd.Dir = absDir
+	hookCmd.Stdout = os.Stdout
+	hookCmd.Stderr = os.Stderr
+	if err := hookCmd.Run(); err != nil {
+		return fmt.Errorf("post-hook %q failed: %w", hook, err)
+	}
 	return nil
 }
 
 func generateResourceConfigurePipelines(containerName, containerImage string, envs []corev1.EnvVar) []unstructured.Unstructured {
-	container := v1alpha1.Container{
-		Name:  containerName,
-		Image: containerImage,
-		Env:   envs,
+	return generateResourceConfigurePipelineSteps([]v1alpha1.Container{{Name: containerName, Image: containerImage}}, envs, nil)
+}
+
+// generateResourceConfigurePipelineSteps generates a pipeline running the
+// containers in order, each with the given envs, pulling their images with
+// the given secrets.
+func generateResourceConfigurePipelineSteps(steps []v1alpha1.Container, envs []corev1.EnvVar, pullSecrets []string) []unstructured.Unstructured {
+	return generatePipelineSteps("instance-configure", steps, envs, pullSecrets)
+}
+
+func generatePipelineSteps(pipelineName string, steps []v1alpha1.Container, envs []corev1.EnvVar, pullSecrets []string) []unstructured.Unstructured {
+	var containers []any
+	for _, step := range steps {
+		step.Env = envs
+		containers = append(containers, step)
 	}
 
 	pipeline := unstructured.Unstructured{
 		Object: map[string]any{
-			"apiVersion": "platform.kratix.io/v1alpha1",
-			"kind":       "Pipeline",
+			"apiVersion": kratixGroupVersion(),
+			"kind":       kratixPipelineKind,
 			"metadata": map[string]any{
-				"name": "instance-configure",
+				"name": pipelineName,
 			},
 			"spec": map[string]any{
-				"containers": []any{container},
+				"containers": containers,
 			},
 		},
 	}
 
+	if len(pullSecrets) > 0 {
+		var secrets []any
+		for _, secret := range pullSecrets {
+			secrets = append(secrets, map[string]any{"name": secret})
+		}
+		pipeline.Object["spec"].(map[string]any)["imagePullSecrets"] = secrets
+	}
+
 	return []unstructured.Unstructured{pipeline}
 }
 
+// operatorWorkflow is a workflow set with --workflow, running a single
+// container.
+type operatorWorkflow struct {
+	lifecycle, action, image string
+}
+
+func (w operatorWorkflow) directory() string {
+	return filepath.Join("workflows", w.lifecycle, w.action)
+}
+
+// pipelineName follows the name of the default resource configure pipeline.
+func (w operatorWorkflow) pipelineName() string {
+	if w.lifecycle == "resource" {
+		return "instance-" + w.action
+	}
+	return "promise-" + w.action
+}
+
+// parseWorkflows parses LIFECYCLE/ACTION=IMAGE workflows, sorted by lifecycle
+// and action.
+func parseWorkflows(workflows []string) ([]operatorWorkflow, error) {
+	var parsed []operatorWorkflow
+	seen := map[string]bool{}
+	for _, workflow := range workflows {
+		lifecycleAction, image, _ := strings.Cut(workflow, "=")
+		lifecycle, action, _ := strings.Cut(lifecycleAction, "/")
+		if image == "" || !slices.Contains([]string{"promise", "resource"}, lifecycle) || !slices.Contains([]string{"configure", "delete"}, action) {
+			return nil, fmt.Errorf("invalid workflow %q: expected LIFECYCLE/ACTION=IMAGE, with a promise or resource lifecycle and a configure or delete action", workflow)
+		}
+		if seen[lifecycleAction] {
+			return nil, fmt.Errorf("invalid workflow %q: the %s workflow is already set", workflow, lifecycleAction)
+		}
+		seen[lifecycleAction] = true
+		parsed = append(parsed, operatorWorkflow{lifecycle: lifecycle, action: action, image: image})
+	}
+	sort.Slice(parsed, func(i, j int) bool {
+		return parsed[i].directory() < parsed[j].directory()
+	})
+	return parsed, nil
+}
+
+// addLifecycleWorkflows adds the promise/configure, promise/delete and
+// resource/delete workflows missing from workflows, running the default
+// container.
+func addLifecycleWorkflows(workflows []operatorWorkflow) []operatorWorkflow {
+	for _, lifecycleAction := range []string{"promise/configure", "promise/delete", "resource/delete"} {
+		lifecycle, action, _ := strings.Cut(lifecycleAction, "/")
+		if !slices.ContainsFunc(workflows, func(w operatorWorkflow) bool { return w.lifecycle == lifecycle && w.action == action }) {
+			workflows = append(workflows, operatorWorkflow{lifecycle: lifecycle, action: action, image: operatorContainerImage})
+		}
+	}
+	sort.Slice(workflows, func(i, j int) bool {
+		return workflows[i].directory() < workflows[j].directory()
+	})
+	return workflows
+}
+
+// workflowEnvs adds the workflowActionEnv of workflow to envs.
+func workflowEnvs(envs []corev1.EnvVar, workflow operatorWorkflow) []corev1.EnvVar {
+	return append(slices.Clone(envs), corev1.EnvVar{Name: workflowActionEnv, Value: workflow.lifecycle + "/" + workflow.action})
+}
+
+func setPromiseWorkflow(promise *v1alpha1.Promise, lifecycle, action string, pipelines []unstructured.Unstructured) {
+	triggers := &promise.Spec.Workflows.Resource
+	if lifecycle == "promise" {
+		triggers = &promise.Spec.Workflows.Promise
+	}
+	if action == "configure" {
+		triggers.Configure = pipelines
+	} else {
+		triggers.Delete = pipelines
+	}
+}
+
+// parsePipelineSteps parses NAME=IMAGE steps. Without steps, the pipeline runs
+// the default operator container.
+func parsePipelineSteps(pipelineSteps []string) ([]v1alpha1.Container, error) {
+	if len(pipelineSteps) == 0 {
+		return []v1alpha1.Container{{Name: operatorContainerName, Image: operatorContainerImage}}, nil
+	}
+
+	var steps []v1alpha1.Container
+	names := map[string]bool{}
+	for _, pipelineStep := range pipelineSteps {
+		name, image, _ := strings.Cut(pipelineStep, "=")
+		if image == "" {
+			return nil, fmt.Errorf("invalid pipeline step %q: expected NAME=IMAGE", pipelineStep)
+		}
+		if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid pipeline step %q: invalid name: %s", pipelineStep, strings.Join(errs, ", "))
+		}
+		if names[name] {
+			return nil, fmt.Errorf("invalid pipeline step %q: a step named %s already exists", pipelineStep, name)
+		}
+		names[name] = true
+		steps = append(steps, v1alpha1.Container{Name: name, Image: image})
+	}
+	return steps, nil
+}
+
+// loadOperatorBaseSpec reads the operator spec of the YAML or JSON file at
+// path, returning it as compact JSON. Without a path, it returns "".
+func loadOperatorBaseSpec(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+
+	specBytes, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read operator base spec %s: %s", path, err)
+	}
+
+	var spec any
+	if err := yamlsig.Unmarshal(specBytes, &spec); err != nil {
+		return "", fmt.Errorf("failed to parse operator base spec %s: %s", path, err)
+	}
+	if _, ok := spec.(map[string]any); !ok {
+		return "", fmt.Errorf("failed to parse operator base spec %s: expected an object", path)
+	}
+
+	specJSON, err := json.Marshal(spec)
+	if err != nil {
+		return "", err
+	}
+	return string(specJSON), nil
+}
+
+// loadPluralDictionary reads the kind to plural mapping of the YAML file at
+// path. Without a path, the dictionary is empty.
+func loadPluralDictionary(path string) (map[string]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	dictionaryBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plural dictionary: %w", err)
+	}
+
+	var plurals map[string]string
+	if err := yamlsig.UnmarshalStrict(dictionaryBytes, &plurals); err != nil {
+		return nil, fmt.Errorf("failed to parse plural dictionary %s: expected a mapping of kinds to plurals: %w", path, err)
+	}
+	return plurals, nil
+}
+
+// pluralFor returns the plural of kind from the dictionary, matching the kind
+// exactly first and then ignoring case, falling back to the lowercase kind
+// followed by "s".
+func pluralFor(kind string, plurals map[string]string) string {
+	if plural, found := plurals[kind]; found {
+		return plural
+	}
+	var kinds []string
+	for dictionaryKind := range plurals {
+		kinds = append(kinds, dictionaryKind)
+	}
+	sort.Strings(kinds)
+	for _, dictionaryKind := range kinds {
+		if strings.EqualFold(dictionaryKind, kind) {
+			return plurals[dictionaryKind]
+		}
+	}
+	return fmt.Sprintf("%ss", strings.ToLower(kind))
+}
+
+// parseRequiredPromises parses NAME:VERSION required Promises.
+func parseRequiredPromises(requires []string) ([]v1alpha1.RequiredPromise, error) {
+	var requiredPromises []v1alpha1.RequiredPromise
+	for _, required := range requires {
+		name, version, _ := strings.Cut(required, ":")
+		if name == "" || version == "" {
+			return nil, fmt.Errorf("invalid required Promise %q: expected NAME:VERSION", required)
+		}
+		if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid required Promise %q: invalid name: %s", required, strings.Join(errs, ", "))
+		}
+		requiredPromises = append(requiredPromises, v1alpha1.RequiredPromise{Name: name, Version: version})
+	}
+	return requiredPromises, nil
+}
+
+// setPipelineCommand sets the command and args of the resource configure
+// container. Args without a command are passed to the entrypoint of the image.
+func setPipelineCommand(steps []v1alpha1.Container, command string, args []string) error {
+	if command == "" && len(args) == 0 {
+		return nil
+	}
+	if len(steps) > 1 {
+		return fmt.Errorf("--pipeline-command and --pipeline-arg set the command of a single container: cannot be used with %d pipeline steps", len(steps))
+	}
+
+	if command != "" {
+		steps[0].Command = []string{command}
+	}
+	steps[0].Args = args
+	return nil
+}
+
+func setImagePullPolicy(steps []v1alpha1.Container, policy string) error {
+	switch corev1.PullPolicy(policy) {
+	case "":
+		return nil
+	case corev1.PullAlways, corev1.PullIfNotPresent, corev1.PullNever:
+	default:
+		return fmt.Errorf("invalid --image-pull-policy %q: must be one of Always, IfNotPresent, Never", policy)
+	}
+
+	for i := range steps {
+		steps[i].ImagePullPolicy = corev1.PullPolicy(policy)
+	}
+	return nil
+}
+
 func topLevelRequiredFields(crd *apiextensionsv1.CustomResourceDefinition) map[string]any {
 	crdSpec := crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"]
 	requiredSpecFields := crdSpec.Required
@@ -250,20 +1816,7 @@ func topLevelRequiredFields(crd *apiextensionsv1.CustomResourceDefinition) map[s
 }
 
 func getFilesToWrite(promiseName string, split bool, workflowDirectory, extraFlags string, destinationSelectors []v1alpha1.PromiseScheduling, dependencies []v1alpha1.Dependency, crd *apiextensionsv1.CustomResourceDefinition, workflow []unstructured.Unstructured, exampleResource *unstructured.Unstructured) (map[string]any, error) {
-	readmeTemplate, err := template.ParseFS(promiseTemplates, "templates/promise/README.md.tpl")
-	if err != nil {
-		return nil, err
-	}
-
-	templatedReadme := bytes.NewBuffer([]byte{})
-	err = readmeTemplate.Execute(templatedReadme, promiseTemplateValues{
-		SubCommand: "operator-promise",
-		ExtraFlags: extraFlags,
-		Name:       promiseName,
-		Group:      crd.Spec.Group,
-		Kind:       crd.Spec.Names.Kind,
-	})
-
+	templatedReadme, err := generatePromiseReadme("operator-promise", promiseName, extraFlags, crd)
 	if err != nil {
 		return nil, err
 	}
@@ -276,7 +1829,7 @@ func getFilesToWrite(promiseName string, split bool, workflowDirectory, extraFla
 			workflowDirectory: map[string]any{
 				"workflow.yaml": workflow,
 			},
-			"README.md": templatedReadme.String(),
+			"README.md": templatedReadme,
 		}, nil
 	}
 
@@ -288,10 +1841,32 @@ func getFilesToWrite(promiseName string, split bool, workflowDirectory, extraFla
 	return map[string]any{
 		"promise.yaml":          promise,
 		"example-resource.yaml": exampleResource,
-		"README.md":             templatedReadme.String(),
+		"README.md":             templatedReadme,
 	}, nil
 }
 
+// generatePromiseReadme templates the README of a Promise generated by the
+// given "kratix init" subcommand.
+func generatePromiseReadme(subCommand, promiseName, extraFlags string, crd *apiextensionsv1.CustomResourceDefinition) (string, error) {
+	readmeTemplate, err := template.ParseFS(promiseTemplates, "templates/promise/README.md.tpl")
+	if err != nil {
+		return "", err
+	}
+
+	templatedReadme := bytes.NewBuffer([]byte{})
+	err = readmeTemplate.Execute(templatedReadme, promiseTemplateValues{
+		SubCommand: subCommand,
+		ExtraFlags: extraFlags,
+		Name:       promiseName,
+		Group:      crd.Spec.Group,
+		Kind:       crd.Spec.Names.Kind,
+	})
+	if err != nil {
+		return "", err
+	}
+	return templatedReadme.String(), nil
+}
+
 func generatePromise(promiseName string, destinationSelectors []v1alpha1.PromiseScheduling, dependencies []v1alpha1.Dependency, crd *apiextensionsv1.CustomResourceDefinition, pipelines []unstructured.Unstructured) (v1alpha1.Promise, error) {
 	promise := newPromise(promiseName)
 
@@ -0,0 +1,396 @@
+package cmd_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/syntasso/kratix-cli/cmd"
+	"github.com/syntasso/kratix/api/v1alpha1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var _ = Describe("InitOperatorPromise", func() {
+	Describe("FindTargetCRD", func() {
+		var dependencies []v1alpha1.Dependency
+
+		BeforeEach(func() {
+			dependencies = []v1alpha1.Dependency{
+				crdDependency("clusters.postgresql.cnpg.io", "Cluster", "postgresql.cnpg.io"),
+				crdDependency("clusters.redis.example.com", "Cluster", "redis.example.com"),
+				{Unstructured: unstructured.Unstructured{Object: map[string]any{
+					"apiVersion": "v1",
+					"kind":       "ServiceAccount",
+					"metadata":   map[string]any{"name": "Cluster.redis.example.com"},
+				}}},
+			}
+		})
+
+		It("finds the CRD by name", func() {
+			crd, err := FindTargetCRD("clusters.redis.example.com", dependencies)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(crd.Spec.Group).To(Equal("redis.example.com"))
+		})
+
+		It("finds the CRD by kind and group", func() {
+			crd, err := FindTargetCRD("Cluster.redis.example.com", dependencies)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(crd.Name).To(Equal("clusters.redis.example.com"))
+		})
+
+		It("returns an error when nothing matches", func() {
+			_, err := FindTargetCRD("Cluster", dependencies)
+			Expect(err).To(MatchError("no CRD found matching name: Cluster"))
+		})
+
+		It("lists the group-qualified candidates when more than one CRD matches", func() {
+			dependencies = append(dependencies, crdDependency("clusters.v2.redis.example.com", "Cluster", "redis.example.com"))
+			_, err := FindTargetCRD("Cluster.redis.example.com", dependencies)
+			Expect(err).To(MatchError("more than one CRD found matching Cluster.redis.example.com: " +
+				"Cluster.redis.example.com (clusters.redis.example.com), Cluster.redis.example.com (clusters.v2.redis.example.com)"))
+		})
+	})
+
+	DescribeTable("FindTargetCRD with a malformed CRD",
+		func(mutate func(spec map[string]any), expectedErr string) {
+			crd := crdDependency("clusters.redis.example.com", "Cluster", "redis.example.com")
+			mutate(crd.Object["spec"].(map[string]any))
+			_, err := FindTargetCRD("clusters.redis.example.com", []v1alpha1.Dependency{crd})
+			Expect(err).To(MatchError(expectedErr))
+		},
+		Entry("without a group", func(spec map[string]any) { delete(spec, "group") },
+			"CRD clusters.redis.example.com has an empty group"),
+		Entry("without a kind", func(spec map[string]any) { spec["names"] = map[string]any{"plural": "clusters"} },
+			"CRD clusters.redis.example.com has an empty kind"),
+		Entry("without versions", func(spec map[string]any) { spec["versions"] = []any{} },
+			"CRD clusters.redis.example.com has no versions"),
+		Entry("with a version without a name", func(spec map[string]any) {
+			spec["versions"] = append(spec["versions"].([]any), map[string]any{"served": true, "storage": false})
+		}, "CRD clusters.redis.example.com has a version with empty name"),
+		Entry("with a version without a schema", func(spec map[string]any) {
+			delete(spec["versions"].([]any)[0].(map[string]any), "schema")
+		}, "version v1 of CRD clusters.redis.example.com has no schema"),
+	)
+
+	It("returns the CRDNoVersions error code for a CRD without versions", func() {
+		crd := crdDependency("clusters.redis.example.com", "Cluster", "redis.example.com")
+		crd.Object["spec"].(map[string]any)["versions"] = []any{}
+		_, err := FindTargetCRD("clusters.redis.example.com", []v1alpha1.Dependency{crd})
+
+		var cliErr *CLIError
+		Expect(errors.As(err, &cliErr)).To(BeTrue())
+		Expect(cliErr.Code).To(Equal("CRDNoVersions"))
+	})
+
+	Describe("SplitDependenciesByScope", func() {
+		It("splits the dependencies by the scope of their kind", func() {
+			clusterIssuerCRD := crdDependency("clusterissuers.example.com", "ClusterIssuer", "example.com")
+			clusterIssuerCRD.Object["spec"].(map[string]any)["scope"] = "Cluster"
+			dependency := func(apiVersion, kind, name string) v1alpha1.Dependency {
+				return v1alpha1.Dependency{Unstructured: unstructured.Unstructured{Object: map[string]any{
+					"apiVersion": apiVersion,
+					"kind":       kind,
+					"metadata":   map[string]any{"name": name},
+				}}}
+			}
+			dependencies := []v1alpha1.Dependency{
+				dependency("v1", "Namespace", "operator-system"),
+				dependency("apps/v1", "Deployment", "operator"),
+				clusterIssuerCRD,
+				dependency("example.com/v1", "ClusterIssuer", "letsencrypt"),
+				dependency("other.example.com/v1", "ClusterIssuer", "self-signed"),
+				dependency("rbac.authorization.k8s.io/v1", "ClusterRole", "operator"),
+			}
+
+			cluster, namespaced := SplitDependenciesByScope(dependencies)
+			Expect(cluster).To(Equal([]v1alpha1.Dependency{dependencies[0], dependencies[2], dependencies[3], dependencies[5]}))
+			Expect(namespaced).To(Equal([]v1alpha1.Dependency{dependencies[1], dependencies[4]}))
+		})
+	})
+
+	Describe("FindTargetCRDs", func() {
+		It("finds every CRD, in order", func() {
+			dependencies := []v1alpha1.Dependency{
+				crdDependency("clusters.postgresql.cnpg.io", "Cluster", "postgresql.cnpg.io"),
+				crdDependency("clusters.redis.example.com", "Cluster", "redis.example.com"),
+			}
+			crds, err := FindTargetCRDs([]string{"Cluster.redis.example.com", "clusters.postgresql.cnpg.io"}, dependencies)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(crds).To(HaveLen(2))
+			Expect(crds[0].Name).To(Equal("clusters.redis.example.com"))
+			Expect(crds[1].Name).To(Equal("clusters.postgresql.cnpg.io"))
+		})
+
+		It("errors when any CRD is missing", func() {
+			_, err := FindTargetCRDs([]string{"clusters.postgresql.cnpg.io", "missing"}, []v1alpha1.Dependency{
+				crdDependency("clusters.postgresql.cnpg.io", "Cluster", "postgresql.cnpg.io"),
+			})
+			Expect(err).To(MatchError("no CRD found matching name: missing"))
+		})
+	})
+
+	DescribeTable("NormaliseKindCase",
+		func(kind, kindCase, expected string) {
+			Expect(NormaliseKindCase(kind, kindCase)).To(Equal(expected))
+		},
+		Entry("keeps the kind without a case", "my-App", "", "my-App"),
+		Entry("pascal from kebab case", "my-app", "pascal", "MyApp"),
+		Entry("pascal from lowercase", "myapp", "pascal", "Myapp"),
+		Entry("camel from pascal case", "MyApp", "camel", "myApp"),
+		Entry("camel from snake case", "my_app_v2", "camel", "myAppV2"),
+		Entry("lower from camel case", "myApp", "lower", "myapp"),
+		Entry("pascal with an acronym", "HTTPRoute", "pascal", "HttpRoute"),
+	)
+
+	It("errors on an unknown kind case", func() {
+		_, err := NormaliseKindCase("MyApp", "snake")
+		Expect(err).To(MatchError(`invalid --kind-case "snake": must be one of pascal, camel, lower`))
+	})
+
+	DescribeTable("ValidateCRDName",
+		func(plural, group, expectedErr string) {
+			err := ValidateCRDName(plural+"."+group, plural, group)
+			if expectedErr == "" {
+				Expect(err).NotTo(HaveOccurred())
+				return
+			}
+			Expect(err).To(MatchError(ContainSubstring(expectedErr)))
+		},
+		Entry("accepts a lowercase plural and group", "databases", "myorg.com", ""),
+		Entry("rejects an uppercase plural", "Databases", "myorg.com",
+			"invalid CRD name Databases.myorg.com: must be the lowercase <plural>.<group>; set --plural databases and --group myorg.com"),
+		Entry("rejects an uppercase group", "databases", "MyOrg.com",
+			"invalid CRD name databases.MyOrg.com: must be the lowercase <plural>.<group>; set --plural databases and --group myorg.com"),
+		Entry("rejects a plural with invalid characters", "data_bases", "myorg.com", "invalid CRD name data_bases.myorg.com: a lowercase RFC 1123 subdomain"),
+	)
+
+	Describe("WritePromiseFiles", func() {
+		var outputDir string
+
+		BeforeEach(func() {
+			var err error
+			outputDir, err = os.MkdirTemp("", "kratix-write-test")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(outputDir)).To(Succeed())
+		})
+
+		It("writes the api with a stable key order regardless of insertion order", func() {
+			names := []string{"zeta", "alpha", "mu", "beta", "omega", "kappa"}
+			var contents []string
+			for i := 0; i < 10; i++ {
+				crd := crdWithProperties(rotated(names, i))
+				dir := filepath.Join(outputDir, fmt.Sprint(i))
+				Expect(WritePromiseFiles(dir, map[string]any{"api.yaml": crd}, false)).To(Succeed())
+
+				content, err := os.ReadFile(filepath.Join(dir, "api.yaml"))
+				Expect(err).NotTo(HaveOccurred())
+				contents = append(contents, string(content))
+			}
+
+			for _, content := range contents {
+				Expect(content).To(Equal(contents[0]))
+			}
+			Expect(strings.Index(contents[0], "alpha:")).To(BeNumerically("<", strings.Index(contents[0], "beta:")))
+			Expect(strings.Index(contents[0], "omega:")).To(BeNumerically("<", strings.Index(contents[0], "zeta:")))
+		})
+	})
+
+	Describe("ConfirmOverwrite", func() {
+		var outputDir string
+		var out *bytes.Buffer
+
+		BeforeEach(func() {
+			var err error
+			outputDir, err = os.MkdirTemp("", "kratix-confirm-test")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(outputDir, "api.yaml"), []byte("api"), 0644)).To(Succeed())
+			out = &bytes.Buffer{}
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(outputDir)).To(Succeed())
+		})
+
+		It("proceeds when the user confirms", func() {
+			Expect(ConfirmOverwrite(outputDir, true, strings.NewReader("y\n"), out)).To(Succeed())
+			Expect(out.String()).To(Equal(fmt.Sprintf("Directory %s not empty, overwrite? [y/N] ", outputDir)))
+		})
+
+		DescribeTable("aborts unless the user confirms",
+			func(answer string) {
+				err := ConfirmOverwrite(outputDir, true, strings.NewReader(answer), out)
+				Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf("aborted: %s is not empty", outputDir))))
+			},
+			Entry("on no", "n\n"),
+			Entry("on an empty answer", "\n"),
+			Entry("on end of input", ""),
+		)
+
+		It("does not prompt when not interactive", func() {
+			Expect(ConfirmOverwrite(outputDir, false, strings.NewReader(""), out)).To(Succeed())
+			Expect(out.String()).To(BeEmpty())
+		})
+
+		It("does not prompt for an empty or missing directory", func() {
+			Expect(os.Remove(filepath.Join(outputDir, "api.yaml"))).To(Succeed())
+			Expect(ConfirmOverwrite(outputDir, true, strings.NewReader(""), out)).To(Succeed())
+			Expect(ConfirmOverwrite(filepath.Join(outputDir, "missing"), true, strings.NewReader(""), out)).To(Succeed())
+			Expect(out.String()).To(BeEmpty())
+		})
+	})
+
+	Describe("WritePromiseFilesConcurrently", func() {
+		var outputDir string
+
+		BeforeEach(func() {
+			var err error
+			outputDir, err = os.MkdirTemp("", "kratix-write-test")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(outputDir)).To(Succeed())
+		})
+
+		It("writes the same files as the sequential writer", func() {
+			files := map[string]any{"README.md": []byte("readme")}
+			for i := 0; i < 20; i++ {
+				files[fmt.Sprintf("crd-%d", i)] = map[string]any{
+					"api.yaml":     crdWithProperties([]string{fmt.Sprint("field", i)}),
+					"workflows":    map[string]any{"resource": map[string]any{"configure.yaml": []byte(fmt.Sprint(i))}},
+					"example.yaml": []byte("example"),
+				}
+			}
+
+			sequentialDir := filepath.Join(outputDir, "sequential")
+			concurrentDir := filepath.Join(outputDir, "concurrent")
+			Expect(WritePromiseFiles(sequentialDir, files, false)).To(Succeed())
+			Expect(WritePromiseFilesConcurrently(context.Background(), concurrentDir, files, false, 8)).To(Succeed())
+
+			Expect(readTree(concurrentDir)).To(Equal(readTree(sequentialDir)))
+			Expect(readTree(concurrentDir)).To(HaveLen(61))
+		})
+
+		It("returns the error of a failed write", func() {
+			Expect(os.MkdirAll(filepath.Join(outputDir, "api.yaml"), os.ModePerm)).To(Succeed())
+			files := map[string]any{"api.yaml": []byte("api"), "README.md": []byte("readme")}
+
+			err := WritePromiseFilesConcurrently(context.Background(), outputDir, files, false, 2)
+			Expect(err).To(MatchError(ContainSubstring("api.yaml: is a directory")))
+		})
+
+		It("does not write anything once the context is cancelled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			err := WritePromiseFilesConcurrently(ctx, outputDir, map[string]any{"README.md": []byte("readme")}, false, 2)
+			Expect(err).To(MatchError(context.Canceled))
+			Expect(filepath.Join(outputDir, "README.md")).NotTo(BeAnExistingFile())
+		})
+	})
+})
+
+func crdWithProperties(names []string) *apiextensionsv1.CustomResourceDefinition {
+	nested := map[string]apiextensionsv1.JSONSchemaProps{}
+	spec := map[string]apiextensionsv1.JSONSchemaProps{}
+	for _, name := range names {
+		nested[name] = apiextensionsv1.JSONSchemaProps{Type: "string"}
+		spec[name] = apiextensionsv1.JSONSchemaProps{Type: "object", Properties: nested}
+	}
+
+	return &apiextensionsv1.CustomResourceDefinition{
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: "example.com",
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{{
+				Name: "v1",
+				Schema: &apiextensionsv1.CustomResourceValidation{
+					OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+						Type: "object",
+						Properties: map[string]apiextensionsv1.JSONSchemaProps{
+							"spec": {Type: "object", Properties: spec},
+						},
+					},
+				},
+			}},
+		},
+	}
+}
+
+func readTree(dir string) map[string]string {
+	tree := map[string]string{}
+	err := filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		tree[relPath] = string(content)
+		return nil
+	})
+	Expect(err).NotTo(HaveOccurred())
+	return tree
+}
+
+func rotated(names []string, offset int) []string {
+	result := make([]string, len(names))
+	for i := range names {
+		result[i] = names[(i+offset)%len(names)]
+	}
+	return result
+}
+
+// BenchmarkFindTargetCRDs looks up 50 CRDs out of a bundle of 500.
+func BenchmarkFindTargetCRDs(b *testing.B) {
+	var dependencies []v1alpha1.Dependency
+	var targets []string
+	for i := 0; i < 500; i++ {
+		name := fmt.Sprintf("kind%ds.example.com", i)
+		dependencies = append(dependencies, crdDependency(name, fmt.Sprintf("Kind%d", i), "example.com"))
+		if i%10 == 0 {
+			targets = append(targets, name)
+		}
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := FindTargetCRDs(targets, dependencies); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func crdDependency(name, kind, group string) v1alpha1.Dependency {
+	return v1alpha1.Dependency{Unstructured: unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "apiextensions.k8s.io/v1",
+		"kind":       "CustomResourceDefinition",
+		"metadata":   map[string]any{"name": name},
+		"spec": map[string]any{
+			"group": group,
+			"names": map[string]any{"kind": kind, "plural": "clusters"},
+			"versions": []any{
+				map[string]any{
+					"name": "v1", "served": true, "storage": true,
+					"schema": map[string]any{"openAPIV3Schema": map[string]any{"type": "object"}},
+				},
+			},
+		},
+	}}}
+}
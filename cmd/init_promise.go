@@ -28,9 +28,13 @@ var initPromiseCmd = &cobra.Command{
 const (
 	promiseFileName                   = "promise.yaml"
 	dependenciesFileName              = "dependencies.yaml"
+	clusterDependenciesFileName       = "dependencies-cluster.yaml"
+	namespacedDependenciesFileName    = "dependencies-namespaced.yaml"
 	apiFileName                       = "api.yaml"
 	resourceFileName                  = "example-resource.yaml"
+	requiredPromisesFileName          = "required-promises.yaml"
 	resourceConfigureWorkflowFileName = "workflows/resource/configure/workflow.yaml"
+	codeOwnersFileName                = "CODEOWNERS"
 )
 
 func init() {
@@ -38,6 +42,7 @@ func init() {
 }
 
 type promiseTemplateValues struct {
+	APIVersion           string
 	Name                 string
 	Group                string
 	Kind                 string
@@ -92,6 +97,7 @@ func generateTemplateValues(promiseName, subCommand, extraFlags, resourceConfigu
 	}
 
 	return promiseTemplateValues{
+		APIVersion:        kratixGroupVersion(),
 		Name:              promiseName,
 		Group:             group,
 		Kind:              kind,
@@ -89,8 +89,8 @@ func generateTerraformModuleResourceConfigurePipeline() (string, error) {
 	pipelines := []unstructured.Unstructured{
 		{
 			Object: map[string]any{
-				"apiVersion": "platform.kratix.io/v1alpha1",
-				"kind":       "Pipeline",
+				"apiVersion": kratixGroupVersion(),
+				"kind":       kratixPipelineKind,
 				"metadata": map[string]any{
 					"name": "instance-configure",
 				},
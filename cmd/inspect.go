@@ -0,0 +1,15 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var inspectCmd = &cobra.Command{
+	Use:   "inspect",
+	Short: "Command to inspect kratix resources",
+	Long:  "Command to inspect kratix resources",
+}
+
+func init() {
+	rootCmd.AddCommand(inspectCmd)
+}
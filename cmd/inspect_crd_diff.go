@@ -0,0 +1,240 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const crdDiffLongHelp = `Report the changes between two versions of a CRD, and whether they are
+backward compatible.
+
+The schemas of the stored versions are compared. The following changes are
+breaking, as resources valid against the old CRD may be rejected by the new
+one:
+  - removed properties
+  - changed types, except widening an integer to a number
+  - newly required properties
+  - added or narrowed enums
+
+Use it before regenerating a Promise from a new operator release.`
+
+var crdDiffCmd = &cobra.Command{
+	Use:   "crd-diff --old OLD-CRD-FILE --new NEW-CRD-FILE",
+	Short: "Report the breaking changes between two versions of a CRD",
+	Long:  crdDiffLongHelp,
+	Example: `  # compare the CRD of two operator releases
+  kratix inspect crd-diff --old v1.0/crd.yaml --new v1.1/crd.yaml
+
+  # print the changes as JSON
+  kratix inspect crd-diff --old v1.0/crd.yaml --new v1.1/crd.yaml --output json`,
+	Args: cobra.NoArgs,
+	RunE: InspectCRDDiff,
+}
+
+var oldCRDFile, newCRDFile, crdDiffOutput string
+
+func init() {
+	inspectCmd.AddCommand(crdDiffCmd)
+	crdDiffCmd.Flags().StringVar(&oldCRDFile, "old", "", "The path to the old CRD")
+	crdDiffCmd.Flags().StringVar(&newCRDFile, "new", "", "The path to the new CRD")
+	crdDiffCmd.Flags().StringVarP(&crdDiffOutput, "output", "o", "text", "The output format. One of: text, json")
+	crdDiffCmd.MarkFlagRequired("old")
+	crdDiffCmd.MarkFlagRequired("new")
+}
+
+type schemaChange struct {
+	Path     string `json:"path"`
+	Breaking bool   `json:"breaking"`
+	Message  string `json:"message"`
+}
+
+type crdDiff struct {
+	Breaking bool           `json:"breaking"`
+	Changes  []schemaChange `json:"changes"`
+}
+
+func InspectCRDDiff(cmd *cobra.Command, args []string) error {
+	if crdDiffOutput != "text" && crdDiffOutput != "json" {
+		return fmt.Errorf("invalid --output %q: must be one of text, json", crdDiffOutput)
+	}
+
+	oldSchema, err := readStoredSchema(oldCRDFile)
+	if err != nil {
+		return err
+	}
+	newSchema, err := readStoredSchema(newCRDFile)
+	if err != nil {
+		return err
+	}
+
+	diff := crdDiff{Changes: diffSchemas("", oldSchema, newSchema)}
+	sort.SliceStable(diff.Changes, func(i, j int) bool {
+		return diff.Changes[i].Path < diff.Changes[j].Path
+	})
+	for _, change := range diff.Changes {
+		diff.Breaking = diff.Breaking || change.Breaking
+	}
+
+	if crdDiffOutput == "json" {
+		if diff.Changes == nil {
+			diff.Changes = []schemaChange{}
+		}
+		output, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(output))
+		return nil
+	}
+
+	for _, change := range diff.Changes {
+		level := "compatible"
+		if change.Breaking {
+			level = "BREAKING"
+		}
+		fmt.Printf("%-10s %s: %s\n", level, change.Path, change.Message)
+	}
+	if diff.Breaking {
+		fmt.Println("The new CRD has breaking changes: review them before regenerating the Promise.")
+	} else {
+		fmt.Println("No breaking changes found.")
+	}
+	return nil
+}
+
+func readStoredSchema(path string) (*apiextensionsv1.JSONSchemaProps, error) {
+	crdBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CRD: %w", err)
+	}
+
+	var crd apiextensionsv1.CustomResourceDefinition
+	if err := yaml.Unmarshal(crdBytes, &crd); err != nil {
+		return nil, fmt.Errorf("failed to parse CRD %s: %w", path, err)
+	}
+	return storedVersionSchema(&crd, path)
+}
+
+// storedVersionSchema returns the schema of the stored version of the CRD
+// read from path.
+func storedVersionSchema(crd *apiextensionsv1.CustomResourceDefinition, path string) (*apiextensionsv1.JSONSchemaProps, error) {
+	if len(crd.Spec.Versions) == 0 {
+		return nil, newCLIError(errCodeCRDNoVersions, map[string]any{"crd": crd.Name}, "no versions found in CRD %s", path)
+	}
+
+	version := crd.Spec.Versions[findStoredVersionIdx(crd)]
+	if version.Schema == nil || version.Schema.OpenAPIV3Schema == nil {
+		return nil, fmt.Errorf("no schema found in the stored version of CRD %s", path)
+	}
+	return version.Schema.OpenAPIV3Schema, nil
+}
+
+// diffSchemas compares the old and new schemas at path, recursing into the
+// properties, the items and the additional properties they have in common.
+func diffSchemas(path string, oldSchema, newSchema *apiextensionsv1.JSONSchemaProps) []schemaChange {
+	var changes []schemaChange
+	change := func(breaking bool, format string, a ...any) {
+		changes = append(changes, schemaChange{Path: displayPath(path), Breaking: breaking, Message: fmt.Sprintf(format, a...)})
+	}
+
+	if oldSchema.Type != newSchema.Type {
+		widened := oldSchema.Type == "integer" && newSchema.Type == "number"
+		change(!widened, "type changed from %s to %s", displayType(oldSchema.Type), displayType(newSchema.Type))
+	}
+
+	oldEnum, newEnum := enumValues(oldSchema), enumValues(newSchema)
+	switch {
+	case len(oldEnum) == 0 && len(newEnum) > 0:
+		change(true, "enum added: %s", strings.Join(newEnum, ", "))
+	case len(oldEnum) > 0 && len(newEnum) == 0:
+		change(false, "enum removed")
+	default:
+		for _, value := range oldEnum {
+			if !slices.Contains(newEnum, value) {
+				change(true, "enum value %s removed", value)
+			}
+		}
+		for _, value := range newEnum {
+			if !slices.Contains(oldEnum, value) {
+				change(false, "enum value %s added", value)
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(oldSchema.Properties) {
+		if _, found := newSchema.Properties[name]; !found {
+			changes = append(changes, schemaChange{Path: joinSchemaPath(path, name), Breaking: true, Message: "property removed"})
+		}
+	}
+	for _, name := range sortedKeys(newSchema.Properties) {
+		newProperty := newSchema.Properties[name]
+		oldProperty, found := oldSchema.Properties[name]
+		if !found {
+			if slices.Contains(newSchema.Required, name) {
+				changes = append(changes, schemaChange{Path: joinSchemaPath(path, name), Breaking: true, Message: "required property added"})
+			} else {
+				changes = append(changes, schemaChange{Path: joinSchemaPath(path, name), Message: "property added"})
+			}
+			continue
+		}
+		if slices.Contains(newSchema.Required, name) && !slices.Contains(oldSchema.Required, name) {
+			changes = append(changes, schemaChange{Path: joinSchemaPath(path, name), Breaking: true, Message: "property is now required"})
+		}
+		changes = append(changes, diffSchemas(joinSchemaPath(path, name), &oldProperty, &newProperty)...)
+	}
+
+	if oldSchema.Items != nil && oldSchema.Items.Schema != nil && newSchema.Items != nil && newSchema.Items.Schema != nil {
+		changes = append(changes, diffSchemas(path+"[]", oldSchema.Items.Schema, newSchema.Items.Schema)...)
+	}
+	if oldSchema.AdditionalProperties != nil && oldSchema.AdditionalProperties.Schema != nil &&
+		newSchema.AdditionalProperties != nil && newSchema.AdditionalProperties.Schema != nil {
+		changes = append(changes, diffSchemas(path+".*", oldSchema.AdditionalProperties.Schema, newSchema.AdditionalProperties.Schema)...)
+	}
+	return changes
+}
+
+func enumValues(schema *apiextensionsv1.JSONSchemaProps) []string {
+	var values []string
+	for _, value := range schema.Enum {
+		values = append(values, string(value.Raw))
+	}
+	return values
+}
+
+func sortedKeys(properties map[string]apiextensionsv1.JSONSchemaProps) []string {
+	var keys []string
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func joinSchemaPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "(root)"
+	}
+	return path
+}
+
+func displayType(schemaType string) string {
+	if schemaType == "" {
+		return "(none)"
+	}
+	return schemaType
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var inspectPipelineContractCmd = &cobra.Command{
+	Use:   "pipeline-contract",
+	Short: "Print the contract of the operator Promise pipeline",
+	Long: `Print the contract of the resource configure pipeline generated by
+"kratix init operator-promise": the env injected in each container, and the
+files the from-api-to-operator container reads the resource request from and
+writes the operator object to.
+
+Custom containers, e.g. given with --pipeline-step, can rely on the same contract.`,
+	Example: `  # print the pipeline contract
+  kratix inspect pipeline-contract
+
+  # print the pipeline contract as JSON
+  kratix inspect pipeline-contract --format json`,
+	Args: cobra.NoArgs,
+	RunE: InspectPipelineContract,
+}
+
+var pipelineContractFormat string
+
+func init() {
+	inspectCmd.AddCommand(inspectPipelineContractCmd)
+	inspectPipelineContractCmd.Flags().StringVar(&pipelineContractFormat, "format", "text", "The output format. One of: text, json")
+}
+
+type pipelineContractEnv struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type pipelineContractFile struct {
+	Path string `json:"path"`
+	Env  string `json:"env"`
+}
+
+type pipelineContract struct {
+	Image  string                `json:"image"`
+	Env    []pipelineContractEnv `json:"env"`
+	Input  pipelineContractFile  `json:"input"`
+	Output pipelineContractFile  `json:"output"`
+}
+
+var operatorPipelineContract = pipelineContract{
+	Image: operatorContainerImage,
+	Env: []pipelineContractEnv{
+		{Name: operatorGroupEnv, Description: "The group of the operator CRD"},
+		{Name: operatorVersionEnv, Description: "The stored version of the operator CRD"},
+		{Name: operatorKindEnv, Description: "The kind of the operator CRD"},
+	},
+	Input:  pipelineContractFile{Path: operatorInputFile, Env: operatorInputFileEnv},
+	Output: pipelineContractFile{Path: operatorOutputFile, Env: operatorOutputFileEnv},
+}
+
+func InspectPipelineContract(cmd *cobra.Command, args []string) error {
+	switch pipelineContractFormat {
+	case "json":
+		output, err := json.MarshalIndent(operatorPipelineContract, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(output))
+		return nil
+	case "text":
+	default:
+		return fmt.Errorf("invalid --format %q: must be one of text, json", pipelineContractFormat)
+	}
+
+	fmt.Printf("Image:  %s\n", operatorPipelineContract.Image)
+	fmt.Printf("Input:  %s (override with %s)\n", operatorPipelineContract.Input.Path, operatorPipelineContract.Input.Env)
+	fmt.Printf("Output: %s (override with %s)\n", operatorPipelineContract.Output.Path, operatorPipelineContract.Output.Env)
+	fmt.Println("Env:")
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	for _, env := range operatorPipelineContract.Env {
+		fmt.Fprintf(writer, "  %s\t%s\n", env.Name, env.Description)
+	}
+	return writer.Flush()
+}
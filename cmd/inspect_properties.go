@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+var inspectPropertiesCmd = &cobra.Command{
+	Use:   "properties",
+	Short: "List the properties of the Promise API",
+	Long: `List the properties of the spec of the Promise API, with their type, whether
+they are required and their default, from the stored version of the API.
+
+The API is read from api.yaml, or from promise.yaml when there is no api.yaml.
+Use "kratix update api" to add, change or remove properties.`,
+	Example: `  # list the properties of the Promise in the current directory
+  kratix inspect properties
+
+  # list the properties as JSON
+  kratix inspect properties --dir path/to/promise --format json`,
+	Args: cobra.NoArgs,
+	RunE: InspectProperties,
+}
+
+var propertiesFormat string
+
+func init() {
+	inspectCmd.AddCommand(inspectPropertiesCmd)
+	inspectPropertiesCmd.Flags().StringVarP(&dir, "dir", "d", ".", "Directory to read the Promise from")
+	inspectPropertiesCmd.Flags().StringVar(&propertiesFormat, "format", "table", "The output format. One of: table, json")
+}
+
+type apiProperty struct {
+	Name     string                `json:"name"`
+	Type     string                `json:"type,omitempty"`
+	Required bool                  `json:"required"`
+	Default  *apiextensionsv1.JSON `json:"default,omitempty"`
+}
+
+func InspectProperties(cmd *cobra.Command, args []string) error {
+	if propertiesFormat != "table" && propertiesFormat != "json" {
+		return fmt.Errorf("invalid --format %q: must be one of table, json", propertiesFormat)
+	}
+
+	crd, _, filePath, err := readPromiseAPI(dir)
+	if err != nil {
+		return err
+	}
+	schema, err := storedVersionSchema(&crd, filePath)
+	if err != nil {
+		return err
+	}
+
+	properties := []apiProperty{}
+	spec := schema.Properties["spec"]
+	for _, name := range sortedKeys(spec.Properties) {
+		property := spec.Properties[name]
+		properties = append(properties, apiProperty{
+			Name:     name,
+			Type:     property.Type,
+			Required: slices.Contains(spec.Required, name),
+			Default:  property.Default,
+		})
+	}
+
+	if propertiesFormat == "json" {
+		output, err := json.MarshalIndent(properties, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(output))
+		return nil
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(writer, "NAME\tTYPE\tREQUIRED\tDEFAULT")
+	for _, property := range properties {
+		defaultValue := ""
+		if property.Default != nil {
+			defaultValue = string(property.Default.Raw)
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%t\t%s\n", property.Name, property.Type, property.Required, defaultValue)
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var inspectSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema of the Promise API",
+	Long: `Print the schema of the stored version of the Promise API as a standalone
+JSON Schema, e.g. for form generation or validation in external systems.
+
+The API is read from api.yaml, or from promise.yaml when there is no api.yaml.
+Nullable types become a union with null, x-kubernetes-int-or-string becomes a
+union of integer and string, and the other x-kubernetes-* extensions are
+dropped.`,
+	Example: `  # write the JSON Schema of the Promise in the current directory
+  kratix inspect schema > schema.json`,
+	Args: cobra.NoArgs,
+	RunE: InspectSchema,
+}
+
+func init() {
+	inspectCmd.AddCommand(inspectSchemaCmd)
+	inspectSchemaCmd.Flags().StringVarP(&dir, "dir", "d", ".", "Directory to read the Promise from")
+}
+
+func InspectSchema(cmd *cobra.Command, args []string) error {
+	crd, _, filePath, err := readPromiseAPI(dir)
+	if err != nil {
+		return err
+	}
+	schema, err := storedVersionSchema(&crd, filePath)
+	if err != nil {
+		return err
+	}
+
+	jsonSchema, err := toJSONSchema(schema, crd.Spec.Names.Kind)
+	if err != nil {
+		return err
+	}
+	output, err := json.MarshalIndent(jsonSchema, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(output))
+	return nil
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// The Kratix API the generated resources belong to.
+const (
+	kratixAPIGroup           = "platform.kratix.io"
+	kratixPromiseKind        = "Promise"
+	kratixPipelineKind       = "Pipeline"
+	kratixPromiseReleaseKind = "PromiseRelease"
+	defaultKratixAPIVersion  = "v1alpha1"
+)
+
+// supportedKratixAPIVersions lists the values of --kratix-api-version.
+var supportedKratixAPIVersions = []string{defaultKratixAPIVersion}
+
+var kratixAPIVersion string
+
+// kratixGroupVersion returns the apiVersion of the generated Promises and
+// Pipelines.
+func kratixGroupVersion() string {
+	return kratixAPIGroup + "/" + kratixAPIVersion
+}
+
+func validateKratixAPIVersion(version string) error {
+	if !slices.Contains(supportedKratixAPIVersions, version) {
+		return fmt.Errorf("invalid --kratix-api-version %q: must be one of %s", version, strings.Join(supportedKratixAPIVersions, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+const (
+	layoutNested = "nested"
+	layoutFlat   = "flat"
+)
+
+var supportedLayouts = []string{layoutNested, layoutFlat}
+
+// flatWorkflowFileName is the name of the workflow.yaml of LIFECYCLE/ACTION in
+// the flat layout, e.g. workflow-resource-configure.yaml.
+func flatWorkflowFileName(lifecycle, action string) string {
+	return fmt.Sprintf("workflow-%s-%s.yaml", lifecycle, action)
+}
+
+// flattenPromiseFiles maps the files of the nested layout to files at the root
+// of the output directory: workflows/LIFECYCLE/ACTION/workflow.yaml becomes
+// workflow-LIFECYCLE-ACTION.yaml, and the other nested files are prefixed with
+// their directories, joined with "-".
+func flattenPromiseFiles(filesToWrite map[string]any) (map[string]any, error) {
+	flat := map[string]any{}
+	var flatten func(prefix string, files map[string]any) error
+	flatten = func(prefix string, files map[string]any) error {
+		for key, value := range files {
+			filePath := path.Join(prefix, key)
+			if nested, ok := value.(map[string]any); ok {
+				if err := flatten(filePath, nested); err != nil {
+					return err
+				}
+				continue
+			}
+
+			name := strings.ReplaceAll(filePath, "/", "-")
+			segments := strings.Split(filePath, "/")
+			if len(segments) == 4 && segments[0] == "workflows" && segments[3] == "workflow.yaml" {
+				name = flatWorkflowFileName(segments[1], segments[2])
+			}
+			if _, found := flat[name]; found {
+				return fmt.Errorf("cannot write %s with --layout flat: another file is also written to %s", filePath, name)
+			}
+			flat[name] = value
+		}
+		return nil
+	}
+	if err := flatten("", filesToWrite); err != nil {
+		return nil, err
+	}
+	return flat, nil
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/syntasso/kratix/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// defaultMetricPrefix is the prefix of the reconcile metrics of operators built
+// with controller-runtime, such as controller_runtime_reconcile_total.
+const defaultMetricPrefix = "controller_runtime"
+
+// grafanaDashboardLabel is the label the Grafana sidecar loads the dashboards
+// of ConfigMaps from.
+const grafanaDashboardLabel = "grafana_dashboard"
+
+var metricPrefixPattern = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+// operatorObservability generates a Grafana dashboard ConfigMap and a
+// PrometheusRule for the reconcile metrics of the operator, named
+// <prefix>_reconcile_total, <prefix>_reconcile_errors_total and
+// <prefix>_reconcile_time_seconds. Both are put in the namespace of the
+// operator Deployment, or in namespace when there is no Deployment.
+func operatorObservability(dependencies []v1alpha1.Dependency, promiseName, namespace, prefix, title string) ([]v1alpha1.Dependency, error) {
+	if !metricPrefixPattern.MatchString(prefix) {
+		return nil, fmt.Errorf("invalid --observability-metric-prefix %q: must be a valid Prometheus metric name", prefix)
+	}
+	if title == "" {
+		title = fmt.Sprintf("%s operator", promiseName)
+	}
+
+	if deployment, err := findOperatorDeployment(dependencies); err == nil {
+		namespace = deployment.GetNamespace()
+	}
+	selector := fmt.Sprintf(`namespace=%q`, namespace)
+
+	dashboard, err := json.MarshalIndent(operatorDashboard(title, prefix, selector), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	configMap := v1alpha1.Dependency{Unstructured: unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]any{
+			"name":      fmt.Sprintf("%s-operator-dashboard", promiseName),
+			"namespace": namespace,
+			"labels":    map[string]any{grafanaDashboardLabel: "1"},
+		},
+		"data": map[string]any{
+			fmt.Sprintf("%s-operator.json", promiseName): string(dashboard),
+		},
+	}}}
+
+	ruleName := fmt.Sprintf("%s-operator-alerts", promiseName)
+	rule := v1alpha1.Dependency{Unstructured: unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "monitoring.coreos.com/v1",
+		"kind":       "PrometheusRule",
+		"metadata": map[string]any{
+			"name":      ruleName,
+			"namespace": namespace,
+		},
+		"spec": map[string]any{
+			"groups": []any{
+				map[string]any{
+					"name":  fmt.Sprintf("%s-operator", promiseName),
+					"rules": operatorAlerts(promiseName, prefix, selector),
+				},
+			},
+		},
+	}}}
+
+	fmt.Fprintf(os.Stderr, "Warning: review the alerts of PrometheusRule %s: their expressions and thresholds are generic defaults\n", ruleName)
+	return []v1alpha1.Dependency{configMap, rule}, nil
+}
+
+func operatorAlerts(promiseName, prefix, selector string) []any {
+	labels := map[string]any{"severity": "warning", "promise": promiseName}
+	return []any{
+		map[string]any{
+			"alert":  "OperatorReconcileErrors",
+			"expr":   fmt.Sprintf("sum(rate(%s_reconcile_errors_total{%s}[5m])) > 0", prefix, selector),
+			"for":    "15m",
+			"labels": labels,
+			"annotations": map[string]any{
+				"summary": fmt.Sprintf("The %s operator is failing to reconcile resources", promiseName),
+			},
+		},
+		map[string]any{
+			"alert":  "OperatorReconcileSlow",
+			"expr":   fmt.Sprintf("histogram_quantile(0.99, sum(rate(%s_reconcile_time_seconds_bucket{%s}[5m])) by (le)) > 10", prefix, selector),
+			"for":    "15m",
+			"labels": labels,
+			"annotations": map[string]any{
+				"summary": fmt.Sprintf("The %s operator takes over 10s to reconcile resources", promiseName),
+			},
+		},
+	}
+}
+
+func operatorDashboard(title, prefix, selector string) map[string]any {
+	panel := func(id int, panelTitle, expr string, x int) map[string]any {
+		return map[string]any{
+			"id":      id,
+			"type":    "timeseries",
+			"title":   panelTitle,
+			"gridPos": map[string]any{"h": 8, "w": 8, "x": x, "y": 0},
+			"targets": []any{map[string]any{"expr": expr, "refId": "A"}},
+		}
+	}
+
+	return map[string]any{
+		"title":         title,
+		"schemaVersion": 39,
+		"time":          map[string]any{"from": "now-6h", "to": "now"},
+		"panels": []any{
+			panel(1, "Reconciles per second", fmt.Sprintf("sum(rate(%s_reconcile_total{%s}[5m])) by (result)", prefix, selector), 0),
+			panel(2, "Reconcile errors per second", fmt.Sprintf("sum(rate(%s_reconcile_errors_total{%s}[5m]))", prefix, selector), 8),
+			panel(3, "Reconcile time p99", fmt.Sprintf("histogram_quantile(0.99, sum(rate(%s_reconcile_time_seconds_bucket{%s}[5m])) by (le))", prefix, selector), 16),
+		},
+	}
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"github.com/spf13/pflag"
+	"github.com/syntasso/kratix/api/v1alpha1"
+)
+
+// ObservabilityOptions are the flags adding the monitoring of the operator to
+// the dependencies.
+type ObservabilityOptions struct {
+	WithServiceMonitor     bool
+	ServiceMonitorPort     string
+	ServiceMonitorInterval string
+	WithObservability      bool
+	MetricPrefix           string
+	DashboardTitle         string
+}
+
+func (o *ObservabilityOptions) addFlags(flags *pflag.FlagSet) {
+	flags.BoolVar(&o.WithServiceMonitor, "with-service-monitor", false, "Add a Prometheus Operator ServiceMonitor scraping the metrics port of the operator to the Promise dependencies.")
+	flags.StringVar(&o.ServiceMonitorPort, "service-monitor-port", "", "The name or number of the container port serving the operator metrics. Defaults to the port named metrics, or else 8080 or 9090. Requires --with-service-monitor.")
+	flags.StringVar(&o.ServiceMonitorInterval, "service-monitor-interval", "30s", "How often Prometheus scrapes the operator metrics. Requires --with-service-monitor.")
+	flags.BoolVar(&o.WithObservability, "with-observability", false, "Add a Grafana dashboard ConfigMap and PrometheusRule alerts for the operator reconcile metrics to the Promise dependencies.")
+	flags.StringVar(&o.MetricPrefix, "observability-metric-prefix", defaultMetricPrefix, "The prefix of the operator reconcile metrics. Requires --with-observability.")
+	flags.StringVar(&o.DashboardTitle, "observability-dashboard-title", "", "The title of the Grafana dashboard. Defaults to \"<promise name> operator\". Requires --with-observability.")
+}
+
+// validate errors on the flags of the monitoring set without the flag adding
+// it.
+func (o *ObservabilityOptions) validate(flags *pflag.FlagSet) error {
+	if err := requireFlag(flags, "with-service-monitor", o.WithServiceMonitor,
+		"service-monitor-port", "service-monitor-interval"); err != nil {
+		return err
+	}
+	return requireFlag(flags, "with-observability", o.WithObservability,
+		"observability-metric-prefix", "observability-dashboard-title")
+}
+
+// dependencies generates the ServiceMonitor, Grafana dashboard and
+// PrometheusRule set by the options for the operator of operatorDependencies.
+// namespace is the namespace of the dependencies without one.
+func (o *ObservabilityOptions) dependencies(operatorDependencies []v1alpha1.Dependency, promiseName, namespace string) ([]v1alpha1.Dependency, error) {
+	var dependencies []v1alpha1.Dependency
+	if o.WithServiceMonitor {
+		serviceMonitor, err := operatorServiceMonitor(operatorDependencies, o.ServiceMonitorPort, o.ServiceMonitorInterval)
+		if err != nil {
+			return nil, err
+		}
+		if serviceMonitor != nil {
+			dependencies = append(dependencies, *serviceMonitor)
+		}
+	}
+
+	if o.WithObservability {
+		observability, err := operatorObservability(operatorDependencies, promiseName, namespace, o.MetricPrefix, o.DashboardTitle)
+		if err != nil {
+			return nil, err
+		}
+		dependencies = append(dependencies, observability...)
+	}
+	return dependencies, nil
+}
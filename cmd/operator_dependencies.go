@@ -0,0 +1,669 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/syntasso/kratix/api/v1alpha1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	yamlsig "sigs.k8s.io/yaml"
+)
+
+const (
+	kratixPlatformNamespace = "kratix-platform-system"
+	kratixControllerLabel   = "control-plane"
+	kratixControllerValue   = "controller-manager"
+)
+
+// The DNS of the cluster, which the operator pods reach under the
+// NetworkPolicy of --with-network-policy.
+const (
+	kubeDNSNamespace = "kube-system"
+	kubeDNSLabel     = "k8s-app"
+	kubeDNSValue     = "kube-dns"
+	dnsPort          = 53
+)
+
+// defaultPDBMaxUnavailable lets one operator pod be evicted at a time, without
+// blocking the drain of the node of a single replica.
+const defaultPDBMaxUnavailable = "1"
+
+// findOperatorDeployment returns the first Deployment in the operator
+// manifests, which is assumed to run the operator.
+func findOperatorDeployment(dependencies []v1alpha1.Dependency) (*v1alpha1.Dependency, error) {
+	for i := range dependencies {
+		if dependencies[i].GetKind() == "Deployment" {
+			return &dependencies[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no Deployment found in the operator manifests")
+}
+
+// deriveDestinationSelectors uses the nodeSelector of the operator Deployment
+// as the Promise destination selectors. Node labels do not necessarily match
+// the labels of the destinations, so this is a best-effort derivation.
+func deriveDestinationSelectors(dependencies []v1alpha1.Dependency) ([]v1alpha1.PromiseScheduling, error) {
+	deployment, err := findOperatorDeployment(dependencies)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive the destination selectors: %w", err)
+	}
+
+	nodeSelector, _, err := unstructured.NestedStringMap(deployment.Object, "spec", "template", "spec", "nodeSelector")
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive the destination selectors: %w", err)
+	}
+	if len(nodeSelector) == 0 {
+		return nil, nil
+	}
+	return []v1alpha1.PromiseScheduling{{MatchLabels: nodeSelector}}, nil
+}
+
+func parseDestinationSelectors(selectors []string) ([]v1alpha1.PromiseScheduling, error) {
+	if len(selectors) == 0 {
+		return nil, nil
+	}
+
+	matchLabels := map[string]string{}
+	for _, selector := range selectors {
+		key, value, found := strings.Cut(selector, "=")
+		if !found || key == "" {
+			return nil, fmt.Errorf("invalid destination selector %q: expected KEY=VALUE", selector)
+		}
+		matchLabels[key] = value
+	}
+	if err := validateSelectorLabels(matchLabels); err != nil {
+		return nil, err
+	}
+	return []v1alpha1.PromiseScheduling{{MatchLabels: matchLabels}}, nil
+}
+
+// loadDestinationSelectors reads a YAML list of label maps from path, each
+// becoming a destination selector of the Promise.
+func loadDestinationSelectors(path string) ([]v1alpha1.PromiseScheduling, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	selectorsBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read destination selectors: %w", err)
+	}
+
+	var labelMaps []map[string]string
+	if err := yamlsig.UnmarshalStrict(selectorsBytes, &labelMaps); err != nil {
+		return nil, fmt.Errorf("failed to parse destination selectors %s: expected a list of label maps", path)
+	}
+
+	var selectors []v1alpha1.PromiseScheduling
+	for i, matchLabels := range labelMaps {
+		if len(matchLabels) == 0 {
+			return nil, fmt.Errorf("invalid destination selector %d in %s: no labels", i, path)
+		}
+		if err := validateSelectorLabels(matchLabels); err != nil {
+			return nil, fmt.Errorf("invalid destination selector %d in %s: %w", i, path, err)
+		}
+		selectors = append(selectors, v1alpha1.PromiseScheduling{MatchLabels: matchLabels})
+	}
+	return selectors, nil
+}
+
+func validateSelectorLabels(matchLabels map[string]string) error {
+	keys := make([]string, 0, len(matchLabels))
+	for key := range matchLabels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("invalid label key %q: %s", key, strings.Join(errs, ", "))
+		}
+		if errs := validation.IsValidLabelValue(matchLabels[key]); len(errs) > 0 {
+			return fmt.Errorf("invalid value %q of label %s: %s", matchLabels[key], key, strings.Join(errs, ", "))
+		}
+	}
+	return nil
+}
+
+// operatorNetworkPolicy generates a NetworkPolicy denying all traffic to and
+// from the operator pods except ingress from the Kratix controller and, when
+// the operator has webhook configurations, from the Kubernetes API server to
+// its webhooks, and egress to the DNS and the Kubernetes API, on the ports set
+// by the options.
+func operatorNetworkPolicy(dependencies []v1alpha1.Dependency, options *OperatorPodOptions) (v1alpha1.Dependency, error) {
+	deployment, err := findOperatorDeployment(dependencies)
+	if err != nil {
+		return v1alpha1.Dependency{}, fmt.Errorf("failed to generate the NetworkPolicy: %w", err)
+	}
+
+	matchLabels, found, err := unstructured.NestedMap(deployment.Object, "spec", "selector", "matchLabels")
+	if err != nil || !found || len(matchLabels) == 0 {
+		return v1alpha1.Dependency{}, fmt.Errorf("failed to generate the NetworkPolicy: Deployment %s has no spec.selector.matchLabels", deployment.GetName())
+	}
+
+	ingress := []any{
+		map[string]any{
+			"from": []any{
+				map[string]any{
+					"namespaceSelector": map[string]any{
+						"matchLabels": map[string]any{"kubernetes.io/metadata.name": kratixPlatformNamespace},
+					},
+					"podSelector": map[string]any{
+						"matchLabels": map[string]any{kratixControllerLabel: kratixControllerValue},
+					},
+				},
+			},
+			"ports": networkPolicyPorts(options.NetworkPolicyIngressPorts),
+		},
+	}
+	if hasWebhookConfigs(dependencies) && len(options.NetworkPolicyWebhookPorts) > 0 {
+		// the API server calls the webhooks from the network of its node, which
+		// no pod or namespace selector matches, so the ports are open to all
+		ingress = append(ingress, map[string]any{
+			"ports": networkPolicyPorts(options.NetworkPolicyWebhookPorts),
+		})
+	}
+
+	apiEgress := map[string]any{
+		"ports": networkPolicyPorts(options.NetworkPolicyEgressPorts),
+	}
+	if len(options.NetworkPolicyAPICIDRs) > 0 {
+		var to []any
+		for _, cidr := range options.NetworkPolicyAPICIDRs {
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				return v1alpha1.Dependency{}, fmt.Errorf("invalid --network-policy-api-cidr %q: %w", cidr, err)
+			}
+			to = append(to, map[string]any{"ipBlock": map[string]any{"cidr": cidr}})
+		}
+		apiEgress["to"] = to
+	}
+
+	return v1alpha1.Dependency{Unstructured: unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "networking.k8s.io/v1",
+		"kind":       "NetworkPolicy",
+		"metadata": map[string]any{
+			"name":      fmt.Sprintf("%s-network-policy", deployment.GetName()),
+			"namespace": deployment.GetNamespace(),
+		},
+		"spec": map[string]any{
+			"podSelector": map[string]any{
+				"matchLabels": matchLabels,
+			},
+			"policyTypes": []any{"Ingress", "Egress"},
+			"ingress":     ingress,
+			"egress": []any{
+				map[string]any{
+					"to": []any{
+						map[string]any{
+							"namespaceSelector": map[string]any{
+								"matchLabels": map[string]any{"kubernetes.io/metadata.name": kubeDNSNamespace},
+							},
+							"podSelector": map[string]any{
+								"matchLabels": map[string]any{kubeDNSLabel: kubeDNSValue},
+							},
+						},
+					},
+					"ports": []any{
+						map[string]any{"protocol": "UDP", "port": int64(dnsPort)},
+						map[string]any{"protocol": "TCP", "port": int64(dnsPort)},
+					},
+				},
+				apiEgress,
+			},
+		},
+	}}}, nil
+}
+
+func hasWebhookConfigs(dependencies []v1alpha1.Dependency) bool {
+	for _, dep := range dependencies {
+		if isWebhookConfig(dep) {
+			return true
+		}
+	}
+	return false
+}
+
+func networkPolicyPorts(ports []int) []any {
+	policyPorts := []any{}
+	for _, port := range ports {
+		policyPorts = append(policyPorts, map[string]any{
+			"protocol": "TCP",
+			"port":     int64(port),
+		})
+	}
+	return policyPorts
+}
+
+// operatorPodDisruptionBudget generates a PodDisruptionBudget for the pods of
+// the operator Deployment, with either minAvailable or maxUnavailable set. It
+// warns and returns nil when there is no Deployment to protect.
+func operatorPodDisruptionBudget(dependencies []v1alpha1.Dependency, minAvailable, maxUnavailable string) (*v1alpha1.Dependency, error) {
+	if minAvailable != "" && maxUnavailable != "" {
+		return nil, fmt.Errorf("--pdb-min-available and --pdb-max-unavailable cannot be used together")
+	}
+	flag, field, value := "--pdb-max-unavailable", "maxUnavailable", maxUnavailable
+	if minAvailable != "" {
+		flag, field, value = "--pdb-min-available", "minAvailable", minAvailable
+	}
+	if value == "" {
+		value = defaultPDBMaxUnavailable
+	}
+	budget, err := parseDisruptionBudget(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q: %s", flag, value, err)
+	}
+
+	deployment, err := findOperatorDeployment(dependencies)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: no PodDisruptionBudget added: %s\n", err)
+		return nil, nil
+	}
+	matchLabels, found, err := unstructured.NestedMap(deployment.Object, "spec", "selector", "matchLabels")
+	if err != nil || !found || len(matchLabels) == 0 {
+		return nil, fmt.Errorf("failed to generate the PodDisruptionBudget: Deployment %s has no spec.selector.matchLabels", deployment.GetName())
+	}
+
+	return &v1alpha1.Dependency{Unstructured: unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "policy/v1",
+		"kind":       "PodDisruptionBudget",
+		"metadata": map[string]any{
+			"name":      fmt.Sprintf("%s-pdb", deployment.GetName()),
+			"namespace": deployment.GetNamespace(),
+		},
+		"spec": map[string]any{
+			field: budget,
+			"selector": map[string]any{
+				"matchLabels": matchLabels,
+			},
+		},
+	}}}, nil
+}
+
+// parseDisruptionBudget parses a number of pods, or a percentage of them.
+func parseDisruptionBudget(value string) (any, error) {
+	if percentage, isPercentage := strings.CutSuffix(value, "%"); isPercentage {
+		number, err := strconv.Atoi(percentage)
+		if err != nil || number < 0 || number > 100 {
+			return nil, fmt.Errorf("must be a percentage between 0%% and 100%%")
+		}
+		return value, nil
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 0 {
+		return nil, fmt.Errorf("must be a number of pods or a percentage, e.g. 1 or 50%%")
+	}
+	return int64(number), nil
+}
+
+func isWebhookConfig(dep v1alpha1.Dependency) bool {
+	return dep.GetKind() == "ValidatingWebhookConfiguration" || dep.GetKind() == "MutatingWebhookConfiguration"
+}
+
+func removeWebhookConfigs(dependencies []v1alpha1.Dependency) []v1alpha1.Dependency {
+	var kept []v1alpha1.Dependency
+	for _, dep := range dependencies {
+		if isWebhookConfig(dep) {
+			fmt.Fprintf(os.Stderr, "Dropped %s %s from the dependencies\n", dep.GetKind(), dep.GetName())
+			continue
+		}
+		kept = append(kept, dep)
+	}
+	return kept
+}
+
+// warnAboutMissingWebhookServices warns about webhook configurations calling
+// a Service which is not part of the dependencies: unless it is created some
+// other way, the API server will fail to call the webhook once installed.
+func warnAboutMissingWebhookServices(dependencies []v1alpha1.Dependency) {
+	services := map[string]bool{}
+	for _, dep := range dependencies {
+		if dep.GetKind() == "Service" {
+			services[dep.GetNamespace()+"/"+dep.GetName()] = true
+		}
+	}
+
+	for _, dep := range dependencies {
+		if !isWebhookConfig(dep) {
+			continue
+		}
+		webhooks, _, _ := unstructured.NestedSlice(dep.Object, "webhooks")
+		for _, webhook := range webhooks {
+			webhookMap, ok := webhook.(map[string]any)
+			if !ok {
+				continue
+			}
+			namespace, _, _ := unstructured.NestedString(webhookMap, "clientConfig", "service", "namespace")
+			name, found, _ := unstructured.NestedString(webhookMap, "clientConfig", "service", "name")
+			if !found || services[namespace+"/"+name] {
+				continue
+			}
+			webhookName, _, _ := unstructured.NestedString(webhookMap, "name")
+			fmt.Fprintf(os.Stderr, "Warning: webhook %s in %s %s calls Service %s/%s, which is not part of the dependencies; use --drop-webhook-configs to remove the webhook configurations\n",
+				webhookName, dep.GetKind(), dep.GetName(), namespace, name)
+		}
+	}
+}
+
+// removedAPIVersions lists commonly used apiVersions which are no longer
+// served by current Kubernetes releases.
+var removedAPIVersions = map[string]bool{
+	"admissionregistration.k8s.io/v1beta1": true,
+	"apiextensions.k8s.io/v1beta1":         true,
+	"apps/v1beta1":                         true,
+	"apps/v1beta2":                         true,
+	"autoscaling/v2beta1":                  true,
+	"autoscaling/v2beta2":                  true,
+	"batch/v1beta1":                        true,
+	"extensions/v1beta1":                   true,
+	"networking.k8s.io/v1beta1":            true,
+	"policy/v1beta1":                       true,
+	"rbac.authorization.k8s.io/v1beta1":    true,
+	"scheduling.k8s.io/v1beta1":            true,
+	"storage.k8s.io/v1beta1":               true,
+}
+
+// parseAPIVersionRewrites parses OLD=NEW apiVersion rewrites.
+func parseAPIVersionRewrites(rewrites []string) (map[string]string, error) {
+	parsed := map[string]string{}
+	for _, rewrite := range rewrites {
+		oldVersion, newVersion, _ := strings.Cut(rewrite, "=")
+		if oldVersion == "" || newVersion == "" {
+			return nil, fmt.Errorf("invalid apiVersion rewrite %q: expected OLD=NEW", rewrite)
+		}
+		for _, apiVersion := range []string{oldVersion, newVersion} {
+			if _, err := schema.ParseGroupVersion(apiVersion); err != nil {
+				return nil, fmt.Errorf("invalid apiVersion rewrite %q: %w", rewrite, err)
+			}
+		}
+		if _, found := parsed[oldVersion]; found {
+			return nil, fmt.Errorf("invalid apiVersion rewrite %q: %s is already rewritten", rewrite, oldVersion)
+		}
+		parsed[oldVersion] = newVersion
+	}
+	return parsed, nil
+}
+
+// rewriteAPIVersions replaces the apiVersion of the dependencies matching a
+// rewrite exactly. Only the apiVersion is changed: the rest of the object must
+// be valid for the new version. It warns about the rewrites matching no
+// dependency, and about the dependencies left with a removed apiVersion.
+func rewriteAPIVersions(dependencies []v1alpha1.Dependency, rewrites map[string]string) {
+	used := map[string]bool{}
+	for i := range dependencies {
+		newVersion, found := rewrites[dependencies[i].GetAPIVersion()]
+		if !found {
+			continue
+		}
+		used[dependencies[i].GetAPIVersion()] = true
+		dependencies[i].SetAPIVersion(newVersion)
+	}
+
+	var unused []string
+	for oldVersion := range rewrites {
+		if !used[oldVersion] {
+			unused = append(unused, oldVersion)
+		}
+	}
+	sort.Strings(unused)
+	for _, oldVersion := range unused {
+		fmt.Fprintf(os.Stderr, "Warning: no dependency has the apiVersion %s; ignoring the rewrite to %s\n", oldVersion, rewrites[oldVersion])
+	}
+
+	for _, dep := range dependencies {
+		if removedAPIVersions[dep.GetAPIVersion()] {
+			fmt.Fprintf(os.Stderr, "Warning: %s %s has the apiVersion %s, which is no longer served by Kubernetes; use --rewrite-apiversion to rewrite it\n",
+				dep.GetKind(), dep.GetName(), dep.GetAPIVersion())
+		}
+	}
+}
+
+// lastAppliedConfigAnnotation is always stripped from the dependencies: it holds
+// a copy of the whole object, as last applied by kubectl.
+const lastAppliedConfigAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// defaultMaxDependenciesBytes keeps the dependencies well within the 1.5MiB
+// etcd allows for a single object, leaving room for the rest of the Promise.
+const defaultMaxDependenciesBytes = 1 << 20
+
+// checkDependenciesSize fails when the serialized dependencies are larger than
+// limit bytes, as Kratix could not store the Promise. A limit of 0 disables
+// the check.
+func checkDependenciesSize(dependencies []v1alpha1.Dependency, limit int) error {
+	if limit < 0 {
+		return fmt.Errorf("invalid --max-dependencies-bytes %d: must not be negative", limit)
+	}
+	if limit == 0 {
+		return nil
+	}
+
+	depBytes, err := yamlsig.Marshal(dependencies)
+	if err != nil {
+		return err
+	}
+	if len(depBytes) > limit {
+		return fmt.Errorf("the %d dependencies are %d bytes, over the --max-dependencies-bytes limit of %d: check the operator manifests only include the operator, "+
+			"filter them with e.g. --drop-webhook-configs or --strip-annotation, or raise the limit", len(dependencies), len(depBytes), limit)
+	}
+	return nil
+}
+
+// helmAnnotations are stripped from the dependencies, unless
+// --preserve-helm-annotations is set: Helm adds them to the objects of a
+// release, and they mean nothing once Kratix applies the objects.
+// helm.sh/resource-policy is kept, as it still tells Helm not to delete an
+// object it adopts.
+var helmAnnotations = []string{
+	"helm.sh/hook",
+	"helm.sh/hook-weight",
+	"helm.sh/hook-delete-policy",
+	"meta.helm.sh/",
+}
+
+// stripAnnotations removes the annotations of the dependencies matching one of
+// the keys. A key ending in "/", e.g. foo.operator.io/, matches every annotation
+// with that prefix; any other key must match exactly.
+func stripAnnotations(dependencies []v1alpha1.Dependency, keys []string, preserveHelmAnnotations bool) {
+	keys = append([]string{lastAppliedConfigAnnotation}, keys...)
+	if !preserveHelmAnnotations {
+		keys = append(keys, helmAnnotations...)
+	}
+	for i := range dependencies {
+		annotations := dependencies[i].GetAnnotations()
+		if len(annotations) == 0 {
+			continue
+		}
+		for annotation := range annotations {
+			if annotationMatches(annotation, keys) {
+				delete(annotations, annotation)
+			}
+		}
+		if len(annotations) == 0 {
+			annotations = nil
+		}
+		dependencies[i].SetAnnotations(annotations)
+	}
+}
+
+func annotationMatches(annotation string, keys []string) bool {
+	for _, key := range keys {
+		if annotation == key || (strings.HasSuffix(key, "/") && strings.HasPrefix(annotation, key)) {
+			return true
+		}
+	}
+	return false
+}
+
+// clusterScopedKinds are the built-in kinds that are not namespaced.
+var clusterScopedKinds = map[string]bool{
+	"APIService":                     true,
+	"CSIDriver":                      true,
+	"CSINode":                        true,
+	"ClusterRole":                    true,
+	"ClusterRoleBinding":             true,
+	"CustomResourceDefinition":       true,
+	"IngressClass":                   true,
+	"MutatingWebhookConfiguration":   true,
+	"Namespace":                      true,
+	"PersistentVolume":               true,
+	"PriorityClass":                  true,
+	"RuntimeClass":                   true,
+	"StorageClass":                   true,
+	"ValidatingWebhookConfiguration": true,
+	"VolumeAttachment":               true,
+}
+
+// splitDependenciesByScope splits the dependencies into the cluster-scoped and
+// the namespaced ones, keeping their order. Besides the built-in cluster-scoped
+// kinds, the custom resources of Cluster-scoped CRDs in the dependencies are
+// cluster-scoped. Any other kind is taken as namespaced.
+func splitDependenciesByScope(dependencies []v1alpha1.Dependency) (cluster, namespaced []v1alpha1.Dependency) {
+	clusterScopedCRDs := map[string]bool{}
+	for _, dep := range dependencies {
+		if dep.GetKind() != "CustomResourceDefinition" {
+			continue
+		}
+		if scope, _, _ := unstructured.NestedString(dep.Object, "spec", "scope"); scope == string(apiextensionsv1.ClusterScoped) {
+			clusterScopedCRDs[crdKindAndGroup(dep.Object)] = true
+		}
+	}
+
+	cluster, namespaced = []v1alpha1.Dependency{}, []v1alpha1.Dependency{}
+	for _, dep := range dependencies {
+		kindAndGroup := dep.GetKind() + "." + dep.GroupVersionKind().Group
+		if clusterScopedKinds[dep.GetKind()] || clusterScopedCRDs[kindAndGroup] {
+			cluster = append(cluster, dep)
+		} else {
+			namespaced = append(namespaced, dep)
+		}
+	}
+	return cluster, namespaced
+}
+
+// dependenciesFiles returns the dependencies files to write: dependencies.yaml,
+// or, with --split-dependencies-by-scope, one file per scope.
+func dependenciesFiles(dependencies []v1alpha1.Dependency, dependenciesFile any) map[string]any {
+	if !splitDependenciesScope {
+		return map[string]any{dependenciesFileName: dependenciesFile}
+	}
+	cluster, namespaced := splitDependenciesByScope(dependencies)
+	return map[string]any{
+		clusterDependenciesFileName:    cluster,
+		namespacedDependenciesFileName: namespaced,
+	}
+}
+
+// normalizeImage fully qualifies an image without a registry with docker.io,
+// adding library/ to the official images: nginx:latest becomes
+// docker.io/library/nginx:latest, and repo/img becomes docker.io/repo/img. The
+// first part of the image is a registry when it has a "." or a ":", or is
+// localhost.
+func normalizeImage(image string) string {
+	first, _, found := strings.Cut(image, "/")
+	if !found {
+		return "docker.io/library/" + image
+	}
+	if strings.ContainsAny(first, ".:") || first == "localhost" {
+		return image
+	}
+	return "docker.io/" + image
+}
+
+// normalizeDependencyImages normalizes the images of the containers, init
+// containers and ephemeral containers anywhere in the dependencies, e.g. in the
+// pod template of a Deployment. CRDs are skipped, as their schemas only describe
+// containers.
+func normalizeDependencyImages(dependencies []v1alpha1.Dependency) {
+	for _, dep := range dependencies {
+		if dep.GetKind() == "CustomResourceDefinition" {
+			continue
+		}
+		normalizeContainerImages(dep.Object, dep.GetKind()+" "+dep.GetName())
+	}
+}
+
+func normalizeContainerImages(obj any, owner string) {
+	switch value := obj.(type) {
+	case map[string]any:
+		for key, field := range value {
+			if containers, ok := field.([]any); ok && (key == "containers" || key == "initContainers" || key == "ephemeralContainers") {
+				for _, c := range containers {
+					container, ok := c.(map[string]any)
+					if !ok {
+						continue
+					}
+					image, _ := container["image"].(string)
+					if normalized := normalizeImage(image); image != "" && normalized != image {
+						container["image"] = normalized
+						logVerbose("Normalized the image of %s: %s -> %s", owner, image, normalized)
+					}
+				}
+			}
+			normalizeContainerImages(field, owner)
+		}
+	case []any:
+		for _, item := range value {
+			normalizeContainerImages(item, owner)
+		}
+	}
+}
+
+// normalizeStepImages normalizes the images of the pipeline steps.
+func normalizeStepImages(steps []v1alpha1.Container) {
+	for i := range steps {
+		if normalized := normalizeImage(steps[i].Image); normalized != steps[i].Image {
+			logVerbose("Normalized the image of pipeline step %s: %s -> %s", steps[i].Name, steps[i].Image, normalized)
+			steps[i].Image = normalized
+		}
+	}
+}
+
+const redactedAnnotation = "kratix.io/redacted"
+
+// redactSecretValues warns about the Secrets of the dependencies holding values
+// and, when redact is set, blanks the values of their data and stringData,
+// keeping the keys so that the Secret still documents what the operator expects.
+func redactSecretValues(dependencies []v1alpha1.Dependency, redact bool) {
+	for _, dep := range dependencies {
+		if dep.GetKind() != "Secret" {
+			continue
+		}
+		var keys []string
+		for _, field := range []string{"data", "stringData"} {
+			values, _ := dep.Object[field].(map[string]any)
+			for key := range values {
+				if redact {
+					values[key] = ""
+				}
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) == 0 {
+			continue
+		}
+		sort.Strings(keys)
+
+		secret := dep.GetName()
+		if dep.GetNamespace() != "" {
+			secret = dep.GetNamespace() + "/" + secret
+		}
+		if !redact {
+			fmt.Fprintf(os.Stderr, "Warning: Secret %s is shipped in the dependencies with the values of its keys %s; use --redact-secrets to remove them\n", secret, strings.Join(keys, ", "))
+			continue
+		}
+
+		annotations := dep.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[redactedAnnotation] = "the values of data and stringData were removed by --redact-secrets"
+		dep.SetAnnotations(annotations)
+		fmt.Fprintf(os.Stderr, "Warning: SECRET REDACTED: the values of the keys %s of Secret %s were removed from the dependencies and must be set on the Destinations\n", strings.Join(keys, ", "), secret)
+	}
+}
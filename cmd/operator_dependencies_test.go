@@ -0,0 +1,93 @@
+package cmd_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/syntasso/kratix-cli/cmd"
+	"github.com/syntasso/kratix/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var _ = Describe("OperatorNetworkPolicy", func() {
+	var (
+		dependencies []v1alpha1.Dependency
+		options      *OperatorPodOptions
+	)
+
+	BeforeEach(func() {
+		dependencies = []v1alpha1.Dependency{
+			{Unstructured: unstructured.Unstructured{Object: map[string]any{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata":   map[string]any{"name": "operator", "namespace": "operator-system"},
+				"spec": map[string]any{
+					"selector": map[string]any{"matchLabels": map[string]any{"app": "operator"}},
+				},
+			}}},
+		}
+		options = &OperatorPodOptions{
+			NetworkPolicyIngressPorts: []int{8080},
+			NetworkPolicyWebhookPorts: []int{9443},
+			NetworkPolicyEgressPorts:  []int{443},
+		}
+	})
+
+	ingress := func(policy v1alpha1.Dependency) []any {
+		rules, _, _ := unstructured.NestedSlice(policy.Object, "spec", "ingress")
+		return rules
+	}
+
+	egress := func(policy v1alpha1.Dependency) []any {
+		rules, _, _ := unstructured.NestedSlice(policy.Object, "spec", "egress")
+		return rules
+	}
+
+	It("only allows the Kratix controller in without webhook configurations", func() {
+		policy, err := OperatorNetworkPolicy(dependencies, options)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ingress(policy)).To(HaveLen(1))
+		Expect(ingress(policy)[0]).To(HaveKey("from"))
+	})
+
+	It("opens the webhook ports to the API server with webhook configurations", func() {
+		dependencies = append(dependencies, v1alpha1.Dependency{Unstructured: unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "admissionregistration.k8s.io/v1",
+			"kind":       "ValidatingWebhookConfiguration",
+			"metadata":   map[string]any{"name": "operator-webhook"},
+		}}})
+		policy, err := OperatorNetworkPolicy(dependencies, options)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ingress(policy)).To(HaveLen(2))
+		Expect(ingress(policy)[1]).To(Equal(map[string]any{
+			"ports": []any{map[string]any{"protocol": "TCP", "port": int64(9443)}},
+		}))
+	})
+
+	It("allows the DNS and the Kubernetes API out", func() {
+		policy, err := OperatorNetworkPolicy(dependencies, options)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(egress(policy)).To(HaveLen(2))
+		Expect(egress(policy)[0]).To(HaveKeyWithValue("ports", []any{
+			map[string]any{"protocol": "UDP", "port": int64(53)},
+			map[string]any{"protocol": "TCP", "port": int64(53)},
+		}))
+		Expect(egress(policy)[1]).To(Equal(map[string]any{
+			"ports": []any{map[string]any{"protocol": "TCP", "port": int64(443)}},
+		}))
+	})
+
+	It("restricts the Kubernetes API egress to the CIDRs", func() {
+		options.NetworkPolicyAPICIDRs = []string{"10.0.0.1/32"}
+		policy, err := OperatorNetworkPolicy(dependencies, options)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(egress(policy)[1]).To(HaveKeyWithValue("to", []any{
+			map[string]any{"ipBlock": map[string]any{"cidr": "10.0.0.1/32"}},
+		}))
+	})
+
+	It("errors on an invalid CIDR", func() {
+		options.NetworkPolicyAPICIDRs = []string{"10.0.0.1"}
+		_, err := OperatorNetworkPolicy(dependencies, options)
+		Expect(err).To(MatchError(ContainSubstring(`invalid --network-policy-api-cidr "10.0.0.1"`)))
+	})
+})
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"github.com/spf13/pflag"
+	"github.com/syntasso/kratix/api/v1alpha1"
+)
+
+// OperatorPodOptions are the flags adding policies for the pods of the
+// operator Deployment to the dependencies.
+type OperatorPodOptions struct {
+	WithNetworkPolicy         bool
+	NetworkPolicyIngressPorts []int
+	NetworkPolicyWebhookPorts []int
+	NetworkPolicyEgressPorts  []int
+	NetworkPolicyAPICIDRs     []string
+	WithPDB                   bool
+	PDBMinAvailable           string
+	PDBMaxUnavailable         string
+}
+
+func (o *OperatorPodOptions) addFlags(flags *pflag.FlagSet) {
+	flags.BoolVar(&o.WithNetworkPolicy, "with-network-policy", false, "Add a NetworkPolicy for the operator pods to the Promise dependencies.")
+	flags.IntSliceVar(&o.NetworkPolicyIngressPorts, "network-policy-ingress-ports", []int{9443}, "The ports the Kratix controller is allowed to reach the operator on. Requires --with-network-policy.")
+	flags.IntSliceVar(&o.NetworkPolicyWebhookPorts, "network-policy-webhook-ports", []int{9443}, "The ports the Kubernetes API server is allowed to call the webhooks of the operator on, when it has webhook configurations. Requires --with-network-policy.")
+	flags.IntSliceVar(&o.NetworkPolicyEgressPorts, "network-policy-egress-ports", []int{443, 6443}, "The ports the operator is allowed to reach the Kubernetes API on. Requires --with-network-policy.")
+	flags.StringArrayVar(&o.NetworkPolicyAPICIDRs, "network-policy-api-cidr", nil, "A CIDR of the Kubernetes API server, to restrict the egress on --network-policy-egress-ports to. Can be specified multiple times. Requires --with-network-policy.")
+	flags.BoolVar(&o.WithPDB, "with-pdb", false, "Add a PodDisruptionBudget for the operator pods to the Promise dependencies.")
+	flags.StringVar(&o.PDBMinAvailable, "pdb-min-available", "", "The minAvailable of the PodDisruptionBudget, as a number of pods or a percentage. Requires --with-pdb.")
+	flags.StringVar(&o.PDBMaxUnavailable, "pdb-max-unavailable", "", "The maxUnavailable of the PodDisruptionBudget, as a number of pods or a percentage. Defaults to 1 without --pdb-min-available. Requires --with-pdb.")
+}
+
+// validate errors on the flags of the policies set without the flag adding
+// the policy.
+func (o *OperatorPodOptions) validate(flags *pflag.FlagSet) error {
+	if err := requireFlag(flags, "with-network-policy", o.WithNetworkPolicy,
+		"network-policy-ingress-ports", "network-policy-webhook-ports", "network-policy-egress-ports", "network-policy-api-cidr"); err != nil {
+		return err
+	}
+	return requireFlag(flags, "with-pdb", o.WithPDB, "pdb-min-available", "pdb-max-unavailable")
+}
+
+// dependencies generates the NetworkPolicy and PodDisruptionBudget set by the
+// options for the operator Deployment of operatorDependencies.
+func (o *OperatorPodOptions) dependencies(operatorDependencies []v1alpha1.Dependency) ([]v1alpha1.Dependency, error) {
+	var dependencies []v1alpha1.Dependency
+	if o.WithNetworkPolicy {
+		networkPolicy, err := operatorNetworkPolicy(operatorDependencies, o)
+		if err != nil {
+			return nil, err
+		}
+		dependencies = append(dependencies, networkPolicy)
+	}
+
+	if o.WithPDB {
+		pdb, err := operatorPodDisruptionBudget(operatorDependencies, o.PDBMinAvailable, o.PDBMaxUnavailable)
+		if err != nil {
+			return nil, err
+		}
+		if pdb != nil {
+			dependencies = append(dependencies, *pdb)
+		}
+	}
+	return dependencies, nil
+}
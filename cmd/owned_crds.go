@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/syntasso/kratix/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const clusterServiceVersionKind = "ClusterServiceVersion"
+
+// ownedCRDFlags are not forwarded to the generation of each owned CRD: the
+// CRD, the kind and the output directory are set per CRD, and the overwrite
+// confirmation is given once for the whole output directory.
+var ownedCRDFlags = map[string]bool{
+	"all-owned-crds":  true,
+	"api-schema-from": true,
+	"kind":            true,
+	"kind-from-crd":   true,
+	"dir":             true,
+	"force":           true,
+	"config":          true,
+}
+
+// ownedCRD is a CRD owned by the ClusterServiceVersion of the operator, with
+// the name and directory of the Promise generated for it.
+type ownedCRD struct {
+	name        string
+	promiseName string
+	dir         string
+}
+
+// findOwnedCRDs lists the CRDs owned by the ClusterServiceVersions of the
+// operator manifests. Each CRD gets the Promise PROMISE-NAME-<lowercase kind>,
+// in the subdirectory of the same name.
+func findOwnedCRDs(promiseName string, dependencies []v1alpha1.Dependency) ([]ownedCRD, error) {
+	var csvs int
+	var names []string
+	seen := map[string]bool{}
+	for _, dep := range dependencies {
+		if dep.GetKind() != clusterServiceVersionKind {
+			continue
+		}
+		csvs++
+		owned, _, err := unstructured.NestedSlice(dep.Object, "spec", "customresourcedefinitions", "owned")
+		if err != nil {
+			return nil, fmt.Errorf("invalid owned CRDs in %s %s: %s", clusterServiceVersionKind, dep.GetName(), err)
+		}
+		for _, item := range owned {
+			entry, _ := item.(map[string]any)
+			name, _ := entry["name"].(string)
+			if name == "" {
+				return nil, fmt.Errorf("invalid owned CRDs in %s %s: owned CRD without a name", clusterServiceVersionKind, dep.GetName())
+			}
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	if csvs == 0 {
+		return nil, fmt.Errorf("--all-owned-crds requires a %s in the operator manifests", clusterServiceVersionKind)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no owned CRDs listed in the %s of the operator manifests", clusterServiceVersionKind)
+	}
+
+	index := newCRDIndex(dependencies)
+	crdsByKind := map[string]string{}
+	var crds []ownedCRD
+	for _, name := range names {
+		crd, err := index.find(name)
+		if err != nil {
+			return nil, fmt.Errorf("owned CRD %s: %s", name, err)
+		}
+		kind := strings.ToLower(crd.Spec.Names.Kind)
+		if other, ok := crdsByKind[kind]; ok {
+			return nil, fmt.Errorf("owned CRDs %s and %s both have the kind %s: generate their Promises separately, with --api-schema-from and --kind", other, name, crd.Spec.Names.Kind)
+		}
+		crdsByKind[kind] = name
+
+		ownedPromiseName := promiseName + "-" + kind
+		crds = append(crds, ownedCRD{
+			name:        name,
+			promiseName: ownedPromiseName,
+			dir:         filepath.Join(outputDir, ownedPromiseName),
+		})
+	}
+	return crds, nil
+}
+
+// initPromisesFromOwnedCRDs generates a Promise for each CRD owned by the
+// ClusterServiceVersion of the operator, by running the command again for each
+// of them with the flags of cmd. Each Promise is generated in a process of its
+// own, as "kratix regenerate" does.
+func initPromisesFromOwnedCRDs(cmd *cobra.Command, crds []ownedCRD) error {
+	var args []string
+	cmd.Flags().Visit(func(flag *pflag.Flag) {
+		if ownedCRDFlags[flag.Name] {
+			return
+		}
+		values := []string{flag.Value.String()}
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			values = slice.GetSlice()
+		}
+		for _, value := range values {
+			args = append(args, fmt.Sprintf("--%s=%s", flag.Name, value))
+		}
+	})
+
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, crd := range crds {
+		fmt.Printf("Generating %s from %s in %s\n", crd.promiseName, crd.name, crd.dir)
+		commandArgs := append(strings.Fields(recordedSourceCommand), crd.promiseName)
+		commandArgs = append(commandArgs, args...)
+		commandArgs = append(commandArgs, "--api-schema-from="+crd.name, "--kind-from-crd", "--dir="+crd.dir)
+		if !fillMissing {
+			commandArgs = append(commandArgs, "--force")
+		}
+
+		generate := exec.Command(executable, commandArgs...)
+		generate.Stdout = os.Stdout
+		generate.Stderr = os.Stderr
+		if err := generate.Run(); err != nil {
+			failed = append(failed, crd.name)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to generate %d of %d Promises: %s", len(failed), len(crds), strings.Join(failed, ", "))
+	}
+	fmt.Printf("Generated %d Promises, one per owned CRD\n", len(crds))
+	return nil
+}
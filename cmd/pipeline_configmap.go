@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/syntasso/kratix/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const pipelineConfigVolumeName = "pipeline-config"
+
+// pipelineConfigMap reads the file of --pipeline-configmap into a ConfigMap
+// dependency named PROMISE-NAME-pipeline-config, with the file under its base
+// name, in namespace.
+func pipelineConfigMap(promiseName, path, namespace string) (v1alpha1.Dependency, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return v1alpha1.Dependency{}, fmt.Errorf("failed to read --pipeline-configmap %s: %s", path, err)
+	}
+	key := filepath.Base(path)
+	if errs := validation.IsConfigMapKey(key); len(errs) > 0 {
+		return v1alpha1.Dependency{}, fmt.Errorf("invalid --pipeline-configmap %s: %s is not a valid ConfigMap key: %s", path, key, strings.Join(errs, ", "))
+	}
+
+	configMap := unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]any{
+			"name":      promiseName + "-" + pipelineConfigVolumeName,
+			"namespace": namespace,
+		},
+		"data": map[string]any{key: string(content)},
+	}}
+	return v1alpha1.Dependency{Unstructured: configMap}, nil
+}
+
+// mountPipelineConfigMap mounts the ConfigMap of --pipeline-configmap at
+// mountPath in every container of the pipelines.
+func mountPipelineConfigMap(pipelines []unstructured.Unstructured, configMapName, mountPath string) {
+	mount := corev1.VolumeMount{Name: pipelineConfigVolumeName, MountPath: mountPath, ReadOnly: true}
+	for _, pipeline := range pipelines {
+		spec := pipeline.Object["spec"].(map[string]any)
+		containers := spec["containers"].([]any)
+		for i, container := range containers {
+			step := container.(v1alpha1.Container)
+			step.VolumeMounts = append(step.VolumeMounts, mount)
+			containers[i] = step
+		}
+		spec["volumes"] = []any{corev1.Volume{
+			Name: pipelineConfigVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: configMapName},
+				},
+			},
+		}}
+	}
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+)
+
+var profileMode, profileOutput string
+
+// stopProfile flushes the profile started by startProfile. It must be called
+// on every exit path, errors included, or the profile is left truncated.
+var stopProfile = func() error { return nil }
+
+func startProfile(mode, output string) error {
+	if mode == "" {
+		return nil
+	}
+	if mode != "cpu" && mode != "mem" {
+		return fmt.Errorf("invalid --profile %q: must be one of cpu, mem", mode)
+	}
+
+	file, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("failed to create profile output file: %w", err)
+	}
+
+	if mode == "cpu" {
+		if err := pprof.StartCPUProfile(file); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to start CPU profile: %w", err)
+		}
+		stopProfile = func() error {
+			pprof.StopCPUProfile()
+			return file.Close()
+		}
+		return nil
+	}
+
+	stopProfile = func() error {
+		runtime.GC()
+		if err := pprof.WriteHeapProfile(file); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to write memory profile: %w", err)
+		}
+		return file.Close()
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/client-go/util/jsonpath"
+	yamlsig "sigs.k8s.io/yaml"
+)
+
+// applyPropertiesFrom merges into the schema the properties inferred from the
+// values of sample resources, given as JSONPATH=SAMPLE-FILE. The JSONPath, e.g.
+// {.spec.tls} or .spec.tls, must be a path of fields from the root of the
+// resource: the same path is used in the schema.
+func applyPropertiesFrom(schema *apiextensionsv1.JSONSchemaProps, values []string) error {
+	for _, value := range values {
+		path, sampleFile, found := strings.Cut(value, "=")
+		if !found || path == "" || sampleFile == "" {
+			return fmt.Errorf("invalid --property-from %q: expected JSONPATH=SAMPLE-FILE", value)
+		}
+
+		fields, err := parseFieldPath(path)
+		if err != nil {
+			return fmt.Errorf("invalid --property-from %q: %s", value, err)
+		}
+		sample, err := readSampleResource(sampleFile)
+		if err != nil {
+			return fmt.Errorf("invalid --property-from %q: %s", value, err)
+		}
+
+		var sampleValue any = sample
+		for i, field := range fields {
+			object, ok := sampleValue.(map[string]any)
+			if !ok {
+				return fmt.Errorf("invalid --property-from %q: %s is not an object in %s", value, strings.Join(fields[:i], "."), sampleFile)
+			}
+			if sampleValue, ok = object[field]; !ok {
+				return fmt.Errorf("invalid --property-from %q: no value at %s in %s", value, path, sampleFile)
+			}
+		}
+
+		property, err := inferSchema(sampleValue)
+		if err != nil {
+			return fmt.Errorf("invalid --property-from %q: %s", value, err)
+		}
+		updated, err := mergeSchemaAt(*schema, fields, property)
+		if err != nil {
+			return fmt.Errorf("invalid --property-from %q: %s", value, err)
+		}
+		*schema = updated
+	}
+	return nil
+}
+
+// parseFieldPath parses a JSONPath made only of fields, with or without the
+// surrounding braces kubectl accepts.
+func parseFieldPath(path string) ([]string, error) {
+	expression := path
+	if !strings.HasPrefix(expression, "{") {
+		expression = "{" + strings.TrimPrefix(expression, "$") + "}"
+	}
+	parser, err := jsonpath.Parse("property-from", expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSONPath: %s", err)
+	}
+
+	if len(parser.Root.Nodes) != 1 {
+		return nil, fmt.Errorf("invalid JSONPath: expected a single expression")
+	}
+	list, ok := parser.Root.Nodes[0].(*jsonpath.ListNode)
+	if !ok || len(list.Nodes) == 0 {
+		return nil, fmt.Errorf("invalid JSONPath: expected a path such as .spec.tls")
+	}
+
+	var fields []string
+	for _, node := range list.Nodes {
+		field, ok := node.(*jsonpath.FieldNode)
+		if !ok {
+			return nil, fmt.Errorf("only paths of fields are supported, found %s", node)
+		}
+		if field.Value == "" {
+			continue
+		}
+		fields = append(fields, field.Value)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("the path must name a property, not the root of the resource")
+	}
+	return fields, nil
+}
+
+// readSampleResource reads a YAML or JSON resource, keeping the integers
+// apart from the other numbers.
+func readSampleResource(path string) (map[string]any, error) {
+	sampleBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sample %s: %s", path, err)
+	}
+	jsonBytes, err := yamlsig.YAMLToJSON(sampleBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse sample %s: %s", path, err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(jsonBytes))
+	decoder.UseNumber()
+	var sample map[string]any
+	if err := decoder.Decode(&sample); err != nil || sample == nil {
+		return nil, fmt.Errorf("failed to parse sample %s: expected a resource", path)
+	}
+	return sample, nil
+}
+
+// inferSchema infers the schema of a value. Arrays get the schema of their
+// first item, and empty arrays accept items of any content.
+func inferSchema(value any) (apiextensionsv1.JSONSchemaProps, error) {
+	switch typed := value.(type) {
+	case string:
+		return apiextensionsv1.JSONSchemaProps{Type: "string"}, nil
+	case bool:
+		return apiextensionsv1.JSONSchemaProps{Type: "boolean"}, nil
+	case json.Number:
+		if _, err := typed.Int64(); err == nil {
+			return apiextensionsv1.JSONSchemaProps{Type: "integer"}, nil
+		}
+		return apiextensionsv1.JSONSchemaProps{Type: "number"}, nil
+	case map[string]any:
+		schema := apiextensionsv1.JSONSchemaProps{Type: "object"}
+		if len(typed) == 0 {
+			schema.XPreserveUnknownFields = ptr(true)
+			return schema, nil
+		}
+		schema.Properties = map[string]apiextensionsv1.JSONSchemaProps{}
+		for name, item := range typed {
+			property, err := inferSchema(item)
+			if err != nil {
+				return schema, err
+			}
+			schema.Properties[name] = property
+		}
+		return schema, nil
+	case []any:
+		items := apiextensionsv1.JSONSchemaProps{XPreserveUnknownFields: ptr(true)}
+		if len(typed) > 0 {
+			var err error
+			if items, err = inferSchema(typed[0]); err != nil {
+				return items, err
+			}
+		}
+		return apiextensionsv1.JSONSchemaProps{
+			Type:  "array",
+			Items: &apiextensionsv1.JSONSchemaPropsOrArray{Schema: &items},
+		}, nil
+	default:
+		return apiextensionsv1.JSONSchemaProps{}, fmt.Errorf("cannot infer the type of a null value")
+	}
+}
+
+// mergeSchemaAt merges property into the schema at the path of fields, adding
+// the missing objects on the way.
+func mergeSchemaAt(schema apiextensionsv1.JSONSchemaProps, fields []string, property apiextensionsv1.JSONSchemaProps) (apiextensionsv1.JSONSchemaProps, error) {
+	if len(fields) == 0 {
+		return mergeSchema(schema, property)
+	}
+	if schema.Type != "" && schema.Type != "object" {
+		return schema, fmt.Errorf("cannot add %q to a property of type %s", fields[0], schema.Type)
+	}
+
+	schema.Type = "object"
+	merged, err := mergeSchemaAt(schema.Properties[fields[0]], fields[1:], property)
+	if err != nil {
+		return schema, err
+	}
+	if schema.Properties == nil {
+		schema.Properties = map[string]apiextensionsv1.JSONSchemaProps{}
+	}
+	schema.Properties[fields[0]] = merged
+	return schema, nil
+}
+
+// mergeSchema adds the properties of inferred missing from the schema, keeping
+// the schema of existing properties.
+func mergeSchema(schema, inferred apiextensionsv1.JSONSchemaProps) (apiextensionsv1.JSONSchemaProps, error) {
+	if schema.Type == "" {
+		return inferred, nil
+	}
+	if schema.Type == "number" && inferred.Type == "integer" {
+		return schema, nil
+	}
+	if schema.Type != inferred.Type {
+		return schema, fmt.Errorf("the sample value is of type %s, but the property is of type %s", inferred.Type, schema.Type)
+	}
+
+	switch schema.Type {
+	case "object":
+		if schema.AdditionalProperties != nil && len(inferred.Properties) > 0 {
+			// properties and additionalProperties cannot be set together
+			return schema, nil
+		}
+		for name, property := range inferred.Properties {
+			merged, err := mergeSchema(schema.Properties[name], property)
+			if err != nil {
+				return schema, err
+			}
+			if schema.Properties == nil {
+				schema.Properties = map[string]apiextensionsv1.JSONSchemaProps{}
+			}
+			schema.Properties[name] = merged
+		}
+	case "array":
+		if schema.Items != nil && schema.Items.Schema != nil && inferred.Items.Schema.Type != "" {
+			merged, err := mergeSchema(*schema.Items.Schema, *inferred.Items.Schema)
+			if err != nil {
+				return schema, err
+			}
+			schema.Items.Schema = &merged
+		}
+	}
+	return schema, nil
+}
@@ -0,0 +1,83 @@
+package cmd_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/syntasso/kratix-cli/cmd"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+var _ = Describe("ApplyPropertiesFrom", func() {
+	var schema *apiextensionsv1.JSONSchemaProps
+	var samplePath string
+
+	BeforeEach(func() {
+		schema = &apiextensionsv1.JSONSchemaProps{
+			Type: "object",
+			Properties: map[string]apiextensionsv1.JSONSchemaProps{
+				"spec": {
+					Type: "object",
+					Properties: map[string]apiextensionsv1.JSONSchemaProps{
+						"size": {Type: "string", Description: "The size"},
+					},
+				},
+			},
+		}
+
+		samplePath = filepath.Join(GinkgoT().TempDir(), "sample.yaml")
+		Expect(os.WriteFile(samplePath, []byte(`apiVersion: example.com/v1
+kind: Cache
+metadata:
+  name: example
+spec:
+  size: large
+  replicas: 3
+  tls:
+    enabled: true
+    ratio: 0.5
+    hosts:
+      - cache.example.com
+`), 0644)).To(Succeed())
+	})
+
+	It("infers the schema of the value and merges it under the same path", func() {
+		Expect(ApplyPropertiesFrom(schema, []string{".spec.tls=" + samplePath, "{.spec.replicas}=" + samplePath, ".spec.size=" + samplePath})).To(Succeed())
+
+		spec := schema.Properties["spec"]
+		Expect(spec.Properties["size"]).To(Equal(apiextensionsv1.JSONSchemaProps{Type: "string", Description: "The size"}))
+		Expect(spec.Properties["replicas"]).To(Equal(apiextensionsv1.JSONSchemaProps{Type: "integer"}))
+		Expect(spec.Properties["tls"]).To(Equal(apiextensionsv1.JSONSchemaProps{
+			Type: "object",
+			Properties: map[string]apiextensionsv1.JSONSchemaProps{
+				"enabled": {Type: "boolean"},
+				"ratio":   {Type: "number"},
+				"hosts": {
+					Type:  "array",
+					Items: &apiextensionsv1.JSONSchemaPropsOrArray{Schema: &apiextensionsv1.JSONSchemaProps{Type: "string"}},
+				},
+			},
+		}))
+	})
+
+	DescribeTable("errors on invalid values",
+		func(value, expectedErr string) {
+			Expect(ApplyPropertiesFrom(schema, []string{value})).To(MatchError(ContainSubstring(expectedErr)))
+		},
+		Entry("without a sample file", ".spec.tls", "expected JSONPATH=SAMPLE-FILE"),
+		Entry("with an invalid JSONPath", "{.spec[}=sample.yaml", "invalid JSONPath"),
+		Entry("with a JSONPath filter", "{.spec.tls.hosts[0]}=sample.yaml", "only paths of fields are supported"),
+		Entry("with a missing sample file", ".spec.tls=missing.yaml", "failed to read sample missing.yaml"),
+	)
+
+	It("errors when the sample has no value at the path", func() {
+		Expect(ApplyPropertiesFrom(schema, []string{".spec.storage=" + samplePath})).To(MatchError(ContainSubstring("no value at .spec.storage")))
+	})
+
+	It("errors when the value does not match the type of the property", func() {
+		schema.Properties["spec"].Properties["replicas"] = apiextensionsv1.JSONSchemaProps{Type: "string"}
+		Expect(ApplyPropertiesFrom(schema, []string{".spec.replicas=" + samplePath})).To(MatchError(ContainSubstring("the sample value is of type integer, but the property is of type string")))
+	})
+})
@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/syntasso/kratix-cli/internal"
+	yamlsig "sigs.k8s.io/yaml"
+)
+
+const sourceRecordFileName = ".kratix-source.yaml"
+
+// recordedSourceCommand is the only command writing a source record.
+const recordedSourceCommand = "init operator-promise"
+
+var regenerateCmd = &cobra.Command{
+	Use:   "regenerate",
+	Short: "Regenerate the Promises generated with --record-source",
+	Long: `Regenerate the Promises generated with --record-source.
+
+"kratix init operator-promise --record-source" writes a .kratix-source.yaml
+file next to the Promise, recording the operator manifests and the flags the
+Promise was generated with. The command finds these files under --root and
+generates each Promise again, in the directory of its record, overwriting the
+generated files, e.g. after bumping the operator release the manifests are
+read from.
+
+The paths of the record are relative to its directory, so the Promises can be
+regenerated from any checkout of the repository. Each Promise is generated in
+a process of its own, and the command fails once all the Promises are
+regenerated when any of them failed. Recorded post-hooks run again: only
+regenerate records you trust.`,
+	Example: `  # regenerate every recorded Promise under promises/
+  kratix regenerate --root promises/`,
+	Args: cobra.NoArgs,
+	RunE: regeneratePromises,
+}
+
+var (
+	regenerateRoot string
+	recordSource   bool
+)
+
+// unrecordedFlags are left out of the source record: the output directory and
+// the overwrite behaviour are set by regenerate, and the others do not change
+// the generated Promise.
+var unrecordedFlags = map[string]bool{
+	"dir":            true,
+	"force":          true,
+	"fill-missing":   true,
+	"record-source":  true,
+	"config":         true,
+	"verbose":        true,
+	"error-format":   true,
+	"print-config":   true,
+	"print-crd-diff": true,
+	"sign-with":      true,
+	"validate-only":  true,
+	"profile":        true,
+	"profile-output": true,
+}
+
+// recordedPathFlags are the flags taking a local path, recorded relative to the
+// directory of the source record.
+var recordedPathFlags = map[string]bool{
+	"operator-manifests":         true,
+	"plural-dictionary":          true,
+	"dependency-patch":           true,
+	"destination-selectors-file": true,
+	"emit-form-schema":           true,
+	"operator-base-spec":         true,
+	"webhook-ca-bundle":          true,
+	"pipeline-configmap":         true,
+	"sync-waves":                 true,
+	"schema-patch":               true,
+}
+
+func init() {
+	rootCmd.AddCommand(regenerateCmd)
+	regenerateCmd.Flags().StringVar(&regenerateRoot, "root", ".", "The directory to search for .kratix-source.yaml records")
+}
+
+// promiseSource records how a Promise was generated, so that "kratix
+// regenerate" can generate it again.
+type promiseSource struct {
+	Command string   `json:"command"`
+	Name    string   `json:"name"`
+	Args    []string `json:"args,omitempty"`
+}
+
+// newPromiseSource records the flags set on cmd, with the local paths made
+// relative to the output directory the record is written to.
+func newPromiseSource(cmd *cobra.Command, promiseName, outputDir string) (*promiseSource, error) {
+	recordDir, err := filepath.Abs(outputDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var args []string
+	cmd.Flags().Visit(func(flag *pflag.Flag) {
+		if err != nil || unrecordedFlags[flag.Name] {
+			return
+		}
+		values := []string{flag.Value.String()}
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			values = slice.GetSlice()
+		}
+		for _, value := range values {
+			if recordedPathFlags[flag.Name] && value != "" && !internal.IsGitSource(value) {
+				var path string
+				if path, err = filepath.Abs(value); err != nil {
+					return
+				}
+				if value, err = filepath.Rel(recordDir, path); err != nil {
+					return
+				}
+				value = filepath.ToSlash(value)
+			}
+			args = append(args, fmt.Sprintf("--%s=%s", flag.Name, value))
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record the source of the Promise: %s", err)
+	}
+
+	return &promiseSource{
+		Command: strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" "),
+		Name:    promiseName,
+		Args:    args,
+	}, nil
+}
+
+func readPromiseSource(path string) (*promiseSource, error) {
+	sourceBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %s", path, err)
+	}
+
+	var source promiseSource
+	if err := yamlsig.UnmarshalStrict(sourceBytes, &source); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %s", path, err)
+	}
+	if source.Command != recordedSourceCommand {
+		return nil, fmt.Errorf("unsupported command %q in %s: must be %s", source.Command, path, recordedSourceCommand)
+	}
+	if source.Name == "" {
+		return nil, fmt.Errorf("no Promise name in %s", path)
+	}
+	return &source, nil
+}
+
+// findSourceRecords lists the source records under root, in lexical order.
+func findSourceRecords(root string) ([]string, error) {
+	var records []string
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() && entry.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !entry.IsDir() && entry.Name() == sourceRecordFileName {
+			records = append(records, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search %s: %s", root, err)
+	}
+	sort.Strings(records)
+	return records, nil
+}
+
+func regeneratePromises(cmd *cobra.Command, args []string) error {
+	records, err := findSourceRecords(regenerateRoot)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("no %s found under %s", sourceRecordFileName, regenerateRoot)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, record := range records {
+		promiseDir := filepath.Dir(record)
+		source, err := readPromiseSource(record)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			failed = append(failed, promiseDir)
+			continue
+		}
+
+		fmt.Printf("Regenerating %s in %s\n", source.Name, promiseDir)
+		commandArgs := append(strings.Fields(source.Command), source.Name)
+		commandArgs = append(commandArgs, source.Args...)
+		commandArgs = append(commandArgs, "--dir=.", "--force", "--record-source")
+		if verbose {
+			commandArgs = append(commandArgs, "--verbose")
+		}
+
+		regenerate := exec.Command(executable, commandArgs...)
+		regenerate.Dir = promiseDir
+		regenerate.Stdout = os.Stdout
+		regenerate.Stderr = os.Stderr
+		if err := regenerate.Run(); err != nil {
+			failed = append(failed, promiseDir)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to regenerate %d of %d Promises: %s", len(failed), len(records), strings.Join(failed, ", "))
+	}
+	fmt.Printf("Regenerated %d Promises\n", len(records))
+	return nil
+}
@@ -24,18 +24,78 @@ var rootCmd = &cobra.Command{
 	Example: `  # To initialize a new promise
   kratix init promise promise-name --group myorg.com --kind Database
 `,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateErrorFormat(errorFormat); err != nil {
+			return err
+		}
+		// --gvk is applied before the config file, so its parts take
+		// precedence over the config file, and again after it, for a --gvk
+		// set in the config file
+		if err := applyGVK(cmd); err != nil {
+			return err
+		}
+		config, err := loadConfig(configFile)
+		if err != nil {
+			return err
+		}
+		if err := applyConfig(cmd, config); err != nil {
+			return err
+		}
+		if err := applyGVK(cmd); err != nil {
+			return err
+		}
+		if err := validateKratixAPIVersion(kratixAPIVersion); err != nil {
+			return err
+		}
+		return startProfile(profileMode, profileOutput)
+	},
+}
+
+var (
+	errorFormat string
+	verbose     bool
+)
+
+// logVerbose prints details about what the command does to stderr, when
+// --verbose is set.
+func logVerbose(format string, a ...any) {
+	if verbose {
+		fmt.Fprintf(os.Stderr, format+"\n", a...)
+	}
 }
 
 func Execute(version string) {
 	rootCmd.Version = version
-	err := rootCmd.Execute()
+	cmd, err := rootCmd.ExecuteC()
+	if profileErr := stopProfile(); profileErr != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", profileErr)
+	}
 	if err != nil {
+		format := errorFormat
+		if format == errorFormatText {
+			format = errorFormatFromArgs(os.Args[1:])
+		}
+		printError(cmd, err, format)
 		os.Exit(1)
 	}
 }
 
 func init() {
+	// errors are printed by Execute, in the format of --error-format
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	rootCmd.PersistentFlags().StringVar(&errorFormat, "error-format", errorFormatText, "The format errors are printed in. One of: text, json")
+	rootCmd.PersistentFlags().StringVar(&kratixAPIVersion, "kratix-api-version", defaultKratixAPIVersion, "The version of the platform.kratix.io API of the generated Promises and Pipelines. One of: "+strings.Join(supportedKratixAPIVersions, ", "))
+	rootCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "Print details about what the command does")
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "A YAML file of flag defaults, e.g. group: myorg.com. Defaults to kratix.yaml in the current directory, when it exists. Flags take precedence over the file")
+
+	// profiling is meant for debugging performance issues reported by users,
+	// so the flags are not advertised
+	rootCmd.PersistentFlags().StringVar(&profileMode, "profile", "", "Profile the command. One of: cpu, mem")
+	rootCmd.PersistentFlags().StringVar(&profileOutput, "profile-output", "kratix.pprof", "The file to write the profile to")
+	rootCmd.PersistentFlags().MarkHidden("profile")
+	rootCmd.PersistentFlags().MarkHidden("profile-output")
 }
 
 func templateFiles(templates embed.FS, outputDir string, filesToTemplate map[string]string, templateValues interface{}) error {
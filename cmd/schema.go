@@ -0,0 +1,534 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	structuralschema "k8s.io/apiextensions-apiserver/pkg/apiserver/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// pruneSchemaDepth replaces the subtrees nested deeper than limit with a
+// schema preserving unknown fields. The properties of the root schema, such as
+// spec, are at depth 1.
+func pruneSchemaDepth(schema *apiextensionsv1.JSONSchemaProps, limit int) {
+	for name, property := range schema.Properties {
+		schema.Properties[name] = pruneSchemaProps(property, 1, limit)
+	}
+}
+
+func pruneSchemaProps(schema apiextensionsv1.JSONSchemaProps, depth, limit int) apiextensionsv1.JSONSchemaProps {
+	if depth >= limit {
+		if !hasNestedSchema(schema) {
+			return schema
+		}
+		pruned := apiextensionsv1.JSONSchemaProps{
+			Type:        schema.Type,
+			Description: schema.Description,
+		}
+		if schema.Type == "array" {
+			pruned.Items = &apiextensionsv1.JSONSchemaPropsOrArray{
+				Schema: &apiextensionsv1.JSONSchemaProps{XPreserveUnknownFields: ptr(true)},
+			}
+		} else {
+			pruned.XPreserveUnknownFields = ptr(true)
+		}
+		return pruned
+	}
+
+	for name, property := range schema.Properties {
+		schema.Properties[name] = pruneSchemaProps(property, depth+1, limit)
+	}
+	if schema.Items != nil && schema.Items.Schema != nil {
+		items := pruneSchemaProps(*schema.Items.Schema, depth+1, limit)
+		schema.Items.Schema = &items
+	}
+	if schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil {
+		additionalProperties := pruneSchemaProps(*schema.AdditionalProperties.Schema, depth+1, limit)
+		schema.AdditionalProperties.Schema = &additionalProperties
+	}
+	return schema
+}
+
+func hasNestedSchema(schema apiextensionsv1.JSONSchemaProps) bool {
+	return len(schema.Properties) > 0 ||
+		(schema.Items != nil && (schema.Items.Schema != nil || len(schema.Items.JSONSchemas) > 0)) ||
+		(schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// trimSchemaProperties removes the root properties of the schema which are not
+// exposed. apiVersion, kind and metadata are always kept.
+func trimSchemaProperties(schema *apiextensionsv1.JSONSchemaProps, exposed []string) error {
+	var available []string
+	for name := range schema.Properties {
+		available = append(available, name)
+	}
+	sort.Strings(available)
+
+	for _, name := range exposed {
+		if _, found := schema.Properties[name]; !found {
+			return newCLIError(errCodeInvalidProperty, map[string]any{"property": name, "availableProperties": available},
+				"cannot expose %q: not a property of the CRD, available properties are: %s", name, strings.Join(available, ", "))
+		}
+	}
+
+	keep := append([]string{"apiVersion", "kind", "metadata"}, exposed...)
+	for name := range schema.Properties {
+		if !slices.Contains(keep, name) {
+			delete(schema.Properties, name)
+		}
+	}
+
+	var required []string
+	for _, name := range schema.Required {
+		if slices.Contains(keep, name) {
+			required = append(required, name)
+		}
+	}
+	schema.Required = required
+	return nil
+}
+
+// celSelfField matches the fields of self, or oldSelf, in a CEL rule, e.g.
+// self.spec in self.spec.replicas > 0.
+var celSelfField = regexp.MustCompile(`\b(?:self|oldSelf)\.([A-Za-z_][A-Za-z0-9_]*)`)
+
+// pruneUnexposedReferences removes what still refers to the properties left
+// out with --expose: the printer columns reading a path missing from the
+// schema, and the validation rules of the root schema on a removed root
+// property, which would fail to compile. The subtrees of the removed
+// properties go with them, as CRD schemas cannot share definitions.
+func pruneUnexposedReferences(crdVersion *apiextensionsv1.CustomResourceDefinitionVersion) {
+	schema := crdVersion.Schema.OpenAPIV3Schema
+
+	var columns []apiextensionsv1.CustomResourceColumnDefinition
+	for _, column := range crdVersion.AdditionalPrinterColumns {
+		if !schemaHasPath(*schema, column.JSONPath) {
+			fmt.Fprintf(os.Stderr, "Warning: dropped the printer column %s: %s is not exposed\n", column.Name, column.JSONPath)
+			continue
+		}
+		columns = append(columns, column)
+	}
+	crdVersion.AdditionalPrinterColumns = columns
+
+	var rules apiextensionsv1.ValidationRules
+	for _, rule := range schema.XValidations {
+		if field := unexposedRuleField(*schema, rule.Rule); field != "" {
+			fmt.Fprintf(os.Stderr, "Warning: dropped the validation rule %q: %s is not exposed\n", rule.Rule, field)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	schema.XValidations = rules
+}
+
+// schemaHasPath reports whether the JSONPath of a printer column, e.g.
+// .spec.volume.size, .status.conditions[0].type or
+// .status.conditions[?(@.type=="Ready")].status, can be set in the schema.
+// The parts of the schema accepting unknown fields accept any path, and so do
+// the paths the parser cannot follow, such as recursive descents.
+func schemaHasPath(schema apiextensionsv1.JSONSchemaProps, jsonPath string) bool {
+	parser, err := jsonpath.Parse("printer-column", "{"+jsonPath+"}")
+	if err != nil || len(parser.Root.Nodes) != 1 {
+		return true
+	}
+	list, ok := parser.Root.Nodes[0].(*jsonpath.ListNode)
+	if !ok {
+		return true
+	}
+
+	for _, node := range list.Nodes {
+		switch node := node.(type) {
+		case *jsonpath.FieldNode:
+			if node.Value == "" {
+				continue
+			}
+			if len(schema.Properties) == 0 {
+				return true
+			}
+			property, found := schema.Properties[node.Value]
+			if !found {
+				return schemaAcceptsUnknownFields(schema)
+			}
+			schema = property
+		case *jsonpath.ArrayNode, *jsonpath.FilterNode, *jsonpath.WildcardNode:
+			if schema.Items == nil || schema.Items.Schema == nil {
+				return true
+			}
+			schema = *schema.Items.Schema
+		default:
+			return true
+		}
+	}
+	return true
+}
+
+func schemaAcceptsUnknownFields(schema apiextensionsv1.JSONSchemaProps) bool {
+	return (schema.XPreserveUnknownFields != nil && *schema.XPreserveUnknownFields) || schema.AdditionalProperties != nil
+}
+
+// unexposedRuleField returns the first field of self used by rule which is not
+// a property of the root schema, or an empty string.
+func unexposedRuleField(schema apiextensionsv1.JSONSchemaProps, rule string) string {
+	for _, match := range celSelfField.FindAllStringSubmatch(rule, -1) {
+		if _, found := schema.Properties[match[1]]; !found {
+			return "self." + match[1]
+		}
+	}
+	return ""
+}
+
+// jsonSchemaDraft is the JSON Schema draft matching the OpenAPI v3 schemas of
+// CRDs, where exclusiveMinimum and exclusiveMaximum are booleans.
+const jsonSchemaDraft = "http://json-schema.org/draft-04/schema#"
+
+// toJSONSchema converts a CRD schema to a standalone JSON Schema. Nullable
+// types become a union with null, x-kubernetes-int-or-string becomes a union
+// of integer and string, and the other x-kubernetes-* extensions are dropped.
+func toJSONSchema(schema *apiextensionsv1.JSONSchemaProps, title string) (map[string]any, error) {
+	schemaBytes, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	var jsonSchema map[string]any
+	if err := json.Unmarshal(schemaBytes, &jsonSchema); err != nil {
+		return nil, err
+	}
+
+	convertToJSONSchema(jsonSchema)
+	jsonSchema["$schema"] = jsonSchemaDraft
+	if title != "" {
+		jsonSchema["title"] = title
+	}
+	return jsonSchema, nil
+}
+
+func convertToJSONSchema(schema map[string]any) {
+	if intOrString, _ := schema["x-kubernetes-int-or-string"].(bool); intOrString && schema["type"] == nil {
+		schema["anyOf"] = []any{map[string]any{"type": "integer"}, map[string]any{"type": "string"}}
+	}
+	if nullable, _ := schema["nullable"].(bool); nullable {
+		if schemaType, ok := schema["type"].(string); ok {
+			schema["type"] = []any{schemaType, "null"}
+		}
+	}
+	delete(schema, "nullable")
+	for key := range schema {
+		if strings.HasPrefix(key, "x-kubernetes-") {
+			delete(schema, key)
+		}
+	}
+
+	for _, key := range []string{"properties", "patternProperties", "definitions"} {
+		if children, ok := schema[key].(map[string]any); ok {
+			for _, child := range children {
+				convertSubschemas(child)
+			}
+		}
+	}
+	for _, key := range []string{"items", "additionalProperties", "additionalItems", "not", "allOf", "anyOf", "oneOf"} {
+		convertSubschemas(schema[key])
+	}
+}
+
+func convertSubschemas(value any) {
+	switch v := value.(type) {
+	case map[string]any:
+		convertToJSONSchema(v)
+	case []any:
+		for _, item := range v {
+			convertSubschemas(item)
+		}
+	}
+}
+
+// formField is a field of the Promise API, in the format expected by
+// developer portal forms.
+type formField struct {
+	Name        string                 `json:"name"`
+	Type        string                 `json:"type,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Enum        []apiextensionsv1.JSON `json:"enum,omitempty"`
+	Default     *apiextensionsv1.JSON  `json:"default,omitempty"`
+	Required    bool                   `json:"required"`
+}
+
+// formSchema flattens the spec of the API schema into a list of fields,
+// sorted by name. Nested objects are not fields themselves: their properties
+// are, named with the dot-separated path from the spec. A nested field is
+// only required when it and all the objects it is nested in are required.
+// Arrays, and objects without properties such as maps, are single fields.
+func formSchema(schema *apiextensionsv1.JSONSchemaProps) []formField {
+	spec, found := schema.Properties["spec"]
+	if !found {
+		return []formField{}
+	}
+
+	fields := flattenFormFields("", spec, true)
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].Name < fields[j].Name
+	})
+	return fields
+}
+
+func flattenFormFields(prefix string, schema apiextensionsv1.JSONSchemaProps, required bool) []formField {
+	fields := []formField{}
+	for name, property := range schema.Properties {
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+		propertyRequired := required && slices.Contains(schema.Required, name)
+
+		if property.Type == "object" && len(property.Properties) > 0 {
+			fields = append(fields, flattenFormFields(path, property, propertyRequired)...)
+			continue
+		}
+		fields = append(fields, formField{
+			Name:        path,
+			Type:        property.Type,
+			Description: property.Description,
+			Enum:        property.Enum,
+			Default:     property.Default,
+			Required:    propertyRequired,
+		})
+	}
+	return fields
+}
+
+// ensureMetadataSchema makes the metadata property of the root schema an
+// object, adding it when the CRD does not define it: Kubernetes requires
+// metadata to be an object, and the structural schema of every property to
+// have a type.
+func ensureMetadataSchema(schema *apiextensionsv1.JSONSchemaProps) {
+	if schema.Properties == nil {
+		schema.Properties = map[string]apiextensionsv1.JSONSchemaProps{}
+	}
+	metadata := schema.Properties["metadata"]
+	if metadata.Type == "" {
+		metadata.Type = "object"
+	}
+	schema.Properties["metadata"] = metadata
+}
+
+// validateStructuralSchemas checks the schema of every version of the CRD is
+// structural, as the API server requires of apiextensions.k8s.io/v1 CRDs.
+func validateStructuralSchemas(crd *apiextensionsv1.CustomResourceDefinition) error {
+	for _, crdVersion := range crd.Spec.Versions {
+		if crdVersion.Schema == nil || crdVersion.Schema.OpenAPIV3Schema == nil {
+			return fmt.Errorf("version %s of CRD %s has no schema", crdVersion.Name, crd.Name)
+		}
+
+		var schema apiextensions.JSONSchemaProps
+		if err := apiextensionsv1.Convert_v1_JSONSchemaProps_To_apiextensions_JSONSchemaProps(crdVersion.Schema.OpenAPIV3Schema, &schema, nil); err != nil {
+			return fmt.Errorf("failed to convert the schema of version %s of CRD %s: %w", crdVersion.Name, crd.Name, err)
+		}
+		structural, err := structuralschema.NewStructural(&schema)
+		if err != nil {
+			return fmt.Errorf("the schema of version %s of CRD %s is not structural: %w", crdVersion.Name, crd.Name, err)
+		}
+		path := field.NewPath("spec", "versions").Key(crdVersion.Name).Child("schema", "openAPIV3Schema")
+		if errs := structuralschema.ValidateStructural(path, structural); len(errs) > 0 {
+			return fmt.Errorf("the schema of version %s of CRD %s is not structural: %w", crdVersion.Name, crd.Name, errs.ToAggregate())
+		}
+	}
+	return nil
+}
+
+// applyEnums restricts the properties named by the PROPERTY=VALUE,VALUE values
+// to the listed values, where PROPERTY is the dot-separated path of the
+// property from the root schema, e.g. spec.storageClass. The values are parsed
+// as the type of the property, which must be a string, integer, number or
+// boolean.
+func applyEnums(schema *apiextensionsv1.JSONSchemaProps, values []string) error {
+	for _, value := range values {
+		path, rawValues, found := strings.Cut(value, "=")
+		if !found || path == "" || rawValues == "" {
+			return fmt.Errorf("invalid --enum %q: expected PROPERTY=VALUE,VALUE", value)
+		}
+
+		updated, err := setPropertyEnum(*schema, strings.Split(path, "."), strings.Split(rawValues, ","))
+		if err != nil {
+			return fmt.Errorf("invalid --enum %q: %s", value, err)
+		}
+		*schema = updated
+	}
+	return nil
+}
+
+func setPropertyEnum(schema apiextensionsv1.JSONSchemaProps, path, values []string) (apiextensionsv1.JSONSchemaProps, error) {
+	if len(path) == 0 {
+		enum, err := encodeEnumValues(schema.Type, values)
+		if err != nil {
+			return schema, err
+		}
+		schema.Enum = enum
+		return schema, nil
+	}
+
+	property, found := schema.Properties[path[0]]
+	if !found {
+		return schema, fmt.Errorf("%s is not a property of the CRD", path[0])
+	}
+	property, err := setPropertyEnum(property, path[1:], values)
+	if err != nil {
+		return schema, err
+	}
+	schema.Properties[path[0]] = property
+	return schema, nil
+}
+
+// encodeEnumValues JSON-encodes the values as the given schema type.
+func encodeEnumValues(schemaType string, values []string) ([]apiextensionsv1.JSON, error) {
+	var enum []apiextensionsv1.JSON
+	for _, value := range values {
+		var encoded any = value
+		var err error
+		switch schemaType {
+		case "string":
+		case "integer":
+			encoded, err = strconv.ParseInt(value, 10, 64)
+		case "number":
+			encoded, err = strconv.ParseFloat(value, 64)
+		case "boolean":
+			encoded, err = strconv.ParseBool(value)
+		default:
+			return nil, fmt.Errorf("cannot set an enum on a property of type %q: must be string, integer, number or boolean", schemaType)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("value %q is not of type %s", value, schemaType)
+		}
+
+		raw, err := json.Marshal(encoded)
+		if err != nil {
+			return nil, err
+		}
+		enum = append(enum, apiextensionsv1.JSON{Raw: raw})
+	}
+	return enum, nil
+}
+
+// reservedRootProperties are the properties of the root schema which are not
+// fields of the API.
+var reservedRootProperties = []string{"apiVersion", "kind", "metadata", "spec", "status"}
+
+// wrapSpec moves the properties of the root schema, other than the reserved
+// ones, to the spec property, with their required flags. It returns the names
+// of the moved properties, in lexical order, and leaves schemas without any
+// unchanged.
+func wrapSpec(schema *apiextensionsv1.JSONSchemaProps) ([]string, error) {
+	var moved []string
+	for name := range schema.Properties {
+		if !slices.Contains(reservedRootProperties, name) {
+			moved = append(moved, name)
+		}
+	}
+	if len(moved) == 0 {
+		return nil, nil
+	}
+	sort.Strings(moved)
+
+	spec, hasSpec := schema.Properties["spec"]
+	if hasSpec && spec.Type != "object" {
+		return nil, fmt.Errorf("cannot wrap the root properties in spec: spec is of type %s", spec.Type)
+	}
+	spec.Type = "object"
+	if spec.Properties == nil {
+		spec.Properties = map[string]apiextensionsv1.JSONSchemaProps{}
+	}
+	for _, name := range moved {
+		if _, found := spec.Properties[name]; found {
+			return nil, fmt.Errorf("cannot wrap the root property %s in spec: spec already has a property %s", name, name)
+		}
+		spec.Properties[name] = schema.Properties[name]
+		delete(schema.Properties, name)
+		if idx := slices.Index(schema.Required, name); idx != -1 {
+			schema.Required = slices.Delete(schema.Required, idx, idx+1)
+			spec.Required = append(spec.Required, name)
+		}
+	}
+	schema.Properties["spec"] = spec
+	return moved, nil
+}
+
+// conditionsSchema is the schema of a list of metav1.Condition, as generated
+// by controller-gen.
+func conditionsSchema() apiextensionsv1.JSONSchemaProps {
+	return apiextensionsv1.JSONSchemaProps{
+		Type:         "array",
+		XListType:    ptr("map"),
+		XListMapKeys: []string{"type"},
+		Items: &apiextensionsv1.JSONSchemaPropsOrArray{Schema: &apiextensionsv1.JSONSchemaProps{
+			Type:     "object",
+			Required: []string{"lastTransitionTime", "message", "reason", "status", "type"},
+			Properties: map[string]apiextensionsv1.JSONSchemaProps{
+				"type": {
+					Type:      "string",
+					MaxLength: ptr[int64](316),
+					Pattern:   `^([a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*/)?(([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9])$`,
+				},
+				"status": {
+					Type: "string",
+					Enum: []apiextensionsv1.JSON{{Raw: []byte(`"True"`)}, {Raw: []byte(`"False"`)}, {Raw: []byte(`"Unknown"`)}},
+				},
+				"observedGeneration": {
+					Type:    "integer",
+					Format:  "int64",
+					Minimum: ptr[float64](0),
+				},
+				"lastTransitionTime": {
+					Type:   "string",
+					Format: "date-time",
+				},
+				"reason": {
+					Type:      "string",
+					MinLength: ptr[int64](1),
+					MaxLength: ptr[int64](1024),
+					Pattern:   `^[A-Za-z]([A-Za-z0-9_,:]*[A-Za-z0-9_])?$`,
+				},
+				"message": {
+					Type:      "string",
+					MaxLength: ptr[int64](32768),
+				},
+			},
+		}},
+	}
+}
+
+// addConditions adds the conditions of metav1.Condition to the status of the
+// schema, keeping the conditions of the operator CRD when it has any.
+func addConditions(schema *apiextensionsv1.JSONSchemaProps) error {
+	status := schema.Properties["status"]
+	if status.Type != "" && status.Type != "object" {
+		return fmt.Errorf("cannot add status.conditions: status is of type %s", status.Type)
+	}
+	if status.AdditionalProperties != nil {
+		return fmt.Errorf("cannot add status.conditions: status is a map in the operator CRD")
+	}
+	if _, found := status.Properties["conditions"]; found {
+		fmt.Fprintln(os.Stderr, "Warning: the operator CRD already has status.conditions: keeping its schema")
+		return nil
+	}
+
+	status.Type = "object"
+	if status.Properties == nil {
+		status.Properties = map[string]apiextensionsv1.JSONSchemaProps{}
+	}
+	status.Properties["conditions"] = conditionsSchema()
+	schema.Properties["status"] = status
+	return nil
+}
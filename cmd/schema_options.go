@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"github.com/spf13/pflag"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+// SchemaOptions are the flags rewriting the schema of the API generated from
+// the operator CRD.
+type SchemaOptions struct {
+	StatusFields          []string
+	SetValues             []string
+	DepthLimit            int
+	Expose                []string
+	Enums                 []string
+	PropertiesFrom        []string
+	DeprecatedVersions    []string
+	WrapSpec              bool
+	PrinterColumns        []string
+	WithConditions        bool
+	PreserveUnknownFields bool
+	PatchFile             string
+}
+
+func (o *SchemaOptions) addFlags(flags *pflag.FlagSet) {
+	flags.StringArrayVar(&o.StatusFields, "status-field", nil, "A dot-separated path to expose under the status of the generated API, enabling its status subresource. New paths are strings. Can be specified multiple times.")
+	flags.StringArrayVar(&o.SetValues, "set", nil, "Set a value, parsed as YAML, in the generated CRD, e.g. spec.versions[0].schema.openAPIV3Schema.properties.spec.properties.size.maximum=100. Can be specified multiple times.")
+	flags.IntVar(&o.DepthLimit, "schema-depth-limit", 0, "Replace the API schema nested deeper than this depth with x-kubernetes-preserve-unknown-fields. Defaults to no limit.")
+	flags.StringArrayVar(&o.Expose, "expose", nil, "A top-level property of the CRD to expose in the Promise API, e.g. spec, dropping the printer columns and validation rules on the others. Can be specified multiple times. Defaults to all properties.")
+	flags.StringArrayVar(&o.Enums, "enum", nil, "Restrict a property of the generated API to a list of values, as PROPERTY=VALUE,VALUE, e.g. spec.teamId=acid,platform. Can be specified multiple times.")
+	flags.StringArrayVar(&o.PropertiesFrom, "property-from", nil, "Add the properties inferred from the value of a sample resource to the generated API, as JSONPATH=SAMPLE-FILE, e.g. .spec.tls=examples/cache.yaml. Can be specified multiple times.")
+	flags.StringArrayVar(&o.DeprecatedVersions, "deprecate-version", nil, "Mark a version of the generated CRD as deprecated, as NAME or NAME=WARNING. Can be specified multiple times.")
+	flags.BoolVar(&o.WrapSpec, "wrap-spec", false, "Move the properties at the root of the operator schema, other than apiVersion, kind, metadata and status, under spec in the generated API, listing them in the OPERATOR_ROOT_FIELDS env.")
+	flags.StringArrayVar(&o.PrinterColumns, "printer-column", nil, "Add a column to kubectl get for the generated API, as NAME:JSONPATH:TYPE, e.g. Size:.spec.size:string. Can be specified multiple times.")
+	flags.BoolVar(&o.WithConditions, "with-conditions", false, "Add the standard status.conditions to the generated API and enable its status subresource.")
+	flags.BoolVar(&o.PreserveUnknownFields, "preserve-unknown-fields", false, "Keep the fields of the resources missing from the schema of the generated API instead of pruning them.")
+	flags.StringVar(&o.PatchFile, "schema-patch", "", "A JSON merge patch (RFC 7396) to apply to the schema of the stored version of the generated API, e.g. to add patterns or minimums.")
+}
+
+// apply rewrites the schema of the generated API, the only version of crd once
+// updateOperatorCrd has run, adding the statusFieldPaths parsed from
+// --status-field. It returns the root properties moved under spec by
+// --wrap-spec.
+func (o *SchemaOptions) apply(crd *apiextensionsv1.CustomResourceDefinition, statusFieldPaths [][]string) ([]string, error) {
+	if o.DepthLimit > 0 {
+		pruneSchemaDepth(crd.Spec.Versions[0].Schema.OpenAPIV3Schema, o.DepthLimit)
+	}
+
+	var wrapped []string
+	if o.WrapSpec {
+		var err error
+		if wrapped, err = wrapSpec(crd.Spec.Versions[0].Schema.OpenAPIV3Schema); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(o.Expose) > 0 {
+		if err := trimSchemaProperties(crd.Spec.Versions[0].Schema.OpenAPIV3Schema, o.Expose); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := addStatusFields(&crd.Spec.Versions[0], statusFieldPaths); err != nil {
+		return nil, err
+	}
+	if len(o.Expose) > 0 {
+		pruneUnexposedReferences(&crd.Spec.Versions[0])
+	}
+
+	if o.WithConditions {
+		if err := addConditions(crd.Spec.Versions[0].Schema.OpenAPIV3Schema); err != nil {
+			return nil, err
+		}
+		crd.Spec.Versions[0].Subresources = &apiextensionsv1.CustomResourceSubresources{
+			Status: &apiextensionsv1.CustomResourceSubresourceStatus{},
+		}
+	}
+
+	if err := addPrinterColumns(&crd.Spec.Versions[0], o.PrinterColumns); err != nil {
+		return nil, err
+	}
+
+	if err := applyPropertiesFrom(crd.Spec.Versions[0].Schema.OpenAPIV3Schema, o.PropertiesFrom); err != nil {
+		return nil, err
+	}
+
+	if err := deprecateVersions(crd, o.DeprecatedVersions); err != nil {
+		return nil, err
+	}
+
+	if err := applyEnums(crd.Spec.Versions[0].Schema.OpenAPIV3Schema, o.Enums); err != nil {
+		return nil, err
+	}
+
+	if o.PreserveUnknownFields {
+		crd.Spec.Versions[0].Schema.OpenAPIV3Schema.XPreserveUnknownFields = ptr(true)
+	}
+
+	if err := applySchemaPatch(crd, o.PatchFile); err != nil {
+		return nil, err
+	}
+
+	if err := applySetValues(crd, o.SetValues); err != nil {
+		return nil, err
+	}
+	return wrapped, nil
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+// applySchemaPatch applies the JSON merge patch (RFC 7396) of --schema-patch to
+// the schema of the stored version of the CRD: the fields of the patch are
+// added to the schema, or replace its fields, and a null removes the field,
+// e.g. a property or one of its constraints. The patched schema must still be
+// structural.
+func applySchemaPatch(crd *apiextensionsv1.CustomResourceDefinition, path string) error {
+	if path == "" {
+		return nil
+	}
+
+	patch, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read --schema-patch: %w", err)
+	}
+	var parsed any
+	if err := json.Unmarshal(patch, &parsed); err != nil {
+		return fmt.Errorf("invalid --schema-patch %s: not valid JSON: %s", path, err)
+	}
+	if _, ok := parsed.(map[string]any); !ok {
+		return fmt.Errorf("invalid --schema-patch %s: the merge patch must be a JSON object", path)
+	}
+
+	crdVersion := &crd.Spec.Versions[findStoredVersionIdx(crd)]
+	original, err := json.Marshal(crdVersion.Schema.OpenAPIV3Schema)
+	if err != nil {
+		return err
+	}
+	patched, err := jsonpatch.MergePatch(original, patch)
+	if err != nil {
+		return fmt.Errorf("failed to apply --schema-patch %s: %w", path, err)
+	}
+
+	var schema apiextensionsv1.JSONSchemaProps
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&schema); err != nil {
+		return fmt.Errorf("invalid --schema-patch %s: the patched schema is not an OpenAPI v3 schema: %s", path, err)
+	}
+	crdVersion.Schema.OpenAPIV3Schema = &schema
+
+	if err := validateStructuralSchemas(crd); err != nil {
+		return fmt.Errorf("invalid --schema-patch %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,408 @@
+package cmd_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/syntasso/kratix-cli/cmd"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+var _ = Describe("Schema", func() {
+	Describe("PruneSchemaDepth", func() {
+		var schema *apiextensionsv1.JSONSchemaProps
+
+		BeforeEach(func() {
+			schema = &apiextensionsv1.JSONSchemaProps{
+				Type: "object",
+				Properties: map[string]apiextensionsv1.JSONSchemaProps{
+					"spec": {
+						Type: "object",
+						Properties: map[string]apiextensionsv1.JSONSchemaProps{
+							"size": {Type: "integer"},
+							"storage": {
+								Type:        "object",
+								Description: "The storage",
+								Required:    []string{"class"},
+								Properties: map[string]apiextensionsv1.JSONSchemaProps{
+									"class": {Type: "string"},
+								},
+							},
+							"volumes": {
+								Type: "array",
+								Items: &apiextensionsv1.JSONSchemaPropsOrArray{Schema: &apiextensionsv1.JSONSchemaProps{
+									Type: "object",
+									Properties: map[string]apiextensionsv1.JSONSchemaProps{
+										"name": {Type: "string"},
+									},
+								}},
+							},
+						},
+					},
+				},
+			}
+		})
+
+		It("replaces the subtrees deeper than the limit", func() {
+			PruneSchemaDepth(schema, 2)
+
+			spec := schema.Properties["spec"]
+			Expect(spec.Properties["size"]).To(Equal(apiextensionsv1.JSONSchemaProps{Type: "integer"}))
+			Expect(spec.Properties["storage"]).To(Equal(apiextensionsv1.JSONSchemaProps{
+				Type:                   "object",
+				Description:            "The storage",
+				XPreserveUnknownFields: ptr(true),
+			}))
+			Expect(spec.Properties["volumes"]).To(Equal(apiextensionsv1.JSONSchemaProps{
+				Type: "array",
+				Items: &apiextensionsv1.JSONSchemaPropsOrArray{Schema: &apiextensionsv1.JSONSchemaProps{
+					XPreserveUnknownFields: ptr(true),
+				}},
+			}))
+		})
+
+		It("keeps the schema when it is within the limit", func() {
+			original := schema.DeepCopy()
+			PruneSchemaDepth(schema, 4)
+			Expect(schema).To(Equal(original))
+		})
+	})
+
+	Describe("EnsureMetadataSchema", func() {
+		var crd *apiextensionsv1.CustomResourceDefinition
+
+		BeforeEach(func() {
+			crd = &apiextensionsv1.CustomResourceDefinition{
+				Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+					Versions: []apiextensionsv1.CustomResourceDefinitionVersion{{
+						Name: "v1",
+						Schema: &apiextensionsv1.CustomResourceValidation{OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+							Type: "object",
+							Properties: map[string]apiextensionsv1.JSONSchemaProps{
+								"spec": {Type: "object"},
+							},
+						}},
+					}},
+				},
+			}
+		})
+
+		It("adds metadata as an object when the CRD does not define it", func() {
+			schema := crd.Spec.Versions[0].Schema.OpenAPIV3Schema
+			EnsureMetadataSchema(schema)
+
+			Expect(schema.Properties).To(HaveKeyWithValue("metadata", apiextensionsv1.JSONSchemaProps{Type: "object"}))
+			Expect(ValidateStructuralSchemas(crd)).To(Succeed())
+		})
+
+		It("keeps the metadata.name validation of a metadata property without a type", func() {
+			schema := crd.Spec.Versions[0].Schema.OpenAPIV3Schema
+			name := apiextensionsv1.JSONSchemaProps{Type: "string", MaxLength: ptr(int64(63))}
+			schema.Properties["metadata"] = apiextensionsv1.JSONSchemaProps{
+				Properties: map[string]apiextensionsv1.JSONSchemaProps{"name": name},
+			}
+			EnsureMetadataSchema(schema)
+
+			Expect(schema.Properties).To(HaveKeyWithValue("metadata", apiextensionsv1.JSONSchemaProps{
+				Type:       "object",
+				Properties: map[string]apiextensionsv1.JSONSchemaProps{"name": name},
+			}))
+			Expect(ValidateStructuralSchemas(crd)).To(Succeed())
+		})
+	})
+
+	Describe("ToJSONSchema", func() {
+		It("converts the kubernetes extensions", func() {
+			schema := &apiextensionsv1.JSONSchemaProps{
+				Type: "object",
+				Properties: map[string]apiextensionsv1.JSONSchemaProps{
+					"spec": {
+						Type:                   "object",
+						XPreserveUnknownFields: ptr(true),
+						Properties: map[string]apiextensionsv1.JSONSchemaProps{
+							"port":  {XIntOrString: true},
+							"owner": {Type: "string", Nullable: true},
+							"tags": {
+								Type:         "array",
+								XListType:    ptr("set"),
+								Items:        &apiextensionsv1.JSONSchemaPropsOrArray{Schema: &apiextensionsv1.JSONSchemaProps{Type: "string", Nullable: true}},
+								XListMapKeys: []string{"name"},
+							},
+						},
+					},
+				},
+			}
+
+			jsonSchema, err := ToJSONSchema(schema, "Database")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(jsonSchema).To(Equal(map[string]any{
+				"$schema": "http://json-schema.org/draft-04/schema#",
+				"title":   "Database",
+				"type":    "object",
+				"properties": map[string]any{
+					"spec": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"port":  map[string]any{"anyOf": []any{map[string]any{"type": "integer"}, map[string]any{"type": "string"}}},
+							"owner": map[string]any{"type": []any{"string", "null"}},
+							"tags": map[string]any{
+								"type":  "array",
+								"items": map[string]any{"type": []any{"string", "null"}},
+							},
+						},
+					},
+				},
+			}))
+		})
+	})
+
+	Describe("FormSchema", func() {
+		It("flattens the nested objects of the spec", func() {
+			schema := &apiextensionsv1.JSONSchemaProps{
+				Type: "object",
+				Properties: map[string]apiextensionsv1.JSONSchemaProps{
+					"spec": {
+						Type:     "object",
+						Required: []string{"storage", "engine"},
+						Properties: map[string]apiextensionsv1.JSONSchemaProps{
+							"engine": {
+								Type:        "string",
+								Description: "The engine",
+								Enum:        []apiextensionsv1.JSON{{Raw: []byte(`"postgres"`)}},
+								Default:     &apiextensionsv1.JSON{Raw: []byte(`"postgres"`)},
+							},
+							"storage": {
+								Type:     "object",
+								Required: []string{"size"},
+								Properties: map[string]apiextensionsv1.JSONSchemaProps{
+									"size":  {Type: "integer"},
+									"class": {Type: "string"},
+								},
+							},
+							"backup": {
+								Type:     "object",
+								Required: []string{"schedule"},
+								Properties: map[string]apiextensionsv1.JSONSchemaProps{
+									"schedule": {Type: "string"},
+								},
+							},
+							"labels": {Type: "object", AdditionalProperties: &apiextensionsv1.JSONSchemaPropsOrBool{Allows: true}},
+							"users":  {Type: "array", Items: &apiextensionsv1.JSONSchemaPropsOrArray{Schema: &apiextensionsv1.JSONSchemaProps{Type: "string"}}},
+						},
+					},
+				},
+			}
+
+			Expect(FormSchema(schema)).To(Equal([]FormField{
+				{Name: "backup.schedule", Type: "string"},
+				{Name: "engine", Type: "string", Description: "The engine", Required: true,
+					Enum:    []apiextensionsv1.JSON{{Raw: []byte(`"postgres"`)}},
+					Default: &apiextensionsv1.JSON{Raw: []byte(`"postgres"`)}},
+				{Name: "labels", Type: "object"},
+				{Name: "storage.class", Type: "string"},
+				{Name: "storage.size", Type: "integer", Required: true},
+				{Name: "users", Type: "array"},
+			}))
+		})
+	})
+
+	Describe("ApplyEnums", func() {
+		var schema *apiextensionsv1.JSONSchemaProps
+
+		BeforeEach(func() {
+			schema = &apiextensionsv1.JSONSchemaProps{
+				Type: "object",
+				Properties: map[string]apiextensionsv1.JSONSchemaProps{
+					"spec": {
+						Type: "object",
+						Properties: map[string]apiextensionsv1.JSONSchemaProps{
+							"size":    {Type: "integer"},
+							"backups": {Type: "boolean"},
+							"storage": {
+								Type: "object",
+								Properties: map[string]apiextensionsv1.JSONSchemaProps{
+									"class": {Type: "string"},
+								},
+							},
+						},
+					},
+				},
+			}
+		})
+
+		It("sets the enum of the properties, encoded as their type", func() {
+			Expect(ApplyEnums(schema, []string{"spec.storage.class=fast,slow", "spec.size=1,3", "spec.backups=true"})).To(Succeed())
+
+			spec := schema.Properties["spec"]
+			Expect(spec.Properties["storage"].Properties["class"].Enum).To(Equal([]apiextensionsv1.JSON{{Raw: []byte(`"fast"`)}, {Raw: []byte(`"slow"`)}}))
+			Expect(spec.Properties["size"].Enum).To(Equal([]apiextensionsv1.JSON{{Raw: []byte(`1`)}, {Raw: []byte(`3`)}}))
+			Expect(spec.Properties["backups"].Enum).To(Equal([]apiextensionsv1.JSON{{Raw: []byte(`true`)}}))
+		})
+
+		It("errors when the property does not exist", func() {
+			Expect(ApplyEnums(schema, []string{"spec.storage.size=1"})).To(MatchError(`invalid --enum "spec.storage.size=1": size is not a property of the CRD`))
+		})
+
+		It("errors when a value does not match the type of the property", func() {
+			Expect(ApplyEnums(schema, []string{"spec.size=1,large"})).To(MatchError(`invalid --enum "spec.size=1,large": value "large" is not of type integer`))
+		})
+
+		It("errors on a property of type object", func() {
+			Expect(ApplyEnums(schema, []string{"spec.storage=fast"})).To(MatchError(ContainSubstring(`cannot set an enum on a property of type "object"`)))
+		})
+
+		It("errors without values", func() {
+			Expect(ApplyEnums(schema, []string{"spec.size"})).To(MatchError(`invalid --enum "spec.size": expected PROPERTY=VALUE,VALUE`))
+		})
+	})
+
+	Describe("WrapSpec", func() {
+		var schema *apiextensionsv1.JSONSchemaProps
+
+		BeforeEach(func() {
+			schema = &apiextensionsv1.JSONSchemaProps{
+				Type:     "object",
+				Required: []string{"size", "metadata"},
+				Properties: map[string]apiextensionsv1.JSONSchemaProps{
+					"apiVersion": {Type: "string"},
+					"kind":       {Type: "string"},
+					"metadata":   {Type: "object"},
+					"status":     {Type: "object"},
+					"size":       {Type: "string"},
+					"replicas":   {Type: "integer"},
+				},
+			}
+		})
+
+		It("moves the root properties under spec, with their required flags", func() {
+			Expect(WrapSpec(schema)).To(Equal([]string{"replicas", "size"}))
+			Expect(schema.Properties).To(HaveLen(5))
+			Expect(schema.Required).To(Equal([]string{"metadata"}))
+			Expect(schema.Properties["spec"]).To(Equal(apiextensionsv1.JSONSchemaProps{
+				Type:     "object",
+				Required: []string{"size"},
+				Properties: map[string]apiextensionsv1.JSONSchemaProps{
+					"size":     {Type: "string"},
+					"replicas": {Type: "integer"},
+				},
+			}))
+		})
+
+		It("adds to the properties of an existing spec", func() {
+			schema.Properties["spec"] = apiextensionsv1.JSONSchemaProps{
+				Type:       "object",
+				Properties: map[string]apiextensionsv1.JSONSchemaProps{"tier": {Type: "string"}},
+			}
+			Expect(WrapSpec(schema)).To(Equal([]string{"replicas", "size"}))
+			Expect(schema.Properties["spec"].Properties).To(HaveKey("tier"))
+			Expect(schema.Properties["spec"].Properties).To(HaveKey("size"))
+		})
+
+		It("leaves a schema with its fields under spec unchanged", func() {
+			wrapped := &apiextensionsv1.JSONSchemaProps{
+				Type: "object",
+				Properties: map[string]apiextensionsv1.JSONSchemaProps{
+					"spec": {Type: "object", Properties: map[string]apiextensionsv1.JSONSchemaProps{"size": {Type: "string"}}},
+				},
+			}
+			original := wrapped.DeepCopy()
+			Expect(WrapSpec(wrapped)).To(BeEmpty())
+			Expect(wrapped).To(Equal(original))
+		})
+
+		It("errors when spec already has a moved property", func() {
+			schema.Properties["spec"] = apiextensionsv1.JSONSchemaProps{
+				Type:       "object",
+				Properties: map[string]apiextensionsv1.JSONSchemaProps{"size": {Type: "integer"}},
+			}
+			_, err := WrapSpec(schema)
+			Expect(err).To(MatchError("cannot wrap the root property size in spec: spec already has a property size"))
+		})
+	})
+
+	Describe("PruneUnexposedReferences", func() {
+		It("drops the printer columns and root rules on removed properties", func() {
+			crdVersion := &apiextensionsv1.CustomResourceDefinitionVersion{
+				AdditionalPrinterColumns: []apiextensionsv1.CustomResourceColumnDefinition{
+					{Name: "Size", JSONPath: ".spec.storage.size"},
+					{Name: "Class", JSONPath: ".spec.storage.class"},
+					{Name: "First-Volume", JSONPath: ".spec.volumes[0].name"},
+					{Name: "Label", JSONPath: ".spec.labels.team"},
+					{Name: "Phase", JSONPath: ".status.phase"},
+					{Name: "Age", JSONPath: ".metadata.creationTimestamp"},
+				},
+				Schema: &apiextensionsv1.CustomResourceValidation{OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+					Type: "object",
+					Properties: map[string]apiextensionsv1.JSONSchemaProps{
+						"metadata": {Type: "object"},
+						"spec": {
+							Type: "object",
+							Properties: map[string]apiextensionsv1.JSONSchemaProps{
+								"storage": {Type: "object", Properties: map[string]apiextensionsv1.JSONSchemaProps{"size": {Type: "string"}}},
+								"volumes": {Type: "array", Items: &apiextensionsv1.JSONSchemaPropsOrArray{Schema: &apiextensionsv1.JSONSchemaProps{
+									Type:       "object",
+									Properties: map[string]apiextensionsv1.JSONSchemaProps{"name": {Type: "string"}},
+								}}},
+								"labels": {Type: "object", AdditionalProperties: &apiextensionsv1.JSONSchemaPropsOrBool{Allows: true}},
+							},
+						},
+					},
+					XValidations: apiextensionsv1.ValidationRules{
+						{Rule: "self.spec.storage.size != ''"},
+						{Rule: "!has(self.status) || self.status.phase != 'Failed'"},
+					},
+				}},
+			}
+
+			PruneUnexposedReferences(crdVersion)
+
+			var columns []string
+			for _, column := range crdVersion.AdditionalPrinterColumns {
+				columns = append(columns, column.Name)
+			}
+			Expect(columns).To(Equal([]string{"Size", "First-Volume", "Label", "Age"}))
+			Expect(crdVersion.Schema.OpenAPIV3Schema.XValidations).To(Equal(apiextensionsv1.ValidationRules{
+				{Rule: "self.spec.storage.size != ''"},
+			}))
+		})
+
+		It("follows the filters of the printer columns", func() {
+			condition := apiextensionsv1.JSONSchemaProps{
+				Type: "object",
+				Properties: map[string]apiextensionsv1.JSONSchemaProps{
+					"type":   {Type: "string"},
+					"status": {Type: "string"},
+				},
+			}
+			crdVersion := &apiextensionsv1.CustomResourceDefinitionVersion{
+				AdditionalPrinterColumns: []apiextensionsv1.CustomResourceColumnDefinition{
+					{Name: "Ready", JSONPath: `.status.conditions[?(@.type=="Ready")].status`},
+					{Name: "Reason", JSONPath: `.status.conditions[?(@.type=="Ready")].reason`},
+					{Name: "Types", JSONPath: ".status.conditions[*].type"},
+				},
+				Schema: &apiextensionsv1.CustomResourceValidation{OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+					Type: "object",
+					Properties: map[string]apiextensionsv1.JSONSchemaProps{
+						"status": {
+							Type: "object",
+							Properties: map[string]apiextensionsv1.JSONSchemaProps{
+								"conditions": {Type: "array", Items: &apiextensionsv1.JSONSchemaPropsOrArray{Schema: &condition}},
+							},
+						},
+					},
+				}},
+			}
+
+			PruneUnexposedReferences(crdVersion)
+
+			var columns []string
+			for _, column := range crdVersion.AdditionalPrinterColumns {
+				columns = append(columns, column.Name)
+			}
+			Expect(columns).To(Equal([]string{"Ready", "Types"}))
+		})
+	})
+})
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+
+	"github.com/syntasso/kratix/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// metricsContainerPorts are the container ports conventionally serving
+// metrics, used when no container port is named metrics.
+var metricsContainerPorts = []int32{8080, 9090}
+
+// prometheusDuration matches the durations accepted by the Prometheus
+// Operator, e.g. 30s or 1m30s.
+var prometheusDuration = regexp.MustCompile(`^(0|(([0-9]+)y)?(([0-9]+)w)?(([0-9]+)d)?(([0-9]+)h)?(([0-9]+)m)?(([0-9]+)s)?(([0-9]+)ms)?)$`)
+
+// operatorServiceMonitor generates a Prometheus Operator ServiceMonitor
+// scraping the metrics port of the operator Deployment, through the Service of
+// the dependencies exposing it. The port is the one named port, or is detected
+// when port is empty. It warns and returns nil when there is no metrics port or
+// no Service exposing it, rather than generating a monitor scraping nothing.
+func operatorServiceMonitor(dependencies []v1alpha1.Dependency, port, interval string) (*v1alpha1.Dependency, error) {
+	if interval == "" || !prometheusDuration.MatchString(interval) {
+		return nil, fmt.Errorf("invalid --service-monitor-interval %q: must be a duration such as 30s or 1m", interval)
+	}
+
+	operatorDeployment, err := findOperatorDeployment(dependencies)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate the ServiceMonitor: %w", err)
+	}
+	var deployment appsv1.Deployment
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(operatorDeployment.Object, &deployment); err != nil {
+		return nil, fmt.Errorf("failed to generate the ServiceMonitor: failed to read Deployment %s: %w", operatorDeployment.GetName(), err)
+	}
+
+	metricsPort, found := findMetricsPort(deployment, port)
+	if !found {
+		fmt.Fprintf(os.Stderr, "Warning: no metrics port found on Deployment %s: set --service-monitor-port to add a ServiceMonitor\n", deployment.Name)
+		return nil, nil
+	}
+
+	service, servicePort, found, err := findMetricsService(dependencies, deployment, metricsPort)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate the ServiceMonitor: %w", err)
+	}
+	if !found {
+		fmt.Fprintf(os.Stderr, "Warning: no Service exposes the metrics port %s of Deployment %s: add one to the operator manifests to add a ServiceMonitor\n",
+			containerPortName(metricsPort), deployment.Name)
+		return nil, nil
+	}
+	if len(service.Labels) == 0 {
+		fmt.Fprintf(os.Stderr, "Warning: Service %s has no labels for a ServiceMonitor to select it: add labels to it to add a ServiceMonitor\n", service.Name)
+		return nil, nil
+	}
+
+	endpoint := map[string]any{"interval": interval}
+	if servicePort.Name != "" {
+		endpoint["port"] = servicePort.Name
+	} else {
+		endpoint["targetPort"] = int64(servicePort.Port)
+	}
+
+	matchLabels := map[string]any{}
+	for key, value := range service.Labels {
+		matchLabels[key] = value
+	}
+
+	return &v1alpha1.Dependency{Unstructured: unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "monitoring.coreos.com/v1",
+		"kind":       "ServiceMonitor",
+		"metadata": map[string]any{
+			"name":      fmt.Sprintf("%s-metrics", deployment.Name),
+			"namespace": service.Namespace,
+		},
+		"spec": map[string]any{
+			"selector": map[string]any{
+				"matchLabels": matchLabels,
+			},
+			"namespaceSelector": map[string]any{
+				"matchNames": []any{service.Namespace},
+			},
+			"endpoints": []any{endpoint},
+		},
+	}}}, nil
+}
+
+// findMetricsPort finds the container port matching port, by name or number.
+// Without a port, it finds the first one named metrics, or else the first one
+// of metricsContainerPorts. A port not declared by the containers is matched
+// against the Services as is.
+func findMetricsPort(deployment appsv1.Deployment, port string) (corev1.ContainerPort, bool) {
+	var ports []corev1.ContainerPort
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		ports = append(ports, container.Ports...)
+	}
+
+	if port != "" {
+		number, err := strconv.Atoi(port)
+		for _, containerPort := range ports {
+			if containerPort.Name == port || (err == nil && containerPort.ContainerPort == int32(number)) {
+				return containerPort, true
+			}
+		}
+		if err == nil {
+			return corev1.ContainerPort{ContainerPort: int32(number)}, true
+		}
+		return corev1.ContainerPort{Name: port}, true
+	}
+
+	for _, containerPort := range ports {
+		if containerPort.Name == "metrics" {
+			return containerPort, true
+		}
+	}
+	for _, containerPort := range ports {
+		for _, number := range metricsContainerPorts {
+			if containerPort.ContainerPort == number {
+				return containerPort, true
+			}
+		}
+	}
+	return corev1.ContainerPort{}, false
+}
+
+// findMetricsService finds the Service in the namespace of the Deployment
+// selecting its pods and exposing the metrics port.
+func findMetricsService(dependencies []v1alpha1.Dependency, deployment appsv1.Deployment, metricsPort corev1.ContainerPort) (corev1.Service, corev1.ServicePort, bool, error) {
+	podLabels := deployment.Spec.Template.Labels
+	for _, dep := range dependencies {
+		if dep.GetKind() != "Service" || dep.GetNamespace() != deployment.Namespace {
+			continue
+		}
+		var service corev1.Service
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(dep.Object, &service); err != nil {
+			return corev1.Service{}, corev1.ServicePort{}, false, fmt.Errorf("failed to read Service %s: %w", dep.GetName(), err)
+		}
+		if !selectsLabels(service.Spec.Selector, podLabels) {
+			continue
+		}
+		for _, servicePort := range service.Spec.Ports {
+			if servicePortTargets(servicePort, metricsPort) {
+				return service, servicePort, true, nil
+			}
+		}
+	}
+	return corev1.Service{}, corev1.ServicePort{}, false, nil
+}
+
+func selectsLabels(selector, labels map[string]string) bool {
+	if len(selector) == 0 {
+		return false
+	}
+	for key, value := range selector {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// servicePortTargets reports whether the Service port sends traffic to the
+// container port. A Service port without targetPort targets its own number.
+func servicePortTargets(servicePort corev1.ServicePort, containerPort corev1.ContainerPort) bool {
+	switch {
+	case servicePort.TargetPort.Type == intstr.String && servicePort.TargetPort.StrVal != "":
+		return servicePort.TargetPort.StrVal == containerPort.Name
+	case servicePort.TargetPort.IntVal != 0:
+		return servicePort.TargetPort.IntVal == containerPort.ContainerPort
+	default:
+		return servicePort.Port == containerPort.ContainerPort
+	}
+}
+
+func containerPortName(port corev1.ContainerPort) string {
+	if port.Name != "" {
+		return port.Name
+	}
+	return strconv.Itoa(int(port.ContainerPort))
+}
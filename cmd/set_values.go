@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// pathSegment is either a field name or, when isIndex is set, a list index.
+type pathSegment struct {
+	field   string
+	index   int
+	isIndex bool
+}
+
+// applySetValues applies PATH=VALUE overrides to the CRD, where PATH is
+// relative to the CRD root, e.g. spec.versions[0].schema.openAPIV3Schema.
+// Missing fields are created, but list indexes must exist. VALUE is parsed as
+// YAML, so numbers and booleans keep their type.
+func applySetValues(crd *apiextensionsv1.CustomResourceDefinition, setValues []string) error {
+	if len(setValues) == 0 {
+		return nil
+	}
+
+	crdBytes, err := json.Marshal(crd)
+	if err != nil {
+		return err
+	}
+	var crdMap map[string]any
+	if err := json.Unmarshal(crdBytes, &crdMap); err != nil {
+		return err
+	}
+
+	for _, setValue := range setValues {
+		path, rawValue, found := strings.Cut(setValue, "=")
+		if !found {
+			return newCLIError(errCodeInvalidSetValue, map[string]any{"set": setValue}, "invalid --set %q: expected PATH=VALUE", setValue)
+		}
+
+		segments, err := parseSetPath(path)
+		if err != nil {
+			return newCLIError(errCodeInvalidSetValue, map[string]any{"set": setValue}, "invalid --set %q: %s", setValue, err)
+		}
+
+		var value any
+		if err := yaml.Unmarshal([]byte(rawValue), &value); err != nil {
+			return newCLIError(errCodeInvalidSetValue, map[string]any{"set": setValue}, "invalid --set %q: failed to parse value: %s", setValue, err)
+		}
+
+		if err := setPathValue(crdMap, segments, value); err != nil {
+			return newCLIError(errCodeInvalidSetValue, map[string]any{"set": setValue}, "invalid --set %q: %s", setValue, err)
+		}
+
+		// decoding after each value attributes unknown fields, usually typos
+		// in the path, to the --set that introduced them
+		if err := decodeCRDStrict(crdMap, crd); err != nil {
+			return newCLIError(errCodeInvalidSetValue, map[string]any{"set": setValue}, "invalid --set %q: %s", setValue, err)
+		}
+	}
+	return nil
+}
+
+func decodeCRDStrict(crdMap map[string]any, crd *apiextensionsv1.CustomResourceDefinition) error {
+	crdBytes, err := json.Marshal(crdMap)
+	if err != nil {
+		return err
+	}
+
+	updatedCRD := apiextensionsv1.CustomResourceDefinition{}
+	decoder := json.NewDecoder(bytes.NewReader(crdBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&updatedCRD); err != nil {
+		return err
+	}
+	*crd = updatedCRD
+	return nil
+}
+
+// parseSetPath parses a dotted path with optional list indexes, e.g.
+// spec.versions[0].name. Dots in field names can be escaped with a backslash.
+func parseSetPath(path string) ([]pathSegment, error) {
+	var segments []pathSegment
+	var field strings.Builder
+	afterIndex := false
+
+	endField := func() error {
+		if field.Len() == 0 {
+			if afterIndex {
+				return nil
+			}
+			return fmt.Errorf("empty field name in path %q", path)
+		}
+		segments = append(segments, pathSegment{field: field.String()})
+		field.Reset()
+		return nil
+	}
+
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; c {
+		case '\\':
+			if i+1 < len(path) && path[i+1] == '.' {
+				field.WriteByte('.')
+				i++
+				continue
+			}
+			field.WriteByte(c)
+		case '.':
+			if err := endField(); err != nil {
+				return nil, err
+			}
+			afterIndex = false
+		case '[':
+			if err := endField(); err != nil {
+				return nil, err
+			}
+			end := strings.IndexByte(path[i:], ']')
+			if end == -1 {
+				return nil, fmt.Errorf("unclosed '[' in path %q", path)
+			}
+			index, err := strconv.Atoi(path[i+1 : i+end])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("invalid index %q in path %q", path[i+1:i+end], path)
+			}
+			segments = append(segments, pathSegment{index: index, isIndex: true})
+			i += end
+			afterIndex = true
+		default:
+			if afterIndex {
+				return nil, fmt.Errorf("expected '.' or '[' after index in path %q", path)
+			}
+			field.WriteByte(c)
+		}
+	}
+
+	if field.Len() > 0 || !afterIndex {
+		if err := endField(); err != nil {
+			return nil, err
+		}
+	}
+	return segments, nil
+}
+
+func setPathValue(obj map[string]any, segments []pathSegment, value any) error {
+	var current any = obj
+	for i, segment := range segments {
+		last := i == len(segments)-1
+		if segment.isIndex {
+			list, ok := current.([]any)
+			if !ok {
+				return fmt.Errorf("cannot index into a non-list at %s", formatSetPath(segments[:i]))
+			}
+			if segment.index >= len(list) {
+				return fmt.Errorf("index %d out of range at %s", segment.index, formatSetPath(segments[:i]))
+			}
+			if last {
+				list[segment.index] = value
+				return nil
+			}
+			if list[segment.index] == nil {
+				list[segment.index] = map[string]any{}
+			}
+			current = list[segment.index]
+			continue
+		}
+
+		fields, ok := current.(map[string]any)
+		if !ok {
+			return fmt.Errorf("cannot set field %q on a non-object at %s", segment.field, formatSetPath(segments[:i]))
+		}
+		if last {
+			fields[segment.field] = value
+			return nil
+		}
+		if fields[segment.field] == nil {
+			if segments[i+1].isIndex {
+				return fmt.Errorf("index %d out of range at %s", segments[i+1].index, formatSetPath(segments[:i+1]))
+			}
+			fields[segment.field] = map[string]any{}
+		}
+		current = fields[segment.field]
+	}
+	return nil
+}
+
+func formatSetPath(segments []pathSegment) string {
+	if len(segments) == 0 {
+		return "the root"
+	}
+	var path strings.Builder
+	for i, segment := range segments {
+		if segment.isIndex {
+			fmt.Fprintf(&path, "[%d]", segment.index)
+			continue
+		}
+		if i > 0 {
+			path.WriteByte('.')
+		}
+		path.WriteString(strings.ReplaceAll(segment.field, ".", `\.`))
+	}
+	return path.String()
+}
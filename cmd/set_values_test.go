@@ -0,0 +1,49 @@
+package cmd_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/syntasso/kratix-cli/cmd"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+var _ = Describe("SetValues", func() {
+	Describe("ApplySetValues", func() {
+		var crd *apiextensionsv1.CustomResourceDefinition
+
+		BeforeEach(func() {
+			crd = crdWithProperties([]string{"size"})
+		})
+
+		It("sets typed values at nested paths", func() {
+			err := ApplySetValues(crd, []string{
+				"spec.versions[0].schema.openAPIV3Schema.properties.spec.properties.size.properties.size.maxLength=10",
+				"spec.versions[0].served=true",
+				"spec.versions[0].schema.openAPIV3Schema.properties.spec.required=[size]",
+				`metadata.annotations.example\.com/owner=platform`,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			version := crd.Spec.Versions[0]
+			spec := version.Schema.OpenAPIV3Schema.Properties["spec"]
+			Expect(*spec.Properties["size"].Properties["size"].MaxLength).To(Equal(int64(10)))
+			Expect(spec.Required).To(Equal([]string{"size"}))
+			Expect(version.Served).To(BeTrue())
+			Expect(crd.Annotations).To(Equal(map[string]string{"example.com/owner": "platform"}))
+		})
+
+		DescribeTable("invalid values",
+			func(setValue, expectedErr string) {
+				Expect(ApplySetValues(crd, []string{setValue})).To(MatchError(ContainSubstring(expectedErr)))
+			},
+			Entry("missing value", "spec.group", "expected PATH=VALUE"),
+			Entry("empty field", "spec..group=foo", `empty field name in path "spec..group"`),
+			Entry("unclosed index", "spec.versions[0=foo", "unclosed '['"),
+			Entry("invalid index", "spec.versions[a].name=foo", `invalid index "a"`),
+			Entry("out of range index", "spec.versions[1].name=foo", "index 1 out of range at spec.versions"),
+			Entry("index on an object", "spec[0]=foo", "cannot index into a non-list at spec"),
+			Entry("field on a scalar", "spec.group.name=foo", `cannot set field "name" on a non-object at spec.group`),
+			Entry("unknown field", "spec.grup=foo", `invalid --set "spec.grup=foo": json: unknown field "grup"`),
+		)
+	})
+})
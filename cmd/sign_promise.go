@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	digestsFileName   = "promise.sha256"
+	signatureFileName = digestsFileName + ".sig"
+)
+
+// signPromiseFiles writes the SHA-256 digests of the generated files to
+// promise.sha256, in the format of sha256sum, and signs it with cosign using
+// key, writing the detached signature to promise.sha256.sig.
+func signPromiseFiles(dir string, filesToWrite map[string]any, key string) error {
+	if _, err := exec.LookPath("cosign"); err != nil {
+		return fmt.Errorf("--sign-with requires the cosign CLI in PATH")
+	}
+
+	var digests strings.Builder
+	for _, path := range promiseFilePaths("", filesToWrite) {
+		content, err := os.ReadFile(filepath.Join(dir, path))
+		if err != nil {
+			return fmt.Errorf("failed to sign the Promise: %s", err)
+		}
+		fmt.Fprintf(&digests, "%x  %s\n", sha256.Sum256(content), filepath.ToSlash(path))
+	}
+	if err := os.WriteFile(filepath.Join(dir, digestsFileName), []byte(digests.String()), filePerm); err != nil {
+		return err
+	}
+
+	sign := exec.Command("cosign", "sign-blob", "--yes", "--key", key, "--output-signature", signatureFileName, digestsFileName)
+	sign.Dir = dir
+	sign.Stdout = os.Stdout
+	sign.Stderr = os.Stderr
+	if err := sign.Run(); err != nil {
+		return fmt.Errorf("failed to sign the Promise with %s: %w", key, err)
+	}
+	fmt.Printf("Signed the digests of the Promise files in %s: %s\n", digestsFileName, signatureFileName)
+	return nil
+}
+
+// promiseFilePaths lists the paths of the files to write, relative to the
+// output directory, in lexical order.
+func promiseFilePaths(prefix string, filesToWrite map[string]any) []string {
+	var paths []string
+	for key, value := range filesToWrite {
+		path := filepath.Join(prefix, key)
+		if files, ok := value.(map[string]any); ok {
+			paths = append(paths, promiseFilePaths(path, files)...)
+			continue
+		}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
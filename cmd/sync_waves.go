@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/syntasso/kratix/api/v1alpha1"
+	yamlsig "sigs.k8s.io/yaml"
+)
+
+const syncWaveAnnotation = "argocd.argoproj.io/sync-wave"
+
+// defaultSyncWaves applies the Namespaces and CRDs first, then the RBAC, the
+// configuration and services, the workloads and, once the workloads serving
+// them are running, the webhooks. The other kinds, such as the custom
+// resources of the CRDs, get the last wave of the mapping.
+var defaultSyncWaves = map[string]int{
+	"Namespace":                      0,
+	"CustomResourceDefinition":       0,
+	"ServiceAccount":                 1,
+	"Role":                           1,
+	"ClusterRole":                    1,
+	"RoleBinding":                    1,
+	"ClusterRoleBinding":             1,
+	"ConfigMap":                      2,
+	"Secret":                         2,
+	"Service":                        2,
+	"PersistentVolumeClaim":          2,
+	"Deployment":                     3,
+	"StatefulSet":                    3,
+	"DaemonSet":                      3,
+	"Job":                            3,
+	"CronJob":                        3,
+	"ValidatingWebhookConfiguration": 4,
+	"MutatingWebhookConfiguration":   4,
+}
+
+// loadSyncWaves reads the kind to sync wave mapping of --sync-waves over the
+// default one.
+func loadSyncWaves(path string) (map[string]int, error) {
+	waves := map[string]int{}
+	for kind, wave := range defaultSyncWaves {
+		waves[kind] = wave
+	}
+	if path == "" {
+		return waves, nil
+	}
+
+	wavesBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read --sync-waves: %w", err)
+	}
+	var overrides map[string]int
+	if err := yamlsig.UnmarshalStrict(wavesBytes, &overrides); err != nil {
+		return nil, fmt.Errorf("failed to parse --sync-waves %s: expected a mapping of kinds to integer waves: %s", path, err)
+	}
+	for kind, wave := range overrides {
+		waves[kind] = wave
+	}
+	return waves, nil
+}
+
+// orderDependenciesByKind annotates the dependencies with the sync wave of
+// their kind. Dependencies already annotated keep their wave.
+func orderDependenciesByKind(dependencies []v1alpha1.Dependency, opts *DependencyOptions) error {
+	if !opts.OrderByKind {
+		if opts.SyncWavesFile != "" {
+			return fmt.Errorf("--sync-waves requires --order-by-kind")
+		}
+		return nil
+	}
+	waves, err := loadSyncWaves(opts.SyncWavesFile)
+	if err != nil {
+		return err
+	}
+	otherKindsWave := 0
+	for _, wave := range waves {
+		otherKindsWave = max(otherKindsWave, wave)
+	}
+
+	for i := range dependencies {
+		annotations := dependencies[i].GetAnnotations()
+		if _, found := annotations[syncWaveAnnotation]; found {
+			logVerbose("%s %s keeps its %s annotation", dependencies[i].GetKind(), dependencies[i].GetName(), syncWaveAnnotation)
+			continue
+		}
+		wave, found := waves[dependencies[i].GetKind()]
+		if !found {
+			wave = otherKindsWave
+		}
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[syncWaveAnnotation] = strconv.Itoa(wave)
+		dependencies[i].SetAnnotations(annotations)
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+# {{ .Kind }} controller
+
+A minimal controller reconciling the {{ .CRDName }} CRD shipped by the
+{{ .Promise }} Promise, as a starting point. It is not part of the Promise: build
+it, push its image and deploy it to the Destinations of the Promise yourself.
+
+The reconciler in `main.go` reads the {{ .Kind }} resources as unstructured
+objects, so it compiles without generated types. Implement `Reconcile`, and
+grant the controller the RBAC of the `+kubebuilder:rbac` markers.
+
+```
+go mod tidy
+go build ./...
+docker build --tag myorg/{{ .Promise }}-controller:v0.1.0 .
+```
@@ -0,0 +1,8 @@
+module {{ .Module }}
+
+go 1.22
+
+require (
+	k8s.io/apimachinery {{ .APIMachineryVersion }}
+	sigs.k8s.io/controller-runtime {{ .ControllerRuntimeVersion }}
+)
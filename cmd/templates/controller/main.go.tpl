@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+// {{ .TypeName }}GVK is the group, version and kind of the resources reconciled
+// by the controller: the {{ .CRDName }} CRD shipped by the {{ .Promise }} Promise.
+var {{ .TypeName }}GVK = schema.GroupVersionKind{
+	Group:   "{{ .Group }}",
+	Version: "{{ .Version }}",
+	Kind:    "{{ .Kind }}",
+}
+
+// {{ .TypeName }}Reconciler reconciles the {{ .Kind }} resources.
+type {{ .TypeName }}Reconciler struct {
+	client.Client
+}
+
+// +kubebuilder:rbac:groups={{ .Group }},resources={{ .Plural }},verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups={{ .Group }},resources={{ .Plural }}/status,verbs=get;update;patch
+
+// Reconcile moves the cluster towards the state declared by a {{ .Kind }}.
+func (r *{{ .TypeName }}Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	resource := &unstructured.Unstructured{}
+	resource.SetGroupVersionKind({{ .TypeName }}GVK)
+	if err := r.Get(ctx, req.NamespacedName, resource); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	logger.Info("Reconciling {{ .Kind }}", "name", req.Name, "namespace", req.Namespace)
+	// TODO: create or update the objects declared by resource.Object["spec"],
+	// and report their state in the status of the resource.
+
+	return ctrl.Result{}, nil
+}
+
+func main() {
+	ctrl.SetLogger(zap.New())
+	logger := ctrl.Log.WithName("setup")
+
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{})
+	if err != nil {
+		logger.Error(err, "unable to create the manager")
+		os.Exit(1)
+	}
+
+	resource := &unstructured.Unstructured{}
+	resource.SetGroupVersionKind({{ .TypeName }}GVK)
+	if err := ctrl.NewControllerManagedBy(mgr).
+		For(resource).
+		Complete(&{{ .TypeName }}Reconciler{Client: mgr.GetClient()}); err != nil {
+		logger.Error(err, "unable to create the controller")
+		os.Exit(1)
+	}
+
+	logger.Info("Starting the manager")
+	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+		logger.Error(err, "unable to run the manager")
+		os.Exit(1)
+	}
+}
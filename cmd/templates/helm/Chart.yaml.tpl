@@ -0,0 +1,6 @@
+apiVersion: v2
+name: {{ .Name }}
+description: A Helm chart for the {{ .Name }} Promise
+type: application
+version: 0.1.0
+appVersion: {{ .AppVersion | quote }}
@@ -1,4 +1,4 @@
-apiVersion: platform.kratix.io/v1alpha1
+apiVersion: {{ .APIVersion }}
 kind: Promise
 metadata:
   name: {{ .Name }}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"encoding/json"
+	"math"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	testScaffoldDir      = "tests"
+	testScaffoldFileName = "chainsaw-test.yaml"
+)
+
+// chainsawTest generates a Chainsaw test requesting a resource of the Promise
+// API and asserting it becomes Ready. The resource has no namespace, so
+// Chainsaw creates it in the namespace of the test.
+func chainsawTest(promiseName string, crd *apiextensionsv1.CustomResourceDefinition) *unstructured.Unstructured {
+	apiVersion := crd.Spec.Group + "/" + crd.Spec.Versions[0].Name
+	metadata := map[string]any{"name": "example-" + promiseName}
+
+	resource := map[string]any{
+		"apiVersion": apiVersion,
+		"kind":       crd.Spec.Names.Kind,
+		"metadata":   metadata,
+	}
+	if spec, ok := crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"]; ok {
+		resource["spec"] = exampleValue(spec)
+	}
+
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "chainsaw.kyverno.io/v1alpha1",
+		"kind":       "Test",
+		"metadata": map[string]any{
+			"name": promiseName,
+		},
+		"spec": map[string]any{
+			"steps": []any{
+				map[string]any{
+					"name": "request-a-" + crd.Spec.Names.Singular,
+					"try": []any{
+						map[string]any{"apply": map[string]any{"resource": resource}},
+						map[string]any{"assert": map[string]any{"resource": map[string]any{
+							"apiVersion": apiVersion,
+							"kind":       crd.Spec.Names.Kind,
+							"metadata":   metadata,
+							"status": map[string]any{
+								"(conditions[?type == 'Ready'])": []any{
+									map[string]any{"status": "True"},
+								},
+							},
+						}}},
+					},
+				},
+			},
+		},
+	}}
+}
+
+// exampleValue returns a value valid against the schema: its default, its
+// first enum value, or a value of its type. Objects only get their required
+// properties.
+func exampleValue(schema apiextensionsv1.JSONSchemaProps) any {
+	if schema.Default != nil {
+		if value, ok := rawJSONValue(schema.Default); ok {
+			return value
+		}
+	}
+	if len(schema.Enum) > 0 {
+		if value, ok := rawJSONValue(&schema.Enum[0]); ok {
+			return value
+		}
+	}
+
+	switch schema.Type {
+	case "string":
+		return "example"
+	case "integer", "number":
+		if schema.Minimum == nil {
+			return int64(1)
+		}
+		minimum := int64(math.Ceil(*schema.Minimum))
+		if schema.ExclusiveMinimum && float64(minimum) == *schema.Minimum {
+			minimum++
+		}
+		return minimum
+	case "boolean":
+		return false
+	case "array":
+		return []any{}
+	}
+
+	object := map[string]any{}
+	for _, name := range schema.Required {
+		object[name] = exampleValue(schema.Properties[name])
+	}
+	return object
+}
+
+func rawJSONValue(raw *apiextensionsv1.JSON) (any, bool) {
+	var value any
+	if err := json.Unmarshal(raw.Raw, &value); err != nil {
+		return nil, false
+	}
+	return value, true
+}
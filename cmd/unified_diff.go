@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// diffContextLines is the number of unchanged lines around each hunk.
+const diffContextLines = 3
+
+type diffOpKind byte
+
+const (
+	diffEqual  diffOpKind = ' '
+	diffDelete diffOpKind = '-'
+	diffInsert diffOpKind = '+'
+)
+
+type diffOp struct {
+	kind diffOpKind
+	line string
+}
+
+// unifiedDiff returns the unified diff of the lines of oldText and newText, or
+// an empty string when they are equal.
+func unifiedDiff(oldName, newName, oldText, newText string) string {
+	ops := diffLines(splitLines(oldText), splitLines(newText))
+	if !slices.ContainsFunc(ops, func(op diffOp) bool { return op.kind != diffEqual }) {
+		return ""
+	}
+
+	var diff strings.Builder
+	fmt.Fprintf(&diff, "--- %s\n+++ %s\n", oldName, newName)
+	for start := 0; start < len(ops); {
+		// skip to the first change, keeping its context
+		first := start
+		for first < len(ops) && ops[first].kind == diffEqual {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		hunkStart := max(start, first-diffContextLines)
+
+		// extend the hunk until the changes are further apart than twice
+		// the context
+		hunkEnd := first
+		for equal := 0; hunkEnd < len(ops) && equal <= 2*diffContextLines; hunkEnd++ {
+			if ops[hunkEnd].kind == diffEqual {
+				equal++
+			} else {
+				equal = 0
+			}
+		}
+		for hunkEnd > first && ops[hunkEnd-1].kind == diffEqual {
+			hunkEnd--
+		}
+		hunkEnd = min(len(ops), hunkEnd+diffContextLines)
+
+		writeHunk(&diff, ops, hunkStart, hunkEnd)
+		start = hunkEnd
+	}
+	return diff.String()
+}
+
+func writeHunk(diff *strings.Builder, ops []diffOp, start, end int) {
+	oldLine, newLine := 1, 1
+	for _, op := range ops[:start] {
+		if op.kind != diffInsert {
+			oldLine++
+		}
+		if op.kind != diffDelete {
+			newLine++
+		}
+	}
+
+	var oldCount, newCount int
+	for _, op := range ops[start:end] {
+		if op.kind != diffInsert {
+			oldCount++
+		}
+		if op.kind != diffDelete {
+			newCount++
+		}
+	}
+	if oldCount == 0 {
+		oldLine--
+	}
+	if newCount == 0 {
+		newLine--
+	}
+
+	fmt.Fprintf(diff, "@@ -%d,%d +%d,%d @@\n", oldLine, oldCount, newLine, newCount)
+	for _, op := range ops[start:end] {
+		fmt.Fprintf(diff, "%c%s\n", op.kind, op.line)
+	}
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines computes the shortest edit script turning a into b, with the
+// Myers algorithm. Only the diagonals reachable at each step are kept, so the
+// memory grows with the square of the number of changes rather than with the
+// size of the inputs.
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+
+	for d := 0; d <= n+m; d++ {
+		trace = append(trace, slices.Clone(v[offset-d:offset+d+1]))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrackDiff(a, b, trace)
+			}
+		}
+	}
+	return nil
+}
+
+func backtrackDiff(a, b []string, trace [][]int) []diffOp {
+	var ops []diffOp
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[k-1+d] < v[k+1+d]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[prevK+d]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			ops = append(ops, diffOp{diffEqual, a[x-1]})
+			x--
+			y--
+		}
+		if x == prevX {
+			ops = append(ops, diffOp{diffInsert, b[y-1]})
+		} else {
+			ops = append(ops, diffOp{diffDelete, a[x-1]})
+		}
+		x, y = prevX, prevY
+	}
+	for x > 0 && y > 0 {
+		ops = append(ops, diffOp{diffEqual, a[x-1]})
+		x--
+		y--
+	}
+	slices.Reverse(ops)
+	return ops
+}
@@ -0,0 +1,73 @@
+package cmd_test
+
+import (
+	"fmt"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/syntasso/kratix-cli/cmd"
+)
+
+var _ = Describe("UnifiedDiff", func() {
+	lines := func(from, to int) string {
+		var text strings.Builder
+		for i := from; i <= to; i++ {
+			fmt.Fprintf(&text, "line %d\n", i)
+		}
+		return text.String()
+	}
+
+	It("is empty when the texts are equal", func() {
+		Expect(UnifiedDiff("old", "new", lines(1, 5), lines(1, 5))).To(BeEmpty())
+	})
+
+	It("prints the changes with their context", func() {
+		oldText := lines(1, 20)
+		newText := strings.Replace(strings.Replace(oldText, "line 2\n", "line two\n", 1), "line 18\n", "", 1) + "line 21\n"
+
+		Expect(UnifiedDiff("old", "new", oldText, newText)).To(Equal(`--- old
++++ new
+@@ -1,5 +1,5 @@
+ line 1
+-line 2
++line two
+ line 3
+ line 4
+ line 5
+@@ -15,6 +15,6 @@
+ line 15
+ line 16
+ line 17
+-line 18
+ line 19
+ line 20
++line 21
+`))
+	})
+
+	It("merges the changes closer than twice the context", func() {
+		oldText := lines(1, 10)
+		newText := strings.Replace(strings.Replace(oldText, "line 2\n", "", 1), "line 8\n", "", 1)
+
+		Expect(UnifiedDiff("old", "new", oldText, newText)).To(Equal(`--- old
++++ new
+@@ -1,10 +1,8 @@
+ line 1
+-line 2
+ line 3
+ line 4
+ line 5
+ line 6
+ line 7
+-line 8
+ line 9
+ line 10
+`))
+	})
+
+	It("diffs from and to an empty text", func() {
+		Expect(UnifiedDiff("old", "new", "", lines(1, 2))).To(Equal("--- old\n+++ new\n@@ -0,0 +1,2 @@\n+line 1\n+line 2\n"))
+		Expect(UnifiedDiff("old", "new", lines(1, 2), "")).To(Equal("--- old\n+++ new\n@@ -1,2 +0,0 @@\n-line 1\n-line 2\n"))
+	})
+})
@@ -27,7 +27,11 @@ and boolean.
 
 For object types, the property name can be nested using the '.' character.
 
-To remove a property, append a '-' to the property name.`
+To remove a property, append a '-' to the property name.
+
+The --set flag sets any value in the API CRD, using a dotted path from the CRD
+root with list indexes, e.g. spec.versions[0].schema.openAPIV3Schema.required[0]=spec.
+Values are parsed as YAML. The --set flags are applied after the other flags.`
 
 var updateAPICmd = &cobra.Command{
 	Use:   "api --property PROPERTY-NAME:TYPE",
@@ -47,6 +51,9 @@ var updateAPICmd = &cobra.Command{
 
   # updates the version and the plural form
   kratix update api --version v1beta3 --plural mydbs
+
+  # sets a maximum on the 'port' property
+  kratix update api --set spec.versions[0].schema.openAPIV3Schema.properties.spec.properties.port.maximum=65535
   `,
 	RunE: UpdateAPI,
 }
@@ -54,6 +61,7 @@ var updateAPICmd = &cobra.Command{
 var (
 	dir, apiVersion string
 	properties      []string
+	setValues       []string
 )
 
 func init() {
@@ -64,33 +72,15 @@ func init() {
 	updateAPICmd.Flags().StringVarP(&apiVersion, "version", "v", "", "The group version for the Promise")
 	updateAPICmd.Flags().StringVar(&plural, "plural", "", "The plural form of the kind")
 	updateAPICmd.Flags().StringArrayVarP(&properties, "property", "p", []string{}, "Property of the Promise API to update")
+	updateAPICmd.Flags().StringArrayVar(&setValues, "set", nil, "Set a value in the API CRD using a PATH=VALUE format. Can be specified multiple times.")
 }
 
 func UpdateAPI(cmd *cobra.Command, args []string) error {
-	var crd apiextensionsv1.CustomResourceDefinition
-	var promise v1alpha1.Promise
-
-	var splitFile bool
-	filePath := filepath.Join(dir, apiFileName)
-	if _, foundErr := os.Stat(filePath); foundErr == nil {
-		splitFile = true
-		apiBytes, err := os.ReadFile(filePath)
-		if err = yaml.Unmarshal(apiBytes, &crd); err != nil {
-			return err
-		}
-	} else {
-		filePath = filepath.Join(dir, promiseFileName)
-		promiseBytes, err := os.ReadFile(filePath)
-		if err != nil {
-			return fmt.Errorf("failed to find %s or %s in directory. Please run 'kratix init promise' first: %s", apiFileName, promiseFileName, err)
-		}
-		if err = yaml.Unmarshal(promiseBytes, &promise); err != nil {
-			return err
-		}
-		if err = yaml.Unmarshal(promise.Spec.API.Raw, &crd); err != nil {
-			return err
-		}
+	crd, promise, filePath, err := readPromiseAPI(dir)
+	if err != nil {
+		return err
 	}
+	splitFile := filepath.Base(filePath) == apiFileName
 
 	jsonBytes, err := updateCRDBytes(&crd)
 	if err != nil {
@@ -116,6 +106,39 @@ func UpdateAPI(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// readPromiseAPI reads the Promise API from the api.yaml file of dir, or from
+// its promise.yaml file when there is no api.yaml. It returns the path of the
+// file read, and the Promise when it is read from promise.yaml.
+func readPromiseAPI(dir string) (apiextensionsv1.CustomResourceDefinition, v1alpha1.Promise, string, error) {
+	var crd apiextensionsv1.CustomResourceDefinition
+	var promise v1alpha1.Promise
+
+	filePath := filepath.Join(dir, apiFileName)
+	if _, foundErr := os.Stat(filePath); foundErr == nil {
+		apiBytes, err := os.ReadFile(filePath)
+		if err != nil {
+			return crd, promise, "", err
+		}
+		if err = yaml.Unmarshal(apiBytes, &crd); err != nil {
+			return crd, promise, "", err
+		}
+		return crd, promise, filePath, nil
+	}
+
+	filePath = filepath.Join(dir, promiseFileName)
+	promiseBytes, err := os.ReadFile(filePath)
+	if err != nil {
+		return crd, promise, "", fmt.Errorf("failed to find %s or %s in directory. Please run 'kratix init promise' first: %s", apiFileName, promiseFileName, err)
+	}
+	if err = yaml.Unmarshal(promiseBytes, &promise); err != nil {
+		return crd, promise, "", err
+	}
+	if err = yaml.Unmarshal(promise.Spec.API.Raw, &crd); err != nil {
+		return crd, promise, "", err
+	}
+	return crd, promise, filePath, nil
+}
+
 func updateCRDBytes(crd *apiextensionsv1.CustomResourceDefinition) ([]byte, error) {
 	if gvkNeedsUpdate() {
 		updateGVK(crd)
@@ -138,7 +161,7 @@ func updateCRDBytes(crd *apiextensionsv1.CustomResourceDefinition) ([]byte, erro
 			parsedProps := strings.Split(prop, ":")
 			if len(parsedProps) != 2 {
 				if prop[len(prop)-1:] != "-" {
-					return nil, fmt.Errorf("invalid property format: %s", prop)
+					return nil, newCLIError(errCodeInvalidProperty, map[string]any{"property": prop}, "invalid property format: %s", prop)
 				}
 				p := strings.TrimRight(prop, "-")
 
@@ -161,7 +184,7 @@ func updateCRDBytes(crd *apiextensionsv1.CustomResourceDefinition) ([]byte, erro
 			propType := parsedProps[1]
 
 			if !slices.Contains([]string{"string", "number", "integer", "object", "boolean"}, propType) {
-				return nil, fmt.Errorf("unsupported property type: %s", propType)
+				return nil, newCLIError(errCodeInvalidProperty, map[string]any{"property": prop}, "unsupported property type: %s", propType)
 			}
 
 			curr := specProperties
@@ -174,7 +197,7 @@ func updateCRDBytes(crd *apiextensionsv1.CustomResourceDefinition) ([]byte, erro
 					}
 				}
 				if curr[propNames[i]].Type != "object" {
-					return nil, fmt.Errorf("nested field %s is not an object", propNames[i])
+					return nil, newCLIError(errCodeInvalidProperty, map[string]any{"property": prop}, "nested field %s is not an object", propNames[i])
 				}
 
 				curr = curr[propNames[i]].Properties
@@ -184,6 +207,10 @@ func updateCRDBytes(crd *apiextensionsv1.CustomResourceDefinition) ([]byte, erro
 			}
 		}
 	}
+
+	if err := applySetValues(crd, setValues); err != nil {
+		return nil, err
+	}
 	return json.Marshal(crd)
 }
 
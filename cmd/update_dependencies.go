@@ -6,10 +6,13 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/syntasso/kratix/api/v1alpha1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/yaml"
 	yamlsig "sigs.k8s.io/yaml"
 )
@@ -31,8 +34,23 @@ func init() {
 	updateCmd.AddCommand(updateDependenciesCmd)
 	updateDependenciesCmd.Flags().StringVarP(&dir, "dir", "d", ".", "Directory to read Promise from")
 	updateDependenciesCmd.Flags().StringVarP(&image, "image", "i", "", "Store dependencies to a Promise Configure workflow image with this image/tag")
+	updateDependenciesCmd.Flags().BoolVar(&updateDependenciesOpts.DropWebhookConfigs, "drop-webhook-configs", false, "Remove ValidatingWebhookConfigurations and MutatingWebhookConfigurations from the dependencies")
+	updateDependenciesCmd.Flags().StringArrayVar(&updateDependenciesOpts.APIVersionRewrites, "rewrite-apiversion", nil, "An OLD=NEW rewrite of the apiVersion of the dependencies, e.g. rbac.authorization.k8s.io/v1beta1=rbac.authorization.k8s.io/v1. Can be specified multiple times")
+	updateDependenciesCmd.Flags().BoolVar(&updateDependenciesOpts.NormalizeImages, "normalize-images", false, "Fully qualify the images of the dependencies without a registry with docker.io, e.g. nginx:latest becomes docker.io/library/nginx:latest")
+	updateDependenciesCmd.Flags().StringVar(&updateDependenciesOpts.Patches, "dependency-patch", "", "A file of JSON6902 or strategic merge patches to apply to the dependencies matching their target kind and name")
+	updateDependenciesCmd.Flags().StringVar(&updateDependenciesOpts.Namespace, "operator-namespace", "", "The namespace of the dependencies without one. Defaults to default")
+	updateDependenciesCmd.Flags().StringArrayVar(&updateDependenciesOpts.AnnotationsToStrip, "strip-annotation", nil, "An annotation key, or a prefix ending in /, to remove from the dependencies. kubectl.kubernetes.io/last-applied-configuration is always removed. Can be specified multiple times")
+	updateDependenciesCmd.Flags().IntVar(&updateDependenciesOpts.MaxBytes, "max-dependencies-bytes", defaultMaxDependenciesBytes, "Fail when the serialized dependencies are larger than this many bytes. 0 disables the check")
+	updateDependenciesCmd.Flags().StringVar(&updateDependenciesOpts.Transform, "transform", "", "A command to pipe the dependencies through, as a YAML stream on its stdin, reading the transformed dependencies from its stdout. Only use trusted commands")
+	updateDependenciesCmd.Flags().BoolVar(&updateDependenciesOpts.Verify, "verify-dependencies", false, "Check the dependencies client-side for a valid apiVersion, kind and name, and the required fields of known kinds, reporting all the problems at once")
+	updateDependenciesCmd.Flags().BoolVar(&updateDependenciesOpts.RedactSecrets, "redact-secrets", false, "Blank the values of the data and stringData of the Secrets of the dependencies, keeping their keys")
+	updateDependenciesCmd.Flags().BoolVar(&updateDependenciesOpts.OrderByKind, "order-by-kind", false, "Annotate the dependencies with the Argo CD sync wave of their kind: Namespaces and CRDs first, then RBAC, configuration, workloads and webhooks")
+	updateDependenciesCmd.Flags().StringVar(&updateDependenciesOpts.SyncWavesFile, "sync-waves", "", "A YAML file mapping kinds to the sync waves of --order-by-kind, overriding the default ones")
+	updateDependenciesCmd.Flags().BoolVar(&updateDependenciesOpts.PreserveHelmAnnotations, "preserve-helm-annotations", false, "Keep the helm.sh/hook and meta.helm.sh/ annotations of the dependencies, which are removed by default")
 }
 
+var updateDependenciesOpts = &DependencyOptions{}
+
 func updateDependencies(cmd *cobra.Command, args []string) error {
 	dependenciesDir := args[0]
 	if image != "" {
@@ -41,10 +59,16 @@ func updateDependencies(cmd *cobra.Command, args []string) error {
 
 	var depBytes []byte
 	mode, fileToUpdate := promiseFileMode()
-	dependencies, err := buildDependencies(dependenciesDir)
+	dependencies, err := buildDependencies(updateDependenciesOpts, dependenciesDir)
 	if err != nil {
 		return err
 	}
+	if err := orderDependenciesByKind(dependencies, updateDependenciesOpts); err != nil {
+		return err
+	}
+	if err := checkDependenciesSize(dependencies, updateDependenciesOpts.MaxBytes); err != nil {
+		return err
+	}
 
 	if depBytes, err = yamlsig.Marshal(dependencies); err != nil {
 		return err
@@ -72,7 +96,85 @@ func promiseFileMode() (mode string, fileToUpdate string) {
 	return "split", dependenciesFileName
 }
 
-func buildDependencies(dependenciesDir string) ([]v1alpha1.Dependency, error) {
+// buildDependencies reads the dependencies of each of dependenciesDirs, in
+// order, dropping the documents already read from an earlier one, and rewrites
+// them as set by opts.
+func buildDependencies(opts *DependencyOptions, dependenciesDirs ...string) ([]v1alpha1.Dependency, error) {
+	if opts.Namespace != "" {
+		if errs := validation.IsDNS1123Label(opts.Namespace); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid --operator-namespace %q: %s", opts.Namespace, strings.Join(errs, ", "))
+		}
+	}
+	rewrites, err := parseAPIVersionRewrites(opts.APIVersionRewrites)
+	if err != nil {
+		return nil, err
+	}
+	patches, err := loadDependencyPatches(opts.Patches)
+	if err != nil {
+		return nil, err
+	}
+
+	var dependencies []v1alpha1.Dependency
+	for _, dependenciesDir := range dependenciesDirs {
+		dirDependencies, err := readDependencies(dependenciesDir, opts.namespace())
+		if err != nil {
+			return nil, err
+		}
+		dependencies = append(dependencies, dirDependencies...)
+	}
+	rewriteAPIVersions(dependencies, rewrites)
+	if len(dependenciesDirs) > 1 {
+		dependencies = dedupeDependencies(dependencies)
+	}
+	if err := applyDependencyPatches(dependencies, patches); err != nil {
+		return nil, err
+	}
+	stripAnnotations(dependencies, opts.AnnotationsToStrip, opts.PreserveHelmAnnotations)
+	redactSecretValues(dependencies, opts.RedactSecrets)
+	if opts.NormalizeImages {
+		normalizeDependencyImages(dependencies)
+	}
+
+	if opts.DropWebhookConfigs {
+		dependencies = removeWebhookConfigs(dependencies)
+	} else {
+		warnAboutMissingWebhookServices(dependencies)
+	}
+
+	if opts.Transform != "" {
+		if dependencies, err = transformDependencies(dependencies, opts.Transform); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.Verify {
+		if err := verifyDependencies(dependencies); err != nil {
+			return nil, err
+		}
+	}
+	return dependencies, nil
+}
+
+// dedupeDependencies keeps the first of the dependencies with the same group,
+// kind, namespace and name, warning when a later one differs from it.
+func dedupeDependencies(dependencies []v1alpha1.Dependency) []v1alpha1.Dependency {
+	var deduped []v1alpha1.Dependency
+	seen := map[string]v1alpha1.Dependency{}
+	for _, dep := range dependencies {
+		key := fmt.Sprintf("%s/%s/%s", dep.GroupVersionKind().GroupKind(), dep.GetNamespace(), dep.GetName())
+		if first, found := seen[key]; found {
+			if !reflect.DeepEqual(first.Object, dep.Object) {
+				fmt.Fprintf(os.Stderr, "Warning: %s %s is defined differently by several --operator-manifests: keeping the first one\n", dep.GetKind(), dep.GetName())
+			}
+			continue
+		}
+		seen[key] = dep
+		deduped = append(deduped, dep)
+	}
+	return deduped
+}
+
+func readDependencies(dependenciesDir, namespace string) ([]v1alpha1.Dependency, error) {
 	dependenciesDirInfo, err := os.Stat(dependenciesDir)
 	if err != nil {
 		return nil, fmt.Errorf("failed to stat dependency: %s", dependenciesDir)
@@ -80,7 +182,7 @@ func buildDependencies(dependenciesDir string) ([]v1alpha1.Dependency, error) {
 
 	var dependencies []v1alpha1.Dependency
 	if !dependenciesDirInfo.IsDir() {
-		dependencies, err = extractDepFromFile(dependenciesDir)
+		dependencies, err = extractDepFromFile(dependenciesDir, namespace)
 		if err != nil {
 			return nil, err
 		}
@@ -102,7 +204,7 @@ func buildDependencies(dependenciesDir string) ([]v1alpha1.Dependency, error) {
 	for _, fileInfo := range files {
 		fileName := filepath.Join(dependenciesDir, fileInfo.Name())
 		if fileInfo.IsDir() {
-			subDirDependencies, err := buildDependencies(fileName)
+			subDirDependencies, err := readDependencies(fileName, namespace)
 			if err != nil {
 				return nil, err
 			}
@@ -112,7 +214,7 @@ func buildDependencies(dependenciesDir string) ([]v1alpha1.Dependency, error) {
 		if !isYAML(fileName) {
 			continue
 		}
-		dep, err := extractDepFromFile(fileName)
+		dep, err := extractDepFromFile(fileName, namespace)
 		if err != nil {
 			return nil, err
 		}
@@ -126,13 +228,19 @@ func buildDependencies(dependenciesDir string) ([]v1alpha1.Dependency, error) {
 	return dependencies, nil
 }
 
-func extractDepFromFile(fileName string) ([]v1alpha1.Dependency, error) {
+// extractDepFromFile reads the dependencies of a file, putting the ones without
+// a namespace in namespace.
+func extractDepFromFile(fileName, namespace string) ([]v1alpha1.Dependency, error) {
 	var dependencies []v1alpha1.Dependency
 	file, err := os.Open(fileName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open dependency file %s: %s", fileName, err)
 	}
+	defer file.Close()
 
+	// Documents are decoded one at a time, so only the current document is held
+	// in memory. Each YAML document is converted to JSON before being decoded,
+	// which resolves any anchors, aliases and merge keys within the document.
 	decoder := yaml.NewYAMLOrJSONDecoder(file, 2048)
 	for {
 		var obj *unstructured.Unstructured
@@ -147,7 +255,7 @@ func extractDepFromFile(fileName string) ([]v1alpha1.Dependency, error) {
 			continue
 		}
 		if obj.GetNamespace() == "" {
-			obj.SetNamespace("default")
+			obj.SetNamespace(namespace)
 		}
 		dependencies = append(dependencies, v1alpha1.Dependency{Unstructured: *obj})
 	}
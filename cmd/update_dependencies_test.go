@@ -0,0 +1,86 @@
+package cmd_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/syntasso/kratix-cli/cmd"
+)
+
+// BenchmarkBuildDependencies measures ingesting a large operator bundle made
+// of 500 CRDs, spread across files of 10 documents each.
+func BenchmarkBuildDependencies(b *testing.B) {
+	dir := b.TempDir()
+	for file := 0; file < 50; file++ {
+		var content []byte
+		for doc := 0; doc < 10; doc++ {
+			content = append(content, syntheticCRD(fmt.Sprintf("kind%d", file*10+doc))...)
+		}
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("crds-%d.yaml", file)), content, 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dependencies, err := BuildDependencies(&DependencyOptions{}, dir)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(dependencies) != 500 {
+			b.Fatalf("expected 500 dependencies, got %d", len(dependencies))
+		}
+	}
+}
+
+func syntheticCRD(kind string) string {
+	properties := ""
+	for i := 0; i < 20; i++ {
+		properties += fmt.Sprintf(`
+                field%d:
+                  type: string
+                  description: A field of the %s resource`, i, kind)
+	}
+
+	return fmt.Sprintf(`---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: %[1]ss.example.com
+spec:
+  group: example.com
+  names:
+    kind: %[1]s
+    plural: %[1]ss
+  scope: Namespaced
+  versions:
+    - name: v1
+      served: true
+      storage: true
+      schema:
+        openAPIV3Schema:
+          type: object
+          properties:
+            spec:
+              type: object
+              properties:%[2]s
+`, kind, properties)
+}
+
+var _ = DescribeTable("NormalizeImage",
+	func(image, expected string) {
+		Expect(NormalizeImage(image)).To(Equal(expected))
+	},
+	Entry("an official image", "nginx:latest", "docker.io/library/nginx:latest"),
+	Entry("an official image without a tag", "busybox", "docker.io/library/busybox"),
+	Entry("an image in a Docker Hub repository", "bitnami/redis:7", "docker.io/bitnami/redis:7"),
+	Entry("an image with a registry", "ghcr.io/syntasso/configure:v1", "ghcr.io/syntasso/configure:v1"),
+	Entry("an image on a registry port", "registry:5000/configure", "registry:5000/configure"),
+	Entry("an image on localhost", "localhost/configure", "localhost/configure"),
+	Entry("an image with a digest", "nginx@sha256:abc", "docker.io/library/nginx@sha256:abc"),
+)
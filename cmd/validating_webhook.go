@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/syntasso/kratix/api/v1alpha1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// injectCAFromAnnotation makes cert-manager inject the CA of a Certificate
+// into the webhook configuration.
+const injectCAFromAnnotation = "cert-manager.io/inject-ca-from"
+
+// promiseValidatingWebhook generates a ValidatingWebhookConfiguration calling
+// the webhook Service on create and update of the resources of the Promise API.
+// The CA of the Service is read from a PEM file, or injected by cert-manager.
+func promiseValidatingWebhook(crd *apiextensionsv1.CustomResourceDefinition, options *WebhookOptions) (v1alpha1.Dependency, error) {
+	serviceNamespace, serviceName, err := parseNamespacedName("--webhook-service", options.Service)
+	if err != nil {
+		return v1alpha1.Dependency{}, err
+	}
+	if !strings.HasPrefix(options.Path, "/") {
+		return v1alpha1.Dependency{}, fmt.Errorf("invalid --webhook-path %q: must start with /", options.Path)
+	}
+	if options.Port < 1 || options.Port > 65535 {
+		return v1alpha1.Dependency{}, fmt.Errorf("invalid --webhook-port %d: must be between 1 and 65535", options.Port)
+	}
+
+	clientConfig := map[string]any{
+		"service": map[string]any{
+			"namespace": serviceNamespace,
+			"name":      serviceName,
+			"path":      options.Path,
+			"port":      int64(options.Port),
+		},
+	}
+	metadata := map[string]any{"name": crd.Name}
+	switch {
+	case options.CABundleFile != "" && options.InjectCAFrom != "":
+		return v1alpha1.Dependency{}, fmt.Errorf("--webhook-ca-bundle and --webhook-inject-ca-from cannot be used together")
+	case options.CABundleFile != "":
+		caBundle, err := readCABundle(options.CABundleFile)
+		if err != nil {
+			return v1alpha1.Dependency{}, err
+		}
+		clientConfig["caBundle"] = base64.StdEncoding.EncodeToString(caBundle)
+	case options.InjectCAFrom != "":
+		if _, _, err := parseNamespacedName("--webhook-inject-ca-from", options.InjectCAFrom); err != nil {
+			return v1alpha1.Dependency{}, err
+		}
+		metadata["annotations"] = map[string]any{injectCAFromAnnotation: options.InjectCAFrom}
+	default:
+		return v1alpha1.Dependency{}, fmt.Errorf("--with-validating-webhook requires --webhook-ca-bundle or --webhook-inject-ca-from: the API server must trust the webhook Service")
+	}
+
+	return v1alpha1.Dependency{Unstructured: unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "admissionregistration.k8s.io/v1",
+		"kind":       "ValidatingWebhookConfiguration",
+		"metadata":   metadata,
+		"webhooks": []any{
+			map[string]any{
+				"name":                    "validate." + crd.Name,
+				"admissionReviewVersions": []any{"v1"},
+				"sideEffects":             "None",
+				"failurePolicy":           "Fail",
+				"clientConfig":            clientConfig,
+				"rules": []any{
+					map[string]any{
+						"apiGroups":   []any{crd.Spec.Group},
+						"apiVersions": []any{crd.Spec.Versions[0].Name},
+						"resources":   []any{crd.Spec.Names.Plural},
+						"operations":  []any{"CREATE", "UPDATE"},
+					},
+				},
+			},
+		},
+	}}}, nil
+}
+
+// parseNamespacedName parses the NAMESPACE/NAME value of flag.
+func parseNamespacedName(flag, value string) (string, string, error) {
+	namespace, name, found := strings.Cut(value, "/")
+	if !found {
+		return "", "", fmt.Errorf("invalid %s %q: expected NAMESPACE/NAME", flag, value)
+	}
+	for _, part := range []string{namespace, name} {
+		if errs := validation.IsDNS1123Label(part); len(errs) > 0 {
+			return "", "", fmt.Errorf("invalid %s %q: %s", flag, value, strings.Join(errs, ", "))
+		}
+	}
+	return namespace, name, nil
+}
+
+// readCABundle reads a PEM file of one or more CA certificates.
+func readCABundle(path string) ([]byte, error) {
+	caBundle, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook CA bundle %s: %s", path, err)
+	}
+
+	rest := caBundle
+	var certificates int
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return nil, fmt.Errorf("invalid webhook CA bundle %s: unexpected PEM block %s, expected CERTIFICATE", path, block.Type)
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return nil, fmt.Errorf("invalid webhook CA bundle %s: %s", path, err)
+		}
+		certificates++
+	}
+	if certificates == 0 {
+		return nil, fmt.Errorf("invalid webhook CA bundle %s: no PEM certificate found", path)
+	}
+	return caBundle, nil
+}
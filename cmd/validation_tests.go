@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const validationTestFileName = "chainsaw-validation-test.yaml"
+
+// validationCase is an invalid request, the valid example request with path
+// removed or set to value.
+type validationCase struct {
+	name   string
+	path   []string
+	value  any
+	remove bool
+}
+
+// validationCases derives the invalid requests from the spec schema: a missing
+// required property without a default, a value of the wrong type for the
+// properties of the spec, and a value outside the enum, minimum, maximum,
+// minLength or maxLength of any property. Properties accepting any value, such
+// as int-or-string ones, only get the cases of their constraints.
+func validationCases(spec apiextensionsv1.JSONSchemaProps) []validationCase {
+	var cases []validationCase
+	var walk func(schema apiextensionsv1.JSONSchemaProps, path []string)
+	walk = func(schema apiextensionsv1.JSONSchemaProps, path []string) {
+		var names []string
+		for name := range schema.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			property := schema.Properties[name]
+			propertyPath := append(slices.Clone(path), name)
+			field := strings.Join(propertyPath, ".")
+
+			if slices.Contains(schema.Required, name) && property.Default == nil {
+				cases = append(cases, validationCase{name: field + " is missing", path: propertyPath, remove: true})
+			}
+			if value, ok := wrongTypeValue(property); ok && len(path) == 1 {
+				cases = append(cases, validationCase{name: field + " has the wrong type", path: propertyPath, value: value})
+			}
+			if len(property.Enum) > 0 && property.Type == "string" {
+				cases = append(cases, validationCase{name: field + " is not in the enum", path: propertyPath, value: "not-in-enum"})
+			}
+			if property.Minimum != nil && (property.Type == "integer" || property.Type == "number") {
+				value := *property.Minimum - 1
+				if property.Type == "integer" {
+					value = math.Ceil(*property.Minimum) - 1
+					if property.ExclusiveMinimum {
+						value = math.Floor(*property.Minimum)
+					}
+				}
+				cases = append(cases, validationCase{name: field + " is below the minimum", path: propertyPath, value: numberValue(value, property.Type)})
+			}
+			if property.Maximum != nil && (property.Type == "integer" || property.Type == "number") {
+				value := *property.Maximum + 1
+				if property.Type == "integer" {
+					value = math.Floor(*property.Maximum) + 1
+					if property.ExclusiveMaximum {
+						value = math.Ceil(*property.Maximum)
+					}
+				}
+				cases = append(cases, validationCase{name: field + " is above the maximum", path: propertyPath, value: numberValue(value, property.Type)})
+			}
+			if property.MinLength != nil && *property.MinLength > 0 && property.Type == "string" {
+				cases = append(cases, validationCase{name: field + " is too short", path: propertyPath, value: strings.Repeat("a", int(*property.MinLength-1))})
+			}
+			if property.MaxLength != nil && property.Type == "string" {
+				cases = append(cases, validationCase{name: field + " is too long", path: propertyPath, value: strings.Repeat("a", int(*property.MaxLength+1))})
+			}
+
+			if property.Type == "object" && len(property.Properties) > 0 {
+				walk(property, propertyPath)
+			}
+		}
+	}
+	walk(spec, []string{"spec"})
+	return cases
+}
+
+// wrongTypeValue returns a value of another type than the one of the schema.
+func wrongTypeValue(schema apiextensionsv1.JSONSchemaProps) (any, bool) {
+	if schema.XIntOrString || (schema.XPreserveUnknownFields != nil && *schema.XPreserveUnknownFields) {
+		return nil, false
+	}
+	switch schema.Type {
+	case "string":
+		return int64(1), true
+	case "integer", "number":
+		return "not-a-number", true
+	case "boolean":
+		return "not-a-boolean", true
+	case "array":
+		return "not-an-array", true
+	case "object":
+		return "not-an-object", true
+	}
+	return nil, false
+}
+
+func numberValue(value float64, schemaType string) any {
+	if schemaType == "integer" {
+		return int64(value)
+	}
+	return value
+}
+
+// apply sets the case on a copy of the valid resource. The objects on the path
+// missing from the resource are created with their required properties, so
+// the request is only invalid for the reason of the case.
+func (c validationCase) apply(resource map[string]any, spec apiextensionsv1.JSONSchemaProps) map[string]any {
+	resource = runtime.DeepCopyJSON(resource)
+	parent := resource
+	schema := apiextensionsv1.JSONSchemaProps{Properties: map[string]apiextensionsv1.JSONSchemaProps{"spec": spec}}
+	for _, name := range c.path[:len(c.path)-1] {
+		schema = schema.Properties[name]
+		child, ok := parent[name].(map[string]any)
+		if !ok {
+			child, _ = exampleValue(schema).(map[string]any)
+			if child == nil {
+				child = map[string]any{}
+			}
+			parent[name] = child
+		}
+		parent = child
+	}
+
+	last := c.path[len(c.path)-1]
+	if c.remove {
+		delete(parent, last)
+	} else {
+		parent[last] = c.value
+	}
+	return resource
+}
+
+// chainsawValidationTest generates a Chainsaw test checking the Promise API
+// accepts the example request and rejects each of the validation cases. The
+// requests are applied with a server-side dry run, so no pipeline runs.
+func chainsawValidationTest(promiseName string, crd *apiextensionsv1.CustomResourceDefinition) *unstructured.Unstructured {
+	schema := crd.Spec.Versions[0].Schema.OpenAPIV3Schema
+	resource := map[string]any{
+		"apiVersion": crd.Spec.Group + "/" + crd.Spec.Versions[0].Name,
+		"kind":       crd.Spec.Names.Kind,
+		"metadata":   map[string]any{"name": "example-" + promiseName},
+	}
+	spec, hasSpec := schema.Properties["spec"]
+	if hasSpec {
+		resource["spec"] = exampleValue(spec)
+	}
+
+	steps := []any{
+		map[string]any{
+			"name": "accepts a valid " + crd.Spec.Names.Singular,
+			"try": []any{
+				map[string]any{"apply": map[string]any{"dryRun": true, "resource": resource}},
+			},
+		},
+	}
+	if hasSpec {
+		for _, validationCase := range validationCases(spec) {
+			steps = append(steps, map[string]any{
+				"name": fmt.Sprintf("rejects a %s where %s", crd.Spec.Names.Singular, validationCase.name),
+				"try": []any{
+					map[string]any{"apply": map[string]any{
+						"dryRun":   true,
+						"resource": validationCase.apply(resource, spec),
+						"expect": []any{
+							map[string]any{"check": map[string]any{"($error != null)": true}},
+						},
+					}},
+				},
+			})
+		}
+	}
+
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "chainsaw.kyverno.io/v1alpha1",
+		"kind":       "Test",
+		"metadata": map[string]any{
+			"name": promiseName + "-validation",
+		},
+		"spec": map[string]any{
+			"steps": steps,
+		},
+	}}
+}
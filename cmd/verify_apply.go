@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const verifyApplyFieldOwner = "kratix-cli"
+
+// verifyApply submits the objects to the cluster of the current kubeconfig
+// with a server-side dry-run, so nothing is persisted. Without a kubeconfig,
+// the verification is skipped unless requireCluster is set.
+func verifyApply(ctx context.Context, requireCluster bool, objects ...any) error {
+	restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		clientcmd.NewDefaultClientConfigLoadingRules(),
+		&clientcmd.ConfigOverrides{},
+	).ClientConfig()
+	if err != nil {
+		if clientcmd.IsEmptyConfig(err) && !requireCluster {
+			return nil
+		}
+		return fmt.Errorf("failed to load the kubeconfig: %w", err)
+	}
+
+	k8sClient, err := client.New(restConfig, client.Options{})
+	if err != nil {
+		return fmt.Errorf("failed to create the Kubernetes client: %w", err)
+	}
+
+	for _, object := range objects {
+		obj, err := toApplyObject(object)
+		if err != nil {
+			return err
+		}
+		if err := k8sClient.Patch(ctx, obj, client.Apply, client.DryRunAll, client.ForceOwnership, client.FieldOwner(verifyApplyFieldOwner)); err != nil {
+			return fmt.Errorf("%s %s was rejected by the cluster: %w", obj.GetKind(), obj.GetName(), err)
+		}
+	}
+
+	fmt.Println("The generated resources were accepted by the cluster (dry-run).")
+	return nil
+}
+
+// toApplyObject converts the object to unstructured, without the fields which
+// cannot be applied.
+func toApplyObject(object any) (*unstructured.Unstructured, error) {
+	objectBytes, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(objectBytes); err != nil {
+		return nil, err
+	}
+	unstructured.RemoveNestedField(obj.Object, "metadata", "creationTimestamp")
+	unstructured.RemoveNestedField(obj.Object, "status")
+	return obj, nil
+}
+
+func crdWithTypeMeta(crd *apiextensionsv1.CustomResourceDefinition) *apiextensionsv1.CustomResourceDefinition {
+	crd = crd.DeepCopy()
+	crd.APIVersion = apiextensionsv1.SchemeGroupVersion.String()
+	crd.Kind = "CustomResourceDefinition"
+	return crd
+}
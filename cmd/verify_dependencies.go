@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/syntasso/kratix/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// dependencyFieldChecks are the light checks of the fields of known kinds,
+// each returning the problems of a dependency of that kind.
+var dependencyFieldChecks = map[string]func(dep v1alpha1.Dependency) []string{
+	"Deployment":               checkPodTemplateOwner,
+	"StatefulSet":              checkPodTemplateOwner,
+	"DaemonSet":                checkPodTemplateOwner,
+	"Service":                  checkService,
+	"Role":                     checkRoleRules,
+	"ClusterRole":              checkRoleRules,
+	"RoleBinding":              checkRoleBinding,
+	"ClusterRoleBinding":       checkRoleBinding,
+	"CustomResourceDefinition": checkCRD,
+}
+
+// verifyDependencies checks the dependencies client-side, without a cluster:
+// every dependency needs a valid apiVersion, kind and name, and the fields
+// known kinds cannot do without are checked. All the problems are reported at
+// once.
+func verifyDependencies(dependencies []v1alpha1.Dependency) error {
+	var problems []string
+	for i, dep := range dependencies {
+		var depProblems []string
+		if dep.GetAPIVersion() == "" {
+			depProblems = append(depProblems, "apiVersion is required")
+		} else if _, err := schema.ParseGroupVersion(dep.GetAPIVersion()); err != nil {
+			depProblems = append(depProblems, fmt.Sprintf("invalid apiVersion %q", dep.GetAPIVersion()))
+		}
+		if dep.GetKind() == "" {
+			depProblems = append(depProblems, "kind is required")
+		}
+		if dep.GetName() == "" {
+			depProblems = append(depProblems, "metadata.name is required")
+		} else if errs := validation.IsDNS1123Subdomain(dep.GetName()); len(errs) > 0 && !strings.Contains(dep.GetKind(), "Role") {
+			// RBAC objects accept any path segment name, e.g. system:metrics
+			depProblems = append(depProblems, fmt.Sprintf("invalid metadata.name: %s", strings.Join(errs, ", ")))
+		}
+		if namespace := dep.GetNamespace(); namespace != "" {
+			if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+				depProblems = append(depProblems, fmt.Sprintf("invalid metadata.namespace %q: %s", namespace, strings.Join(errs, ", ")))
+			}
+		}
+		if check, ok := dependencyFieldChecks[dep.GetKind()]; ok {
+			depProblems = append(depProblems, check(dep)...)
+		}
+
+		for _, problem := range depProblems {
+			problems = append(problems, fmt.Sprintf("%s: %s", describeDependency(i, dep), problem))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("--verify-dependencies found %d problems in the dependencies:\n  - %s", len(problems), strings.Join(problems, "\n  - "))
+	}
+	return nil
+}
+
+func describeDependency(i int, dep v1alpha1.Dependency) string {
+	if dep.GetKind() == "" || dep.GetName() == "" {
+		return fmt.Sprintf("dependency %d", i)
+	}
+	return dep.GetKind() + " " + dep.GetName()
+}
+
+// checkPodTemplateOwner checks the workloads of apps/v1, which require a
+// selector and containers with a name and an image.
+func checkPodTemplateOwner(dep v1alpha1.Dependency) []string {
+	var problems []string
+	if _, found, _ := unstructured.NestedMap(dep.Object, "spec", "selector"); !found {
+		problems = append(problems, "spec.selector is required")
+	}
+	containers, _, _ := unstructured.NestedSlice(dep.Object, "spec", "template", "spec", "containers")
+	if len(containers) == 0 {
+		return append(problems, "spec.template.spec.containers must not be empty")
+	}
+	for i, item := range containers {
+		container, _ := item.(map[string]any)
+		if name, _ := container["name"].(string); name == "" {
+			problems = append(problems, fmt.Sprintf("spec.template.spec.containers[%d].name is required", i))
+		}
+		if image, _ := container["image"].(string); image == "" {
+			problems = append(problems, fmt.Sprintf("spec.template.spec.containers[%d].image is required", i))
+		}
+	}
+	return problems
+}
+
+func checkService(dep v1alpha1.Dependency) []string {
+	var problems []string
+	ports, _, _ := unstructured.NestedSlice(dep.Object, "spec", "ports")
+	for i, item := range ports {
+		port, _ := item.(map[string]any)
+		if _, ok := port["port"].(int64); !ok {
+			problems = append(problems, fmt.Sprintf("spec.ports[%d].port must be an integer", i))
+		}
+	}
+	return problems
+}
+
+func checkRoleRules(dep v1alpha1.Dependency) []string {
+	var problems []string
+	rules, _, _ := unstructured.NestedSlice(dep.Object, "rules")
+	for i, item := range rules {
+		rule, _ := item.(map[string]any)
+		if verbs, _ := rule["verbs"].([]any); len(verbs) == 0 {
+			problems = append(problems, fmt.Sprintf("rules[%d].verbs must not be empty", i))
+		}
+	}
+	return problems
+}
+
+func checkRoleBinding(dep v1alpha1.Dependency) []string {
+	var problems []string
+	for _, field := range []string{"apiGroup", "kind", "name"} {
+		if value, _, _ := unstructured.NestedString(dep.Object, "roleRef", field); value == "" {
+			problems = append(problems, fmt.Sprintf("roleRef.%s is required", field))
+		}
+	}
+	return problems
+}
+
+func checkCRD(dep v1alpha1.Dependency) []string {
+	var problems []string
+	for _, path := range [][]string{{"spec", "group"}, {"spec", "names", "kind"}, {"spec", "names", "plural"}, {"spec", "scope"}} {
+		if value, _, _ := unstructured.NestedString(dep.Object, path...); value == "" {
+			problems = append(problems, strings.Join(path, ".")+" is required")
+		}
+	}
+	if versions, _, _ := unstructured.NestedSlice(dep.Object, "spec", "versions"); len(versions) == 0 {
+		problems = append(problems, "spec.versions must not be empty")
+	}
+	return problems
+}
@@ -0,0 +1,30 @@
+package cmd
+
+import "github.com/spf13/pflag"
+
+// WebhookOptions are the flags of the ValidatingWebhookConfiguration of the
+// Promise API, pointing at a Service the user provides.
+type WebhookOptions struct {
+	Enabled      bool
+	Service      string
+	Path         string
+	Port         int
+	CABundleFile string
+	InjectCAFrom string
+}
+
+func (o *WebhookOptions) addFlags(flags *pflag.FlagSet) {
+	flags.BoolVar(&o.Enabled, "with-validating-webhook", false, "Add a ValidatingWebhookConfiguration calling --webhook-service for the resources of the Promise API to the Promise dependencies.")
+	flags.StringVar(&o.Service, "webhook-service", "", "The NAMESPACE/NAME of the Service serving the validating webhook. Requires --with-validating-webhook.")
+	flags.StringVar(&o.Path, "webhook-path", "/validate", "The URL path the validating webhook is served on. Requires --with-validating-webhook.")
+	flags.IntVar(&o.Port, "webhook-port", 443, "The port of the webhook Service. Requires --with-validating-webhook.")
+	flags.StringVar(&o.CABundleFile, "webhook-ca-bundle", "", "A PEM file of the CA certificates the webhook Service is signed by. Requires --with-validating-webhook.")
+	flags.StringVar(&o.InjectCAFrom, "webhook-inject-ca-from", "", "The NAMESPACE/NAME of the cert-manager Certificate to inject the CA of, instead of --webhook-ca-bundle. Requires --with-validating-webhook.")
+}
+
+// validate errors on the flags of the webhook set without
+// --with-validating-webhook.
+func (o *WebhookOptions) validate(flags *pflag.FlagSet) error {
+	return requireFlag(flags, "with-validating-webhook", o.Enabled,
+		"webhook-service", "webhook-path", "webhook-port", "webhook-ca-bundle", "webhook-inject-ca-from")
+}
@@ -0,0 +1,55 @@
+package internal
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const gitSourcePrefix = "git::"
+
+// IsGitSource reports whether the operator manifests have to be fetched from a
+// git repository, e.g. git::https://github.com/org/operator//config/crd?ref=v1.2.3
+func IsGitSource(source string) bool {
+	return strings.HasPrefix(source, gitSourcePrefix)
+}
+
+// FetchGitManifests shallow clones the repository of a git source into a
+// temporary directory and returns the path to the requested subdirectory. The
+// returned cleanup function removes the temporary directory.
+func FetchGitManifests(source string) (string, func(), error) {
+	source, err := withShallowClone(source)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid git source %q: %w", source, err)
+	}
+
+	tempDir, err := mkdirTemp("", "operator-manifests")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	cleanup := func() { os.RemoveAll(tempDir) }
+
+	// the destination must not exist, or the git getter tries to update it
+	manifestsDir := filepath.Join(tempDir, "manifests")
+	if err := getModule(manifestsDir, source); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to fetch the operator manifests: %w", err)
+	}
+	return manifestsDir, cleanup, nil
+}
+
+// withShallowClone adds depth=1 to the source, unless a depth is set already.
+func withShallowClone(source string) (string, error) {
+	address, rawQuery, _ := strings.Cut(source, "?")
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "", err
+	}
+	if query.Has("depth") {
+		return source, nil
+	}
+	query.Set("depth", "1")
+	return address + "?" + query.Encode(), nil
+}
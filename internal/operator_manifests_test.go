@@ -0,0 +1,71 @@
+package internal_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/go-getter"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/syntasso/kratix-cli/internal"
+)
+
+var _ = Describe("FetchGitManifests", func() {
+	var dst, src, tempDir string
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "test-operator-manifests")
+		Expect(err).ToNot(HaveOccurred())
+
+		internal.SetMkdirTempFunc(func(dir, pattern string) (string, error) {
+			return tempDir, nil
+		})
+		internal.SetGetModuleFunc(func(givenDst, givenSrc string, opts ...getter.ClientOption) error {
+			dst = givenDst
+			src = givenSrc
+			return os.MkdirAll(givenDst, 0755)
+		})
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tempDir)
+	})
+
+	It("shallow clones the repository into a temporary directory", func() {
+		manifestsDir, cleanup, err := internal.FetchGitManifests("git::https://github.com/org/operator//config?ref=v1.2.3")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(src).To(Equal("git::https://github.com/org/operator//config?depth=1&ref=v1.2.3"))
+		Expect(dst).To(Equal(filepath.Join(tempDir, "manifests")))
+		Expect(manifestsDir).To(Equal(dst))
+
+		cleanup()
+		Expect(tempDir).NotTo(BeADirectory())
+	})
+
+	It("keeps the depth when set", func() {
+		_, cleanup, err := internal.FetchGitManifests("git::https://github.com/org/operator?depth=0")
+		Expect(err).ToNot(HaveOccurred())
+		defer cleanup()
+		Expect(src).To(Equal("git::https://github.com/org/operator?depth=0"))
+	})
+
+	It("removes the temporary directory when the clone fails", func() {
+		internal.SetGetModuleFunc(func(givenDst, givenSrc string, opts ...getter.ClientOption) error {
+			return errors.New("clone failed")
+		})
+
+		_, _, err := internal.FetchGitManifests("git::https://github.com/org/operator")
+		Expect(err).To(MatchError("failed to fetch the operator manifests: clone failed"))
+		Expect(tempDir).NotTo(BeADirectory())
+	})
+})
+
+var _ = Describe("IsGitSource", func() {
+	It("only matches git sources", func() {
+		Expect(internal.IsGitSource("git::https://github.com/org/operator")).To(BeTrue())
+		Expect(internal.IsGitSource("assets/operator")).To(BeFalse())
+	})
+})
@@ -239,6 +239,55 @@ var _ = Describe("build", func() {
 
 			expectDependenciesToMatchOperatorManifests(promise.Spec.Dependencies)
 		})
+
+		It("wraps the promise in a helm chart", func() {
+			chartDir := filepath.Join(promiseDir, "chart")
+			r.run("build", "helm-chart", "postgresql", "--dir", promiseDir, "--out", chartDir)
+
+			chart, err := os.ReadFile(filepath.Join(chartDir, "Chart.yaml"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(chart)).To(SatisfyAll(
+				ContainSubstring("apiVersion: v2"),
+				ContainSubstring("name: postgresql"),
+			))
+
+			values, err := os.ReadFile(filepath.Join(chartDir, "values.yaml"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(values)).To(Equal(`images:
+  from-api-to-operator:
+    repository: ghcr.io/syntasso/kratix-cli/from-api-to-operator
+    tag: v0.1.0
+`))
+
+			promiseTemplate, err := os.ReadFile(filepath.Join(chartDir, "templates", "promise.yaml"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(promiseTemplate)).To(SatisfyAll(
+				ContainSubstring("name: postgresql"),
+				ContainSubstring(`image: {{ include "promise.image" (index .Values.images "from-api-to-operator") | quote }}`),
+				Not(ContainSubstring("ghcr.io/syntasso/kratix-cli/from-api-to-operator")),
+			))
+			Expect(filepath.Join(chartDir, "templates", "_helpers.tpl")).To(BeAnExistingFile())
+		})
+	})
+
+	Context("after init operator promise with dependencies split by scope", func() {
+		BeforeEach(func() {
+			r.run("init", "operator-promise", "postgresql", "--group", "syntasso.io", "--kind", "Database", "--split", "--split-dependencies-by-scope", "--dir", promiseDir, "--operator-manifests", "assets/operator", "--api-schema-from", "postgresqls.acid.zalan.do")
+		})
+
+		It("combines the dependencies, cluster-scoped first", func() {
+			outputFile := filepath.Join(promiseDir, "built-promise.yaml")
+			r.run("build", "promise", "postgresql", "--dir", promiseDir, "--output", outputFile)
+
+			var promise v1alpha1.Promise
+			Expect(yaml.Unmarshal([]byte(cat(outputFile)), &promise)).To(Succeed())
+			var kinds []string
+			for _, dep := range promise.Spec.Dependencies {
+				kinds = append(kinds, dep.GetKind())
+			}
+			Expect(kinds).To(Equal([]string{"ClusterRole", "CustomResourceDefinition", "CustomResourceDefinition", "CustomResourceDefinition",
+				"ServiceAccount", "Deployment", "ServiceAccount"}))
+		})
 	})
 
 	Context("after init helm promise with split", func() {
@@ -269,4 +318,40 @@ var _ = Describe("build", func() {
 				{Name: "CHART_NAME", Value: "hello-world"}})
 		})
 	})
+
+	Describe("build promise-release", func() {
+		It("builds a PromiseRelease for the hosted Promise", func() {
+			sess := r.run("build", "promise-release", "postgresql", "--url", "https://example.com/promises/postgresql.yaml", "--version", "v1.0.0")
+
+			var release v1alpha1.PromiseRelease
+			Expect(yaml.Unmarshal(sess.Out.Contents(), &release)).To(Succeed())
+			Expect(release.Kind).To(Equal("PromiseRelease"))
+			Expect(release.APIVersion).To(Equal(v1alpha1.GroupVersion.String()))
+			Expect(release.Name).To(Equal("postgresql"))
+			Expect(release.Spec.Version).To(Equal("v1.0.0"))
+			Expect(release.Spec.SourceRef).To(Equal(v1alpha1.SourceRef{Type: v1alpha1.TypeHTTP, URL: "https://example.com/promises/postgresql.yaml"}))
+		})
+
+		It("writes the PromiseRelease to --output", func() {
+			output := filepath.Join(promiseDir, "promise-release.yaml")
+			r.run("build", "promise-release", "postgresql", "--url", "https://example.com/promises/postgresql.yaml", "--version", "v1.0.0", "--output", output)
+
+			releaseBytes, err := os.ReadFile(output)
+			Expect(err).NotTo(HaveOccurred())
+			var release v1alpha1.PromiseRelease
+			Expect(yaml.Unmarshal(releaseBytes, &release)).To(Succeed())
+			Expect(release.Spec.Version).To(Equal("v1.0.0"))
+		})
+
+		DescribeTable("rejects invalid flags",
+			func(flags []string, message string) {
+				r.exitCode = 1
+				sess := r.run(append([]string{"build", "promise-release", "postgresql"}, flags...)...)
+				Expect(sess.Err).To(gbytes.Say(message))
+			},
+			Entry("a URL without an http scheme", []string{"--url", "oci://ghcr.io/myorg/postgresql", "--version", "v1.0.0"}, `invalid --url "oci://ghcr.io/myorg/postgresql": must be an http or https URL`),
+			Entry("a version that is not a label value", []string{"--url", "https://example.com/promise.yaml", "--version", "v1.0.0 beta"}, `invalid --version "v1.0.0 beta"`),
+			Entry("a source type the PromiseRelease API does not accept", []string{"--url", "https://example.com/promise.yaml", "--version", "v1.0.0", "--type", "oci"}, `invalid --type "oci": must be one of http`),
+		)
+	})
 })
@@ -0,0 +1,137 @@
+package integration_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var _ = Describe("InitCRDPromise", func() {
+	var r *runner
+	var workingDir string
+	var initPromiseCmd []string
+	var session *gexec.Session
+
+	BeforeEach(func() {
+		var err error
+		workingDir, err = os.MkdirTemp("", "kratix-test")
+		Expect(err).NotTo(HaveOccurred())
+		r = &runner{exitCode: 0}
+		r.flags = map[string]string{
+			"--group": "myorg.com",
+			"--kind":  "database",
+			"--crd":   "assets/operator/postgres-crd.yaml",
+			"--image": "myorg/postgres-pipeline:v1.0.0",
+			"--dir":   workingDir,
+		}
+		initPromiseCmd = []string{"init", "crd-promise", "postgresql"}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(workingDir)).To(Succeed())
+	})
+
+	When("called without required flags", func() {
+		It("prints an error", func() {
+			r.exitCode = 1
+			r.flags = map[string]string{}
+			session := r.run(initPromiseCmd...)
+			Expect(session.Err).To(gbytes.Say(`Error: required flag\(s\) "crd", "group", "image", "kind" not set`))
+		})
+	})
+
+	When("the CRD file does not exist", func() {
+		It("returns an error", func() {
+			r.exitCode = 1
+			r.flags["--crd"] = "does-not-exist.yaml"
+			session := r.run(initPromiseCmd...)
+			Expect(session.Err).To(gbytes.Say(`Error: failed to open dependency file does-not-exist.yaml`))
+		})
+	})
+
+	When("the file does not contain a single CRD", func() {
+		It("returns an error", func() {
+			r.exitCode = 1
+			r.flags["--crd"] = "assets/operator/crd-bundle.yaml"
+			session := r.run(initPromiseCmd...)
+			Expect(session.Err).To(gbytes.Say(`Error: assets/operator/crd-bundle.yaml must contain a single CustomResourceDefinition, found 2 documents`))
+		})
+	})
+
+	Describe("generating a promise from a CRD", func() {
+		It("wraps the CRD in a Promise running the image", func() {
+			session = r.run(initPromiseCmd...)
+			Expect(session.Out).To(SatisfyAll(
+				gbytes.Say(`Promise generated successfully.`),
+				gbytes.Say(`The CRD postgresqls.acid.zalan.do is the only dependency of the Promise`),
+			))
+
+			matchPromise(workingDir, "postgresql", "myorg.com", "v1Stored", "database", "database", "databases")
+			matchExampleResource(workingDir, "example-request", "myorg.com", "v1Stored", "database")
+
+			dependencies := getDependencies(workingDir, false)
+			Expect(dependencies).To(HaveLen(1))
+			Expect(dependencies[0].GetKind()).To(Equal("CustomResourceDefinition"))
+			Expect(dependencies[0].GetName()).To(Equal("postgresqls.acid.zalan.do"))
+
+			Expect(cat(filepath.Join(workingDir, "README.md"))).To(ContainSubstring(
+				"kratix init crd-promise postgresql --crd assets/operator/postgres-crd.yaml --image myorg/postgres-pipeline:v1.0.0 --group myorg.com --kind database",
+			))
+		})
+
+		It("writes the Promise files separately with --split", func() {
+			r.flags["--split"] = ""
+			r.run(initPromiseCmd...)
+
+			matchGvkInAPIFile(workingDir, "myorg.com", "v1Stored", "database", "database", "databases")
+			Expect(getDependencies(workingDir, true)).To(HaveLen(1))
+
+			pipelines := getPipelines(workingDir)
+			Expect(pipelines).To(HaveLen(1))
+			Expect(pipelines[0].Spec.Containers).To(HaveLen(1))
+			Expect(pipelines[0].Spec.Containers[0].Image).To(Equal("myorg/postgres-pipeline:v1.0.0"))
+			Expect(pipelines[0].Spec.Containers[0].Env).To(ConsistOf([]corev1.EnvVar{
+				{Name: "OPERATOR_GROUP", Value: "acid.zalan.do"},
+				{Name: "OPERATOR_KIND", Value: "postgresql"},
+				{Name: "OPERATOR_VERSION", Value: "v1Stored"},
+			}))
+		})
+
+		It("does not write a controller by default", func() {
+			r.run(initPromiseCmd...)
+			Expect(filepath.Join(workingDir, "controller")).NotTo(BeADirectory())
+		})
+	})
+
+	When("called with --with-controller-scaffold", func() {
+		It("writes a controller reconciling the resources of the CRD", func() {
+			r.flags["--with-controller-scaffold"] = ""
+			session = r.run(initPromiseCmd...)
+			Expect(session.Out).To(gbytes.Say(`Controller scaffold for postgresqls.acid.zalan.do generated in .*controller`))
+
+			controllerDir := filepath.Join(workingDir, "controller")
+			for _, file := range []string{"main.go", "go.mod", "Dockerfile", "README.md"} {
+				Expect(filepath.Join(controllerDir, file)).To(BeARegularFile())
+			}
+			Expect(cat(filepath.Join(controllerDir, "main.go"))).To(SatisfyAll(
+				ContainSubstring("var PostgresqlGVK = schema.GroupVersionKind{"),
+				ContainSubstring(`Group:   "acid.zalan.do",`),
+				ContainSubstring(`Version: "v1Stored",`),
+				ContainSubstring(`Kind:    "postgresql",`),
+				ContainSubstring("type PostgresqlReconciler struct"),
+				ContainSubstring("+kubebuilder:rbac:groups=acid.zalan.do,resources=postgresqls,"),
+			))
+			Expect(cat(filepath.Join(controllerDir, "go.mod"))).To(SatisfyAll(
+				ContainSubstring("module example.com/postgresql-controller"),
+				ContainSubstring("sigs.k8s.io/controller-runtime v0.19.0"),
+			))
+
+			Expect(getDependencies(workingDir, false)).To(HaveLen(1))
+		})
+	})
+})
@@ -1,8 +1,22 @@
 package integration_test
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	. "github.com/onsi/ginkgo/v2"
@@ -50,106 +64,2742 @@ var _ = Describe("InitOperatorPromise", func() {
 			session := r.run(initPromiseCmd...)
 			Expect(session.Err).To(gbytes.Say(`Error: required flag\(s\) "api-schema-from", "group", "kind", "operator-manifests" not set`))
 		})
+
+		It("leaves out the flags made optional by another flag", func() {
+			r.exitCode = 1
+			r.flags = map[string]string{"--kind-from-crd": "", "--group-from-crd": ""}
+			session := r.run(initPromiseCmd...)
+			Expect(session.Err).To(gbytes.Say(`Error: required flag\(s\) "api-schema-from", "operator-manifests" not set`))
+		})
+	})
+
+	When("called without required arguments", func() {
+		It("prints an error", func() {
+			r.exitCode = 1
+			session := r.run("init", "operator-promise")
+			Expect(session.Err).To(gbytes.Say(`Error: accepts 1 arg\(s\), received 0`))
+		})
 	})
 
-	When("called without required arguments", func() {
-		It("prints an error", func() {
-			r.exitCode = 1
-			session := r.run("init", "operator-promise")
-			Expect(session.Err).To(gbytes.Say(`Error: accepts 1 arg\(s\), received 0`))
-		})
-	})
+	Describe("generating a promise from an operator", func() {
+
+		Describe("the generated files", func() {
+			var generatedFiles []string
+			BeforeEach(func() {
+				session = r.run(initPromiseCmd...)
+				fileEntries, err := os.ReadDir(workingDir)
+				generatedFiles = []string{}
+				for _, fileEntry := range fileEntries {
+					generatedFiles = append(generatedFiles, fileEntry.Name())
+				}
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("includes a dependencies.yaml file with the contents of the operator manifests", func() {
+				Expect(generatedFiles).To(ContainElement("dependencies.yaml"))
+
+				var dependencies v1alpha1.Dependencies
+				depsContent, err := os.ReadFile(filepath.Join(workingDir, "dependencies.yaml"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(yaml.Unmarshal(depsContent, &dependencies)).To(Succeed())
+				expectDependenciesToMatchOperatorManifests(dependencies)
+			})
+
+			It("includes an api.yaml file with the api-schema-from CRD", func() {
+				Expect(generatedFiles).To(ContainElement("api.yaml"))
+
+				apiContent, err := os.ReadFile(filepath.Join(workingDir, "api.yaml"))
+				Expect(err).ToNot(HaveOccurred())
+
+				var apiCRD apiextensionsv1.CustomResourceDefinition
+				Expect(yaml.Unmarshal(apiContent, &apiCRD)).To(Succeed())
+				expectCRDToMatchOperatorCRD(apiCRD)
+			})
+
+			It("includes a workflow", func() {
+				expectedWorkflowFilepath := filepath.Join(workingDir, "workflows", "resource", "configure", "workflow.yaml")
+				Expect(expectedWorkflowFilepath).To(BeAnExistingFile())
+
+				workflowContent, err := os.ReadFile(expectedWorkflowFilepath)
+				Expect(err).ToNot(HaveOccurred())
+
+				var pipelines []v1alpha1.Pipeline
+				Expect(yaml.Unmarshal(workflowContent, &pipelines)).To(Succeed())
+
+				expectPipelinesToMatchOperatorPipelines(pipelines)
+			})
+
+			It("includes an example resource request", func() {
+				Expect(generatedFiles).To(ContainElement("example-resource.yaml"))
+				expectExampleResourceToMatchOperatorResource(workingDir)
+			})
+
+			It("includes a README.md file", func() {
+				Expect(generatedFiles).To(ContainElement("README.md"))
+				readmeContents, err := os.ReadFile(filepath.Join(workingDir, "README.md"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(readmeContents)).To(ContainSubstring("init operator-promise postgresql --operator-manifests assets/operator --api-schema-from postgresqls.acid.zalan.do --group myorg.com --kind database"))
+			})
+		})
+
+		When("a version is provided", func() {
+			BeforeEach(func() {
+				r.flags["--version"] = "v2beta1"
+				session = r.run(initPromiseCmd...)
+			})
+
+			It("sets the api version to the provided version", func() {
+				apiContent, err := os.ReadFile(filepath.Join(workingDir, "api.yaml"))
+				Expect(err).ToNot(HaveOccurred())
+
+				var apiCRD apiextensionsv1.CustomResourceDefinition
+				Expect(yaml.Unmarshal(apiContent, &apiCRD)).To(Succeed())
+
+				Expect(apiCRD.Spec.Versions).To(HaveLen(1))
+				Expect(apiCRD.Spec.Versions[0].Name).To(Equal("v2beta1"))
+				Expect(apiCRD.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["apiVersion"].Enum).To(HaveLen(1))
+				Expect(apiCRD.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["apiVersion"].Enum[0].Raw).To(BeEquivalentTo(`"myorg.com/v2beta1"`))
+			})
+		})
+
+		When("there is no matching CRD in the manifests directory", func() {
+			BeforeEach(func() {
+				r.flags["--api-schema-from"] = "does-not-exist"
+			})
+
+			It("returns an error", func() {
+				r.exitCode = 1
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say(`Error: no CRD found matching name: does-not-exist`))
+			})
+
+			It("returns a structured error when --error-format is json", func() {
+				r.exitCode = 1
+				r.flags["--error-format"] = "json"
+				session := r.run(initPromiseCmd...)
+
+				var output map[string]any
+				Expect(json.Unmarshal(session.Err.Contents(), &output)).To(Succeed())
+				Expect(output).To(Equal(map[string]any{
+					"code":    "CRDNotFound",
+					"message": "no CRD found matching name: does-not-exist",
+					"details": map[string]any{
+						"availableCRDs": []any{
+							"postgresteams.acid.zalan.do",
+							"operatorconfigurations.acid.zalan.do",
+							"postgresqls.acid.zalan.do",
+						},
+					},
+				}))
+			})
+		})
+
+		When("a flag cannot be parsed and --error-format is json", func() {
+			It("only prints the JSON error", func() {
+				session := withExitCode(1).run("init", "operator-promise", "postgresql", "--not-a-flag", "--error-format", "json")
+
+				var output map[string]any
+				Expect(json.Unmarshal(session.Err.Contents(), &output)).To(Succeed())
+				Expect(output).To(Equal(map[string]any{
+					"code":    "Unknown",
+					"message": "unknown flag: --not-a-flag",
+				}))
+				Expect(session.Out.Contents()).To(BeEmpty())
+			})
+		})
+
+		Describe("destination selectors", func() {
+			var promise v1alpha1.Promise
+
+			BeforeEach(func() {
+				delete(r.flags, "--split")
+				r.flags["--operator-manifests"] = "assets/operator-anchors"
+				r.flags["--api-schema-from"] = "caches.example.com"
+			})
+
+			readPromise := func() {
+				promiseContent, err := os.ReadFile(filepath.Join(workingDir, "promise.yaml"))
+				Expect(err).ToNot(HaveOccurred())
+				promise = v1alpha1.Promise{}
+				Expect(yaml.Unmarshal(promiseContent, &promise)).To(Succeed())
+			}
+
+			It("derives them from the operator nodeSelector with --derive-destination-selectors", func() {
+				r.flags["--derive-destination-selectors"] = ""
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say("Warning: the destination selectors are derived from the operator nodeSelector on a best-effort basis"))
+
+				readPromise()
+				Expect(promise.Spec.DestinationSelectors).To(Equal([]v1alpha1.PromiseScheduling{
+					{MatchLabels: map[string]string{"kubernetes.io/arch": "arm64"}},
+				}))
+			})
+
+			It("uses --destination-selector over the derived ones", func() {
+				r.flags["--derive-destination-selectors"] = ""
+				r.run(append(initPromiseCmd, "--destination-selector", "env=dev", "--destination-selector", "zone=eu")...)
+
+				readPromise()
+				Expect(promise.Spec.DestinationSelectors).To(Equal([]v1alpha1.PromiseScheduling{
+					{MatchLabels: map[string]string{"env": "dev", "zone": "eu"}},
+				}))
+			})
+
+			It("errors when a destination selector is invalid", func() {
+				r.exitCode = 1
+				r.flags["--destination-selector"] = "env"
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say(`invalid destination selector "env": expected KEY=VALUE`))
+			})
+
+			It("errors when a destination selector label is invalid", func() {
+				r.exitCode = 1
+				r.flags["--destination-selector"] = "env=dev prod"
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say(`invalid value "dev prod" of label env`))
+			})
+
+			Describe("--destination-selectors-file", func() {
+				var selectorsFile string
+
+				BeforeEach(func() {
+					selectorsFile = filepath.Join(workingDir, "selectors.yaml")
+				})
+
+				It("adds a selector per label map, before the --destination-selector labels", func() {
+					Expect(os.WriteFile(selectorsFile, []byte("- environment: production\n  region: eu-west-1\n- environment: staging\n"), 0644)).To(Succeed())
+					r.flags["--derive-destination-selectors"] = ""
+					r.flags["--destination-selectors-file"] = selectorsFile
+					r.run(append(initPromiseCmd, "--destination-selector", "env=dev")...)
+
+					readPromise()
+					Expect(promise.Spec.DestinationSelectors).To(Equal([]v1alpha1.PromiseScheduling{
+						{MatchLabels: map[string]string{"environment": "production", "region": "eu-west-1"}},
+						{MatchLabels: map[string]string{"environment": "staging"}},
+						{MatchLabels: map[string]string{"env": "dev"}},
+					}))
+				})
+
+				It("errors when a label key is invalid", func() {
+					Expect(os.WriteFile(selectorsFile, []byte("- environment: production\n- bad key: staging\n"), 0644)).To(Succeed())
+					r.exitCode = 1
+					r.flags["--destination-selectors-file"] = selectorsFile
+					session := r.run(initPromiseCmd...)
+					Expect(session.Err).To(gbytes.Say(`invalid destination selector 1 in .*selectors.yaml: invalid label key "bad key"`))
+				})
+
+				It("errors when the file is not a list of label maps", func() {
+					Expect(os.WriteFile(selectorsFile, []byte("environment: production\n"), 0644)).To(Succeed())
+					r.exitCode = 1
+					r.flags["--destination-selectors-file"] = selectorsFile
+					session := r.run(initPromiseCmd...)
+					Expect(session.Err).To(gbytes.Say("failed to parse destination selectors .*: expected a list of label maps"))
+				})
+			})
+		})
+
+		When("--expose is set", func() {
+			It("only keeps the exposed top-level properties", func() {
+				r.flags["--expose"] = "spec"
+				r.run(initPromiseCmd...)
+
+				apiContent, err := os.ReadFile(filepath.Join(workingDir, "api.yaml"))
+				Expect(err).ToNot(HaveOccurred())
+				var apiCRD apiextensionsv1.CustomResourceDefinition
+				Expect(yaml.Unmarshal(apiContent, &apiCRD)).To(Succeed())
+
+				schema := apiCRD.Spec.Versions[0].Schema.OpenAPIV3Schema
+				Expect(schema.Properties).To(SatisfyAll(HaveLen(4), HaveKey("apiVersion"), HaveKey("kind"), HaveKey("metadata"), HaveKey("spec")))
+				Expect(schema.Required).To(ConsistOf("kind", "apiVersion", "spec"))
+			})
+
+			It("prunes the subtrees and printer columns of the removed properties", func() {
+				r.flags["--expose"] = "spec"
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say(`Warning: dropped the printer column Status: .status.PostgresClusterStatus is not exposed`))
+
+				apiContent := cat(filepath.Join(workingDir, "api.yaml"))
+				Expect(apiContent).NotTo(ContainSubstring("PostgresClusterStatus"))
+
+				var apiCRD apiextensionsv1.CustomResourceDefinition
+				Expect(yaml.Unmarshal([]byte(apiContent), &apiCRD)).To(Succeed())
+				var columns []string
+				for _, column := range apiCRD.Spec.Versions[0].AdditionalPrinterColumns {
+					columns = append(columns, column.JSONPath)
+				}
+				Expect(columns).To(HaveLen(7))
+				Expect(columns).To(ContainElements(".spec.volume.size", ".metadata.creationTimestamp"))
+			})
+
+			It("removes the nested subtrees of the properties left out", func() {
+				r.flags["--expose"] = "status"
+				r.run(initPromiseCmd...)
+
+				var apiCRD apiextensionsv1.CustomResourceDefinition
+				Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "api.yaml"))), &apiCRD)).To(Succeed())
+				var propertyNames []string
+				var collect func(schema apiextensionsv1.JSONSchemaProps)
+				collect = func(schema apiextensionsv1.JSONSchemaProps) {
+					for name, property := range schema.Properties {
+						propertyNames = append(propertyNames, name)
+						collect(property)
+					}
+					if schema.Items != nil && schema.Items.Schema != nil {
+						collect(*schema.Items.Schema)
+					}
+					if schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil {
+						collect(*schema.AdditionalProperties.Schema)
+					}
+				}
+				collect(*apiCRD.Spec.Versions[0].Schema.OpenAPIV3Schema)
+
+				Expect(propertyNames).To(ConsistOf("apiVersion", "kind", "metadata", "status"))
+			})
+
+			It("errors when the property does not exist", func() {
+				r.exitCode = 1
+				r.flags["--expose"] = "specification"
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say(`cannot expose "specification": not a property of the CRD, available properties are: apiVersion, kind, metadata, spec, status`))
+			})
+		})
+
+		When("--schema-patch is set", func() {
+			getSpecSchema := func() apiextensionsv1.JSONSchemaProps {
+				var apiCRD apiextensionsv1.CustomResourceDefinition
+				Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "api.yaml"))), &apiCRD)).To(Succeed())
+				return apiCRD.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"]
+			}
+
+			It("adds the constraints of the patch to the schema", func() {
+				r.flags["--schema-patch"] = "assets/schema-patch/additive.json"
+				r.run(initPromiseCmd...)
+
+				spec := getSpecSchema()
+				Expect(spec.Properties["teamId"].Type).To(Equal("string"))
+				Expect(spec.Properties["teamId"].Pattern).To(Equal("^[a-z]+$"))
+				Expect(*spec.Properties["teamId"].MaxLength).To(BeEquivalentTo(16))
+				Expect(*spec.Properties["numberOfInstances"].Minimum).To(BeEquivalentTo(0))
+				Expect(*spec.Properties["numberOfInstances"].Maximum).To(BeEquivalentTo(5))
+			})
+
+			It("removes the fields set to null in the patch", func() {
+				r.flags["--schema-patch"] = "assets/schema-patch/nullifying.json"
+				r.run(initPromiseCmd...)
+
+				spec := getSpecSchema()
+				Expect(spec.Properties).NotTo(HaveKey("tls"))
+				Expect(spec.Properties["numberOfInstances"].Type).To(Equal("integer"))
+				Expect(spec.Properties["numberOfInstances"].Minimum).To(BeNil())
+				Expect(spec.Properties).To(HaveKey("teamId"))
+			})
+
+			It("errors when the patch is not valid JSON", func() {
+				patchFile := filepath.Join(workingDir, "patch.json")
+				Expect(os.WriteFile(patchFile, []byte(`{"properties": `), 0644)).To(Succeed())
+				r.exitCode = 1
+				r.flags["--schema-patch"] = patchFile
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say(`invalid --schema-patch .*patch.json: not valid JSON`))
+			})
+
+			It("errors when the patched schema is not structural", func() {
+				patchFile := filepath.Join(workingDir, "patch.json")
+				Expect(os.WriteFile(patchFile, []byte(`{"properties": {"spec": {"type": null}}}`), 0644)).To(Succeed())
+				r.exitCode = 1
+				r.flags["--schema-patch"] = patchFile
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say(`invalid --schema-patch .*patch.json: the schema of version v1Stored of CRD databases.myorg.com is not structural`))
+			})
+
+			It("errors when the patched schema has unknown fields", func() {
+				patchFile := filepath.Join(workingDir, "patch.json")
+				Expect(os.WriteFile(patchFile, []byte(`{"properties": {"spec": {"patern": "^[a-z]+$"}}}`), 0644)).To(Succeed())
+				r.exitCode = 1
+				r.flags["--schema-patch"] = patchFile
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say(`invalid --schema-patch .*patch.json: the patched schema is not an OpenAPI v3 schema: .*patern`))
+			})
+		})
+
+		When("--schema-depth-limit is set", func() {
+			It("preserves unknown fields instead of the nested schema", func() {
+				r.flags["--schema-depth-limit"] = "2"
+				r.run(initPromiseCmd...)
+
+				apiContent, err := os.ReadFile(filepath.Join(workingDir, "api.yaml"))
+				Expect(err).ToNot(HaveOccurred())
+				var apiCRD apiextensionsv1.CustomResourceDefinition
+				Expect(yaml.Unmarshal(apiContent, &apiCRD)).To(Succeed())
+
+				spec := apiCRD.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"]
+				Expect(spec.Properties["numberOfInstances"].Type).To(Equal("integer"))
+				volume := spec.Properties["volume"]
+				Expect(volume.Type).To(Equal("object"))
+				Expect(volume.Properties).To(BeEmpty())
+				Expect(*volume.XPreserveUnknownFields).To(BeTrue())
+			})
+		})
+
+		When("--pipeline-configmap is set", func() {
+			It("ships the file as a ConfigMap dependency mounted into the pipeline", func() {
+				r.flags["--pipeline-configmap"] = "assets/pipeline-config/config.yaml"
+				r.run(initPromiseCmd...)
+
+				configMap := findDependency(getDependencies(workingDir, true), "ConfigMap", "postgresql-pipeline-config")
+				Expect(configMap).NotTo(BeNil())
+				Expect(configMap.GetNamespace()).To(Equal("default"))
+				Expect(configMap.Object["data"]).To(Equal(map[string]any{"config.yaml": cat("assets/pipeline-config/config.yaml")}))
+
+				pipelines := getPipelines(workingDir)
+				Expect(pipelines).To(HaveLen(1))
+				Expect(pipelines[0].Spec.Volumes).To(Equal([]corev1.Volume{{
+					Name: "pipeline-config",
+					VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "postgresql-pipeline-config"},
+					}},
+				}}))
+				Expect(pipelines[0].Spec.Containers[0].VolumeMounts).To(Equal([]corev1.VolumeMount{
+					{Name: "pipeline-config", MountPath: "/etc/pipeline-config", ReadOnly: true},
+				}))
+			})
+
+			It("mounts the ConfigMap in --pipeline-configmap-mount-path", func() {
+				r.flags["--pipeline-configmap"] = "assets/pipeline-config/config.yaml"
+				r.flags["--pipeline-configmap-mount-path"] = "/config/"
+				r.run(initPromiseCmd...)
+
+				Expect(getPipelines(workingDir)[0].Spec.Containers[0].VolumeMounts[0].MountPath).To(Equal("/config"))
+			})
+
+			It("errors when the file does not exist", func() {
+				r.exitCode = 1
+				r.flags["--pipeline-configmap"] = "assets/pipeline-config/missing.yaml"
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say(`failed to read --pipeline-configmap assets/pipeline-config/missing.yaml`))
+			})
+
+			It("errors with --dependencies-only, which generates no pipeline", func() {
+				r.exitCode = 1
+				r.flags["--pipeline-configmap"] = "assets/pipeline-config/config.yaml"
+				r.flags["--dependencies-only"] = ""
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say(`--pipeline-configmap cannot be used with --dependencies-only: there is no pipeline to mount it in`))
+			})
+
+			It("errors on a mount path under /kratix", func() {
+				r.exitCode = 1
+				r.flags["--pipeline-configmap"] = "assets/pipeline-config/config.yaml"
+				r.flags["--pipeline-configmap-mount-path"] = "/kratix/input"
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say(`invalid --pipeline-configmap-mount-path "/kratix/input": must be an absolute path outside of /kratix`))
+			})
+		})
+
+		When("--pipeline-step is set", func() {
+			It("runs every step in order in the configure pipeline", func() {
+				r.run(append(initPromiseCmd,
+					"--pipeline-step", "create-secret=myorg/create-secret:v1",
+					"--pipeline-step", "mapper=myorg/mapper:v1")...)
+
+				workflowContent, err := os.ReadFile(filepath.Join(workingDir, "workflows", "resource", "configure", "workflow.yaml"))
+				Expect(err).ToNot(HaveOccurred())
+				var pipelines []v1alpha1.Pipeline
+				Expect(yaml.Unmarshal(workflowContent, &pipelines)).To(Succeed())
+
+				Expect(pipelines).To(HaveLen(1))
+				containers := pipelines[0].Spec.Containers
+				Expect(containers).To(HaveLen(2))
+				Expect(containers[0].Name).To(Equal("create-secret"))
+				Expect(containers[0].Image).To(Equal("myorg/create-secret:v1"))
+				Expect(containers[1].Name).To(Equal("mapper"))
+				Expect(containers[1].Image).To(Equal("myorg/mapper:v1"))
+				for _, container := range containers {
+					Expect(container.Env).To(ContainElement(corev1.EnvVar{Name: "OPERATOR_KIND", Value: "postgresql"}))
+				}
+			})
+
+			It("keeps the containers in the --pipeline-step order, so Kratix runs them sequentially", func() {
+				r.run(append(initPromiseCmd,
+					"--pipeline-step", "validate=myorg/validate:v1",
+					"--pipeline-step", "create-secret=myorg/create-secret:v1",
+					"--pipeline-step", "apply=myorg/apply:v1")...)
+
+				pipelines := getPipelines(workingDir)
+				Expect(pipelines).To(HaveLen(1))
+				var names []string
+				for _, container := range pipelines[0].Spec.Containers {
+					names = append(names, container.Name)
+				}
+				Expect(names).To(Equal([]string{"validate", "create-secret", "apply"}))
+			})
+
+			DescribeTable("errors on invalid steps",
+				func(expectedErr string, steps ...string) {
+					r.exitCode = 1
+					args := initPromiseCmd
+					for _, step := range steps {
+						args = append(args, "--pipeline-step", step)
+					}
+					session := r.run(args...)
+					Expect(session.Err).To(gbytes.Say(expectedErr))
+				},
+				Entry("without an image", `invalid pipeline step "mapper": expected NAME=IMAGE`, "mapper"),
+				Entry("with an invalid name", `invalid pipeline step "My_Step=img": invalid name`, "My_Step=img"),
+				Entry("with duplicate names", `invalid pipeline step "a=img2": a step named a already exists`, "a=img", "a=img2"),
+			)
+		})
+
+		When("--workflow is set", func() {
+			readWorkflow := func(lifecycle, action string) []v1alpha1.Pipeline {
+				var pipelines []v1alpha1.Pipeline
+				Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "workflows", lifecycle, action, "workflow.yaml"))), &pipelines)).To(Succeed())
+				return pipelines
+			}
+
+			It("generates every workflow with the given image", func() {
+				r.run(append(initPromiseCmd,
+					"--workflow", "resource/delete=myorg/cleanup:v1",
+					"--workflow", "promise/configure=myorg/setup:v1")...)
+
+				configure := readWorkflow("resource", "configure")
+				Expect(configure[0].Spec.Containers[0].Image).To(Equal("ghcr.io/syntasso/kratix-cli/from-api-to-operator:v0.1.0"))
+
+				for _, workflow := range []struct{ lifecycle, action, name, image string }{
+					{"resource", "delete", "instance-delete", "myorg/cleanup:v1"},
+					{"promise", "configure", "promise-configure", "myorg/setup:v1"},
+				} {
+					pipelines := readWorkflow(workflow.lifecycle, workflow.action)
+					Expect(pipelines).To(HaveLen(1))
+					Expect(pipelines[0].Name).To(Equal(workflow.name))
+					Expect(pipelines[0].Spec.Containers).To(HaveLen(1))
+					Expect(pipelines[0].Spec.Containers[0].Image).To(Equal(workflow.image))
+					Expect(pipelines[0].Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "OPERATOR_KIND", Value: "postgresql"}))
+				}
+				Expect(filepath.Join(workingDir, "workflows", "promise", "delete")).NotTo(BeADirectory())
+			})
+
+			It("replaces the default resource configure container", func() {
+				r.run(append(initPromiseCmd, "--workflow", "resource/configure=myorg/mapper:v1")...)
+				Expect(readWorkflow("resource", "configure")[0].Spec.Containers[0].Image).To(Equal("myorg/mapper:v1"))
+			})
+
+			It("adds the workflows to the Promise without --split", func() {
+				delete(r.flags, "--split")
+				r.run(append(initPromiseCmd, "--workflow", "resource/delete=myorg/cleanup:v1")...)
+
+				var promise v1alpha1.Promise
+				Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "promise.yaml"))), &promise)).To(Succeed())
+				Expect(promise.Spec.Workflows.Resource.Configure).To(HaveLen(1))
+				Expect(promise.Spec.Workflows.Resource.Delete).To(HaveLen(1))
+				Expect(promise.Spec.Workflows.Resource.Delete[0].GetName()).To(Equal("instance-delete"))
+			})
+
+			DescribeTable("errors on invalid workflows",
+				func(expectedErr string, args ...string) {
+					r.exitCode = 1
+					session := r.run(append(initPromiseCmd, args...)...)
+					Expect(session.Err).To(gbytes.Say(expectedErr))
+				},
+				Entry("with an unknown action", `invalid workflow "resource/update=img": expected LIFECYCLE/ACTION=IMAGE`, "--workflow", "resource/update=img"),
+				Entry("without an image", `invalid workflow "resource/delete": expected LIFECYCLE/ACTION=IMAGE`, "--workflow", "resource/delete"),
+				Entry("with a duplicate workflow", `invalid workflow "resource/delete=img2": the resource/delete workflow is already set`,
+					"--workflow", "resource/delete=img", "--workflow", "resource/delete=img2"),
+				Entry("with --pipeline-step", "--workflow resource/configure and --pipeline-step cannot be used together",
+					"--workflow", "resource/configure=img", "--pipeline-step", "mapper=img"),
+			)
+		})
+
+		When("--full-lifecycle is set", func() {
+			readWorkflow := func(lifecycle, action string) []v1alpha1.Pipeline {
+				var pipelines []v1alpha1.Pipeline
+				Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "workflows", lifecycle, action, "workflow.yaml"))), &pipelines)).To(Succeed())
+				return pipelines
+			}
+
+			It("generates the four workflows with the default image and the workflow action env", func() {
+				r.run(append(initPromiseCmd, "--full-lifecycle", "--workflow", "promise/delete=myorg/teardown:v1")...)
+
+				for _, workflow := range []struct{ lifecycle, action, name, image string }{
+					{"promise", "configure", "promise-configure", "ghcr.io/syntasso/kratix-cli/from-api-to-operator:v0.1.0"},
+					{"promise", "delete", "promise-delete", "myorg/teardown:v1"},
+					{"resource", "configure", "instance-configure", "ghcr.io/syntasso/kratix-cli/from-api-to-operator:v0.1.0"},
+					{"resource", "delete", "instance-delete", "ghcr.io/syntasso/kratix-cli/from-api-to-operator:v0.1.0"},
+				} {
+					pipelines := readWorkflow(workflow.lifecycle, workflow.action)
+					Expect(pipelines).To(HaveLen(1))
+					Expect(pipelines[0].Name).To(Equal(workflow.name))
+					Expect(pipelines[0].Spec.Containers[0].Image).To(Equal(workflow.image))
+					Expect(pipelines[0].Spec.Containers[0].Env).To(ContainElements(
+						corev1.EnvVar{Name: "OPERATOR_KIND", Value: "postgresql"},
+						corev1.EnvVar{Name: "KRATIX_WORKFLOW_ACTION", Value: workflow.lifecycle + "/" + workflow.action},
+					))
+				}
+			})
+
+			It("adds the workflows to the Promise without --split", func() {
+				delete(r.flags, "--split")
+				r.run(append(initPromiseCmd, "--full-lifecycle")...)
+
+				var promise v1alpha1.Promise
+				Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "promise.yaml"))), &promise)).To(Succeed())
+				Expect(promise.Spec.Workflows.Promise.Configure).To(HaveLen(1))
+				Expect(promise.Spec.Workflows.Promise.Delete).To(HaveLen(1))
+				Expect(promise.Spec.Workflows.Resource.Configure).To(HaveLen(1))
+				Expect(promise.Spec.Workflows.Resource.Delete).To(HaveLen(1))
+			})
+		})
+
+		Describe("--operator-base-spec", func() {
+			var baseSpecPath string
+
+			BeforeEach(func() {
+				baseSpecPath = filepath.Join(workingDir, "base-spec.yaml")
+				r.flags["--operator-base-spec"] = baseSpecPath
+			})
+
+			It("passes the base spec to the pipeline as JSON", func() {
+				Expect(os.WriteFile(baseSpecPath, []byte("numberOfInstances: 2\npostgresql:\n  version: \"15\"\n"), 0644)).To(Succeed())
+				r.run(initPromiseCmd...)
+
+				var pipelines []v1alpha1.Pipeline
+				Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "workflows", "resource", "configure", "workflow.yaml"))), &pipelines)).To(Succeed())
+				Expect(pipelines[0].Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{
+					Name:  "OPERATOR_BASE_SPEC",
+					Value: `{"numberOfInstances":2,"postgresql":{"version":"15"}}`,
+				}))
+			})
+
+			It("errors when the file does not hold an object", func() {
+				Expect(os.WriteFile(baseSpecPath, []byte("- numberOfInstances: 2\n"), 0644)).To(Succeed())
+				r.exitCode = 1
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say("failed to parse operator base spec .*base-spec.yaml: expected an object"))
+			})
+		})
+
+		When("--pipeline-command is set", func() {
+			It("sets the command and args of the resource configure container", func() {
+				r.run(append(initPromiseCmd,
+					"--pipeline-step", "mapper=busybox:1.36",
+					"--pipeline-command", "sh",
+					"--pipeline-arg", "-c",
+					"--pipeline-arg", "cp /scripts/* /kratix/output")...)
+
+				var pipelines []v1alpha1.Pipeline
+				Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "workflows", "resource", "configure", "workflow.yaml"))), &pipelines)).To(Succeed())
+				Expect(pipelines[0].Spec.Containers).To(HaveLen(1))
+				Expect(pipelines[0].Spec.Containers[0].Command).To(Equal([]string{"sh"}))
+				Expect(pipelines[0].Spec.Containers[0].Args).To(Equal([]string{"-c", "cp /scripts/* /kratix/output"}))
+			})
+
+			It("passes the args to the image entrypoint without a command", func() {
+				r.run(append(initPromiseCmd, "--pipeline-arg", "--verbose")...)
+
+				var pipelines []v1alpha1.Pipeline
+				Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "workflows", "resource", "configure", "workflow.yaml"))), &pipelines)).To(Succeed())
+				Expect(pipelines[0].Spec.Containers[0].Command).To(BeEmpty())
+				Expect(pipelines[0].Spec.Containers[0].Args).To(Equal([]string{"--verbose"}))
+			})
+
+			It("errors with more than one pipeline step", func() {
+				r.exitCode = 1
+				session := r.run(append(initPromiseCmd,
+					"--pipeline-command", "sh",
+					"--pipeline-step", "create-secret=myorg/create-secret:v1",
+					"--pipeline-step", "mapper=myorg/mapper:v1")...)
+				Expect(session.Err).To(gbytes.Say("--pipeline-command and --pipeline-arg set the command of a single container: cannot be used with 2 pipeline steps"))
+			})
+		})
+
+		When("--image-pull-policy is set", func() {
+			It("sets the pull policy of every pipeline container", func() {
+				r.run(append(initPromiseCmd,
+					"--image-pull-policy", "Always",
+					"--pipeline-step", "create-secret=myorg/create-secret:v1",
+					"--pipeline-step", "mapper=myorg/mapper:v1")...)
+
+				var pipelines []v1alpha1.Pipeline
+				Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "workflows", "resource", "configure", "workflow.yaml"))), &pipelines)).To(Succeed())
+				Expect(pipelines[0].Spec.Containers).To(HaveLen(2))
+				for _, container := range pipelines[0].Spec.Containers {
+					Expect(container.ImagePullPolicy).To(Equal(corev1.PullAlways))
+				}
+			})
+
+			It("errors on an unknown pull policy", func() {
+				r.exitCode = 1
+				session := r.run(append(initPromiseCmd, "--image-pull-policy", "always")...)
+				Expect(session.Err).To(gbytes.Say(`invalid --image-pull-policy "always": must be one of Always, IfNotPresent, Never`))
+			})
+		})
+
+		When("--image-pull-secret is set", func() {
+			It("adds the secrets to the pipeline", func() {
+				r.run(append(initPromiseCmd, "--image-pull-secret", "registry-creds", "--image-pull-secret", "mirror-creds")...)
+
+				var pipelines []v1alpha1.Pipeline
+				Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "workflows", "resource", "configure", "workflow.yaml"))), &pipelines)).To(Succeed())
+				Expect(pipelines[0].Spec.ImagePullSecrets).To(Equal([]corev1.LocalObjectReference{
+					{Name: "registry-creds"},
+					{Name: "mirror-creds"},
+				}))
+			})
+
+			It("errors on an invalid secret name", func() {
+				r.exitCode = 1
+				session := r.run(append(initPromiseCmd, "--image-pull-secret", "Registry_Creds")...)
+				Expect(session.Err).To(gbytes.Say(`invalid --image-pull-secret "Registry_Creds"`))
+			})
+		})
+
+		When("--verify-apply is set", func() {
+			BeforeEach(func() {
+				r.flags["--verify-apply"] = ""
+			})
+
+			When("there is no kubeconfig", func() {
+				BeforeEach(func() {
+					r.env = map[string]string{"KUBECONFIG": filepath.Join(workingDir, "missing-kubeconfig")}
+				})
+
+				It("skips the verification", func() {
+					session := r.run(initPromiseCmd...)
+					Expect(session.Out).To(gbytes.Say("Promise generated successfully."))
+					Expect(filepath.Join(workingDir, "api.yaml")).To(BeAnExistingFile())
+				})
+
+				It("errors with --require-cluster", func() {
+					r.exitCode = 1
+					r.flags["--require-cluster"] = ""
+					session := r.run(initPromiseCmd...)
+					Expect(session.Err).To(gbytes.Say("failed to load the kubeconfig"))
+				})
+			})
+
+			When("the cluster cannot be reached", func() {
+				It("errors without writing any files", func() {
+					kubeconfigPath := filepath.Join(workingDir, "kubeconfig")
+					Expect(os.WriteFile(kubeconfigPath, []byte(unreachableKubeconfig), 0644)).To(Succeed())
+
+					promiseDir := filepath.Join(workingDir, "promise")
+					r.flags["--dir"] = promiseDir
+					r.env = map[string]string{"KUBECONFIG": kubeconfigPath}
+					r.exitCode = 1
+					r.timeout = 10 * time.Second
+					session := r.run(initPromiseCmd...)
+					Expect(session.Err).To(gbytes.Say("CustomResourceDefinition databases.myorg.com was rejected by the cluster"))
+					Expect(promiseDir).NotTo(BeADirectory())
+				})
+			})
+		})
+
+		When("--profile is set", func() {
+			var profilePath string
+
+			BeforeEach(func() {
+				profilePath = filepath.Join(workingDir, "profile.pprof")
+				r.flags["--profile-output"] = profilePath
+			})
+
+			DescribeTable("writes the profile",
+				func(mode string) {
+					r.flags["--profile"] = mode
+					r.run(initPromiseCmd...)
+					Expect(os.Stat(profilePath)).To(HaveField("Size()", BeNumerically(">", 0)))
+				},
+				Entry("cpu", "cpu"),
+				Entry("mem", "mem"),
+			)
+
+			It("writes the profile when the command fails", func() {
+				r.exitCode = 1
+				r.flags["--profile"] = "mem"
+				r.flags["--api-schema-from"] = "does-not-exist"
+				r.run(initPromiseCmd...)
+				Expect(os.Stat(profilePath)).To(HaveField("Size()", BeNumerically(">", 0)))
+			})
+		})
+
+		When("the CRD is referenced by its kind and group", func() {
+			BeforeEach(func() {
+				r.flags["--api-schema-from"] = "postgresql.acid.zalan.do"
+				r.run(initPromiseCmd...)
+			})
+
+			It("generates the api from the matching CRD", func() {
+				apiContent, err := os.ReadFile(filepath.Join(workingDir, "api.yaml"))
+				Expect(err).ToNot(HaveOccurred())
+
+				var apiCRD apiextensionsv1.CustomResourceDefinition
+				Expect(yaml.Unmarshal(apiContent, &apiCRD)).To(Succeed())
+				expectCRDToMatchOperatorCRD(apiCRD)
+			})
+		})
+
+		When("--kind-case is set", func() {
+			It("normalises the kind and derives lowercase names from it", func() {
+				r.flags["--kind"] = "my-database"
+				r.flags["--kind-case"] = "pascal"
+				r.run(initPromiseCmd...)
+
+				apiContent, err := os.ReadFile(filepath.Join(workingDir, "api.yaml"))
+				Expect(err).ToNot(HaveOccurred())
+				var apiCRD apiextensionsv1.CustomResourceDefinition
+				Expect(yaml.Unmarshal(apiContent, &apiCRD)).To(Succeed())
+				Expect(apiCRD.Spec.Names).To(Equal(apiextensionsv1.CustomResourceDefinitionNames{
+					Kind:     "MyDatabase",
+					Singular: "mydatabase",
+					Plural:   "mydatabases",
+				}))
+			})
+		})
+
+		When("--kind-from-crd is set", func() {
+			BeforeEach(func() {
+				delete(r.flags, "--kind")
+				r.flags["--kind-from-crd"] = ""
+			})
+
+			It("uses the kind of the operator CRD", func() {
+				r.run(initPromiseCmd...)
+
+				apiContent, err := os.ReadFile(filepath.Join(workingDir, "api.yaml"))
+				Expect(err).ToNot(HaveOccurred())
+				var apiCRD apiextensionsv1.CustomResourceDefinition
+				Expect(yaml.Unmarshal(apiContent, &apiCRD)).To(Succeed())
+				Expect(apiCRD.Name).To(Equal("postgresqls.myorg.com"))
+				Expect(apiCRD.Spec.Names).To(Equal(apiextensionsv1.CustomResourceDefinitionNames{
+					Kind:     "postgresql",
+					Singular: "postgresql",
+					Plural:   "postgresqls",
+				}))
+
+				exampleContent, err := os.ReadFile(filepath.Join(workingDir, "example-resource.yaml"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(exampleContent)).To(ContainSubstring("kind: postgresql\n"))
+			})
+
+			It("errors when --kind is also set", func() {
+				r.exitCode = 1
+				r.flags["--kind"] = "database"
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say("--kind and --kind-from-crd cannot be used together"))
+			})
+		})
+
+		When("--group-from-crd is set", func() {
+			BeforeEach(func() {
+				delete(r.flags, "--group")
+				r.flags["--group-from-crd"] = ""
+			})
+
+			It("uses the group of the operator CRD", func() {
+				r.run(initPromiseCmd...)
+
+				apiContent, err := os.ReadFile(filepath.Join(workingDir, "api.yaml"))
+				Expect(err).ToNot(HaveOccurred())
+				var apiCRD apiextensionsv1.CustomResourceDefinition
+				Expect(yaml.Unmarshal(apiContent, &apiCRD)).To(Succeed())
+				Expect(apiCRD.Name).To(Equal("databases.acid.zalan.do"))
+				Expect(apiCRD.Spec.Group).To(Equal("acid.zalan.do"))
+			})
+
+			It("errors when the API would have the name of the operator CRD", func() {
+				r.exitCode = 1
+				delete(r.flags, "--kind")
+				r.flags["--kind-from-crd"] = ""
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say("--group-from-crd generates an API with the same name as the operator CRD postgresqls.acid.zalan.do"))
+			})
+
+			It("errors when --group is also set", func() {
+				r.exitCode = 1
+				r.flags["--group"] = "myorg.com"
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say("--group and --group-from-crd cannot be used together"))
+			})
+		})
+
+		When("--all-owned-crds is set", func() {
+			BeforeEach(func() {
+				delete(r.flags, "--kind")
+				delete(r.flags, "--api-schema-from")
+				r.flags["--operator-manifests"] = "assets/operator-olm"
+				r.flags["--all-owned-crds"] = ""
+			})
+
+			It("generates a Promise per CRD owned by the ClusterServiceVersion", func() {
+				session := r.run("init", "operator-promise", "cache")
+				Expect(session.Out).To(gbytes.Say("Generated 2 Promises, one per owned CRD"))
+
+				for name, kind := range map[string]string{"cache-cache": "Cache", "cache-cachebackup": "CacheBackup"} {
+					promiseDir := filepath.Join(workingDir, name)
+					apiContent, err := os.ReadFile(filepath.Join(promiseDir, "api.yaml"))
+					Expect(err).ToNot(HaveOccurred())
+					var apiCRD apiextensionsv1.CustomResourceDefinition
+					Expect(yaml.Unmarshal(apiContent, &apiCRD)).To(Succeed())
+					Expect(apiCRD.Spec.Group).To(Equal("myorg.com"))
+					Expect(apiCRD.Spec.Names.Kind).To(Equal(kind))
+
+					var dependencies v1alpha1.Dependencies
+					dependenciesContent, err := os.ReadFile(filepath.Join(promiseDir, "dependencies.yaml"))
+					Expect(err).ToNot(HaveOccurred())
+					Expect(yaml.Unmarshal(dependenciesContent, &dependencies)).To(Succeed())
+					Expect(findDependency(dependencies, "Deployment", "cache-operator")).ToNot(BeNil())
+					Expect(findDependency(dependencies, "ClusterRole", "cache-operator")).ToNot(BeNil())
+				}
+			})
+
+			It("errors without a ClusterServiceVersion", func() {
+				r.exitCode = 1
+				r.flags["--operator-manifests"] = "assets/operator"
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say("--all-owned-crds requires a ClusterServiceVersion in the operator manifests"))
+			})
+
+			It("errors when --api-schema-from is also set", func() {
+				r.exitCode = 1
+				r.flags["--api-schema-from"] = "caches.example.com"
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say("--all-owned-crds and --api-schema-from cannot be used together"))
+			})
+		})
+
+		When("--dependencies-only is set", func() {
+			BeforeEach(func() {
+				delete(r.flags, "--api-schema-from")
+				delete(r.flags, "--group")
+				delete(r.flags, "--kind")
+				r.flags["--dependencies-only"] = ""
+			})
+
+			It("only generates the dependencies", func() {
+				session := r.run(initPromiseCmd...)
+				Expect(session.Out).To(gbytes.Say("Dependencies generated successfully."))
+
+				files, err := os.ReadDir(workingDir)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(files).To(HaveLen(1))
+				Expect(files[0].Name()).To(Equal("dependencies.yaml"))
+
+				var dependencies v1alpha1.Dependencies
+				depsContent, err := os.ReadFile(filepath.Join(workingDir, "dependencies.yaml"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(yaml.Unmarshal(depsContent, &dependencies)).To(Succeed())
+				expectDependenciesToMatchOperatorManifests(dependencies)
+			})
+		})
+
+		When("--crd-only is set", func() {
+			BeforeEach(func() {
+				r.flags["--crd-only"] = ""
+			})
+
+			It("only generates the api", func() {
+				session := r.run(initPromiseCmd...)
+				Expect(session.Out).To(gbytes.Say("CRD generated successfully."))
+
+				files, err := os.ReadDir(workingDir)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(files).To(HaveLen(1))
+				Expect(files[0].Name()).To(Equal("api.yaml"))
+
+				var crd apiextensionsv1.CustomResourceDefinition
+				Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "api.yaml"))), &crd)).To(Succeed())
+				Expect(crd.Name).To(Equal("databases.myorg.com"))
+				Expect(crd.Spec.Names.Kind).To(Equal("database"))
+			})
+
+			It("errors with --dependencies-only", func() {
+				r.exitCode = 1
+				r.flags["--dependencies-only"] = ""
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say("--crd-only and --dependencies-only cannot be used together"))
+			})
+		})
+
+		When("--owner is set", func() {
+			It("writes a CODEOWNERS file covering the generated files", func() {
+				delete(r.flags, "--split")
+				r.flags["--owner"] = "myorg/platform"
+				r.run(initPromiseCmd...)
+
+				Expect(cat(filepath.Join(workingDir, "CODEOWNERS"))).To(Equal(
+					"# Generated by kratix init operator-promise\n" +
+						"/CODEOWNERS @myorg/platform\n" +
+						"/README.md @myorg/platform\n" +
+						"/example-resource.yaml @myorg/platform\n" +
+						"/promise.yaml @myorg/platform\n",
+				))
+			})
+
+			It("errors when the owner contains whitespace", func() {
+				r.exitCode = 1
+				r.flags["--owner"] = "my team"
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say(`invalid owner "my team": must not contain whitespace`))
+			})
+		})
+
+		When("--with-network-policy is set", func() {
+			BeforeEach(func() {
+				r.flags["--with-network-policy"] = ""
+				r.flags["--network-policy-ingress-ports"] = "8443,9443"
+				r.run(initPromiseCmd...)
+			})
+
+			It("adds a NetworkPolicy for the operator pods to the dependencies", func() {
+				depsContent, err := os.ReadFile(filepath.Join(workingDir, "dependencies.yaml"))
+				Expect(err).ToNot(HaveOccurred())
+				var dependencies v1alpha1.Dependencies
+				Expect(yaml.Unmarshal(depsContent, &dependencies)).To(Succeed())
+
+				policy := findDependency(dependencies, "NetworkPolicy", "operator-deployment-network-policy")
+				Expect(policy).NotTo(BeNil())
+				Expect(policy.GetNamespace()).To(Equal("defined-namespace"))
+
+				podSelector, _, _ := unstructured.NestedStringMap(policy.Object, "spec", "podSelector", "matchLabels")
+				Expect(podSelector).To(Equal(map[string]string{"app": "operator-deployment"}))
+
+				policyTypes, _, _ := unstructured.NestedStringSlice(policy.Object, "spec", "policyTypes")
+				Expect(policyTypes).To(ConsistOf("Ingress", "Egress"))
+
+				ingress, _, _ := unstructured.NestedSlice(policy.Object, "spec", "ingress")
+				Expect(ingress).To(Equal([]any{
+					map[string]any{
+						"from": []any{map[string]any{
+							"namespaceSelector": map[string]any{"matchLabels": map[string]any{"kubernetes.io/metadata.name": "kratix-platform-system"}},
+							"podSelector":       map[string]any{"matchLabels": map[string]any{"control-plane": "controller-manager"}},
+						}},
+						"ports": []any{
+							map[string]any{"port": int64(8443), "protocol": "TCP"},
+							map[string]any{"port": int64(9443), "protocol": "TCP"},
+						},
+					},
+				}))
+
+				egress, _, _ := unstructured.NestedSlice(policy.Object, "spec", "egress")
+				Expect(egress).To(Equal([]any{
+					map[string]any{
+						"to": []any{map[string]any{
+							"namespaceSelector": map[string]any{"matchLabels": map[string]any{"kubernetes.io/metadata.name": "kube-system"}},
+							"podSelector":       map[string]any{"matchLabels": map[string]any{"k8s-app": "kube-dns"}},
+						}},
+						"ports": []any{
+							map[string]any{"port": int64(53), "protocol": "UDP"},
+							map[string]any{"port": int64(53), "protocol": "TCP"},
+						},
+					},
+					map[string]any{"ports": []any{
+						map[string]any{"port": int64(443), "protocol": "TCP"},
+						map[string]any{"port": int64(6443), "protocol": "TCP"},
+					}},
+				}))
+			})
+		})
+
+		DescribeTable("errors on a flag set without the flag it requires",
+			func(expectedErr string, args ...string) {
+				r.exitCode = 1
+				session := r.run(append(initPromiseCmd, args...)...)
+				Expect(session.Err).To(gbytes.Say(expectedErr))
+				Expect(filepath.Join(workingDir, "promise.yaml")).NotTo(BeAnExistingFile())
+			},
+			Entry("with --network-policy-ingress-ports", "--network-policy-ingress-ports requires --with-network-policy",
+				"--network-policy-ingress-ports", "8443"),
+			Entry("with --network-policy-api-cidr", "--network-policy-api-cidr requires --with-network-policy",
+				"--network-policy-api-cidr", "10.0.0.1/32"),
+			Entry("with --service-monitor-port", "--service-monitor-port requires --with-service-monitor",
+				"--service-monitor-port", "metrics"),
+			Entry("with --observability-dashboard-title", "--observability-dashboard-title requires --with-observability",
+				"--observability-dashboard-title", "Databases"),
+			Entry("with --webhook-service", "--webhook-service requires --with-validating-webhook",
+				"--webhook-service", "platform/database-validator"),
+			Entry("with --pdb-min-available", "--pdb-min-available requires --with-pdb",
+				"--pdb-min-available", "1"),
+		)
+
+		When("--with-pdb is set", func() {
+			readPDB := func() *v1alpha1.Dependency {
+				var dependencies v1alpha1.Dependencies
+				Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "dependencies.yaml"))), &dependencies)).To(Succeed())
+				return findDependency(dependencies, "PodDisruptionBudget", "operator-deployment-pdb")
+			}
+
+			BeforeEach(func() {
+				r.flags["--with-pdb"] = ""
+			})
+
+			It("adds a PodDisruptionBudget for the operator pods, allowing one unavailable pod by default", func() {
+				r.run(initPromiseCmd...)
+
+				pdb := readPDB()
+				Expect(pdb).NotTo(BeNil())
+				Expect(pdb.GetAPIVersion()).To(Equal("policy/v1"))
+				Expect(pdb.GetNamespace()).To(Equal("defined-namespace"))
+				Expect(pdb.Object["spec"]).To(Equal(map[string]any{
+					"maxUnavailable": int64(1),
+					"selector":       map[string]any{"matchLabels": map[string]any{"app": "operator-deployment"}},
+				}))
+			})
+
+			It("sets --pdb-min-available", func() {
+				r.flags["--pdb-min-available"] = "50%"
+				r.run(initPromiseCmd...)
+
+				spec := readPDB().Object["spec"].(map[string]any)
+				Expect(spec).To(HaveKeyWithValue("minAvailable", "50%"))
+				Expect(spec).NotTo(HaveKey("maxUnavailable"))
+			})
+
+			It("warns and adds no PodDisruptionBudget without an operator Deployment", func() {
+				r.flags["--operator-manifests"] = "assets/operator-webhooks"
+				r.flags["--api-schema-from"] = "widgets.example.com"
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say("Warning: no PodDisruptionBudget added: no Deployment found in the operator manifests"))
+				Expect(readPDB()).To(BeNil())
+			})
+
+			DescribeTable("errors on invalid budgets",
+				func(expectedErr string, args ...string) {
+					r.exitCode = 1
+					session := r.run(append(initPromiseCmd, args...)...)
+					Expect(session.Err).To(gbytes.Say(expectedErr))
+				},
+				Entry("with both budgets", "--pdb-min-available and --pdb-max-unavailable cannot be used together",
+					"--pdb-min-available", "1", "--pdb-max-unavailable", "1"),
+				Entry("with a negative number", `invalid --pdb-min-available "-1": must be a number of pods or a percentage`, "--pdb-min-available", "-1"),
+				Entry("with a percentage over 100", `invalid --pdb-max-unavailable "150%": must be a percentage between 0% and 100%`, "--pdb-max-unavailable", "150%"),
+			)
+		})
+
+		When("--with-service-monitor is set", func() {
+			BeforeEach(func() {
+				r.flags["--with-service-monitor"] = ""
+				r.flags["--operator-manifests"] = "assets/operator-metrics"
+				r.flags["--api-schema-from"] = "caches.example.com"
+			})
+
+			readServiceMonitor := func() *v1alpha1.Dependency {
+				var dependencies v1alpha1.Dependencies
+				Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "dependencies.yaml"))), &dependencies)).To(Succeed())
+				return findDependency(dependencies, "ServiceMonitor", "cache-operator-metrics")
+			}
+
+			It("adds a ServiceMonitor scraping the metrics port through the operator Service", func() {
+				r.run(initPromiseCmd...)
+
+				serviceMonitor := readServiceMonitor()
+				Expect(serviceMonitor).NotTo(BeNil())
+				Expect(serviceMonitor.GetAPIVersion()).To(Equal("monitoring.coreos.com/v1"))
+				Expect(serviceMonitor.GetNamespace()).To(Equal("cache-system"))
+				Expect(serviceMonitor.Object["spec"]).To(Equal(map[string]any{
+					"selector":          map[string]any{"matchLabels": map[string]any{"app.kubernetes.io/name": "cache-operator"}},
+					"namespaceSelector": map[string]any{"matchNames": []any{"cache-system"}},
+					"endpoints":         []any{map[string]any{"port": "https-metrics", "interval": "30s"}},
+				}))
+			})
+
+			It("sets the scrape interval", func() {
+				r.flags["--service-monitor-interval"] = "1m"
+				r.run(initPromiseCmd...)
+
+				endpoints, _, _ := unstructured.NestedSlice(readServiceMonitor().Object, "spec", "endpoints")
+				Expect(endpoints).To(Equal([]any{map[string]any{"port": "https-metrics", "interval": "1m"}}))
+			})
+
+			It("warns and adds no ServiceMonitor when no Service exposes the port", func() {
+				r.flags["--service-monitor-port"] = "webhook"
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say("Warning: no Service exposes the metrics port webhook of Deployment cache-operator"))
+				Expect(readServiceMonitor()).To(BeNil())
+			})
+
+			It("warns and adds no ServiceMonitor when there is no metrics port", func() {
+				r.flags["--operator-manifests"] = "assets/operator"
+				r.flags["--api-schema-from"] = "postgresqls.acid.zalan.do"
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say("Warning: no metrics port found on Deployment operator-deployment: set --service-monitor-port to add a ServiceMonitor"))
+			})
+
+			It("errors on an invalid interval", func() {
+				r.exitCode = 1
+				r.flags["--service-monitor-interval"] = "often"
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say(`invalid --service-monitor-interval "often": must be a duration such as 30s or 1m`))
+			})
+		})
+
+		When("--with-observability is set", func() {
+			BeforeEach(func() {
+				r.flags["--with-observability"] = ""
+			})
+
+			readDependencies := func() v1alpha1.Dependencies {
+				var dependencies v1alpha1.Dependencies
+				Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "dependencies.yaml"))), &dependencies)).To(Succeed())
+				return dependencies
+			}
+
+			It("adds a dashboard ConfigMap and alerts for the operator namespace", func() {
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say("Warning: review the alerts of PrometheusRule postgresql-operator-alerts"))
+
+				dependencies := readDependencies()
+				configMap := findDependency(dependencies, "ConfigMap", "postgresql-operator-dashboard")
+				Expect(configMap).NotTo(BeNil())
+				Expect(configMap.GetNamespace()).To(Equal("defined-namespace"))
+				Expect(configMap.GetLabels()).To(HaveKeyWithValue("grafana_dashboard", "1"))
+				dashboardJSON, _, _ := unstructured.NestedString(configMap.Object, "data", "postgresql-operator.json")
+				var dashboard map[string]any
+				Expect(json.Unmarshal([]byte(dashboardJSON), &dashboard)).To(Succeed())
+				Expect(dashboard).To(HaveKeyWithValue("title", "postgresql operator"))
+				Expect(dashboardJSON).To(ContainSubstring(`controller_runtime_reconcile_total{namespace=\"defined-namespace\"}`))
+
+				rule := findDependency(dependencies, "PrometheusRule", "postgresql-operator-alerts")
+				Expect(rule).NotTo(BeNil())
+				groups, _, _ := unstructured.NestedSlice(rule.Object, "spec", "groups")
+				Expect(groups).To(HaveLen(1))
+				rules := groups[0].(map[string]any)["rules"].([]any)
+				Expect(rules).To(HaveLen(2))
+				Expect(rules[0]).To(HaveKeyWithValue("expr", `sum(rate(controller_runtime_reconcile_errors_total{namespace="defined-namespace"}[5m])) > 0`))
+			})
+
+			It("sets the metric prefix and the dashboard title", func() {
+				r.flags["--observability-metric-prefix"] = "postgres_operator"
+				r.flags["--observability-dashboard-title"] = "Postgres"
+				r.run(initPromiseCmd...)
+
+				dependencies := readDependencies()
+				dashboardJSON, _, _ := unstructured.NestedString(findDependency(dependencies, "ConfigMap", "postgresql-operator-dashboard").Object, "data", "postgresql-operator.json")
+				Expect(dashboardJSON).To(ContainSubstring(`"title": "Postgres"`))
+				Expect(dashboardJSON).To(ContainSubstring("postgres_operator_reconcile_total"))
+			})
+
+			It("errors on an invalid metric prefix", func() {
+				r.exitCode = 1
+				r.flags["--observability-metric-prefix"] = "postgres-operator"
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say(`invalid --observability-metric-prefix "postgres-operator": must be a valid Prometheus metric name`))
+			})
+		})
+
+		When("--with-validating-webhook is set", func() {
+			BeforeEach(func() {
+				r.flags["--with-validating-webhook"] = ""
+				r.flags["--webhook-service"] = "platform/database-validator"
+			})
+
+			readWebhook := func() *v1alpha1.Dependency {
+				var dependencies v1alpha1.Dependencies
+				Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "dependencies.yaml"))), &dependencies)).To(Succeed())
+				return findDependency(dependencies, "ValidatingWebhookConfiguration", "databases.myorg.com")
+			}
+
+			It("targets the Promise API with the CA of the bundle", func() {
+				caBundle := selfSignedCertificate()
+				caBundlePath := filepath.Join(workingDir, "ca.pem")
+				Expect(os.WriteFile(caBundlePath, caBundle, 0644)).To(Succeed())
+				r.flags["--webhook-ca-bundle"] = caBundlePath
+				r.flags["--webhook-path"] = "/validate-databases"
+				r.run(initPromiseCmd...)
+
+				webhook := readWebhook()
+				Expect(webhook).NotTo(BeNil())
+				webhooks, _, _ := unstructured.NestedSlice(webhook.Object, "webhooks")
+				Expect(webhooks).To(HaveLen(1))
+				Expect(webhooks[0]).To(SatisfyAll(
+					HaveKeyWithValue("name", "validate.databases.myorg.com"),
+					HaveKeyWithValue("sideEffects", "None"),
+					HaveKeyWithValue("rules", []any{map[string]any{
+						"apiGroups":   []any{"myorg.com"},
+						"apiVersions": []any{"v1Stored"},
+						"resources":   []any{"databases"},
+						"operations":  []any{"CREATE", "UPDATE"},
+					}}),
+					HaveKeyWithValue("clientConfig", map[string]any{
+						"service": map[string]any{
+							"namespace": "platform",
+							"name":      "database-validator",
+							"path":      "/validate-databases",
+							"port":      int64(443),
+						},
+						"caBundle": base64.StdEncoding.EncodeToString(caBundle),
+					}),
+				))
+			})
+
+			It("annotates the configuration for cert-manager with --webhook-inject-ca-from", func() {
+				r.flags["--webhook-inject-ca-from"] = "platform/database-validator-cert"
+				r.run(initPromiseCmd...)
+
+				Expect(readWebhook().GetAnnotations()).To(Equal(map[string]string{
+					"cert-manager.io/inject-ca-from": "platform/database-validator-cert",
+				}))
+			})
+
+			DescribeTable("errors on an invalid CA source",
+				func(expectedError string, args ...string) {
+					r.exitCode = 1
+					session := r.run(append(initPromiseCmd, args...)...)
+					Expect(session.Err).To(gbytes.Say(expectedError))
+				},
+				Entry("without a CA source", "--with-validating-webhook requires --webhook-ca-bundle or --webhook-inject-ca-from"),
+				Entry("with both CA sources", "--webhook-ca-bundle and --webhook-inject-ca-from cannot be used together",
+					"--webhook-ca-bundle", "assets/operator/account.yaml", "--webhook-inject-ca-from", "platform/cert"),
+				Entry("with a CA bundle without certificates", "invalid webhook CA bundle assets/operator/account.yaml: no PEM certificate found",
+					"--webhook-ca-bundle", "assets/operator/account.yaml"),
+				Entry("with an invalid Certificate reference", `invalid --webhook-inject-ca-from "cert": expected NAMESPACE/NAME`,
+					"--webhook-inject-ca-from", "cert"),
+			)
+		})
+
+		Describe("--fill-missing", func() {
+			var workflowPath string
+
+			BeforeEach(func() {
+				workflowPath = filepath.Join(workingDir, "workflows", "resource", "configure", "workflow.yaml")
+				Expect(os.MkdirAll(filepath.Dir(workflowPath), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(workflowPath, []byte("hand-authored"), 0644)).To(Succeed())
+				r.flags["--fill-missing"] = ""
+			})
+
+			It("only writes the files missing from the output directory", func() {
+				session := r.run(initPromiseCmd...)
+				Expect(session.Out).To(gbytes.Say("Skipped %s: file already exists", workflowPath))
+
+				Expect(os.ReadFile(workflowPath)).To(BeEquivalentTo("hand-authored"))
+				Expect(filepath.Join(workingDir, "api.yaml")).To(BeAnExistingFile())
+				Expect(filepath.Join(workingDir, "dependencies.yaml")).To(BeAnExistingFile())
+			})
+
+			When("--force is set", func() {
+				It("overwrites the existing files", func() {
+					r.flags["--force"] = ""
+					session := r.run(initPromiseCmd...)
+					Expect(session.Out).NotTo(gbytes.Say("Skipped"))
+					Expect(os.ReadFile(workflowPath)).NotTo(BeEquivalentTo("hand-authored"))
+				})
+			})
+		})
+
+		Describe("--emit-form-schema", func() {
+			It("writes the flattened fields of the API", func() {
+				formPath := filepath.Join(workingDir, "form.json")
+				r.flags["--emit-form-schema"] = formPath
+				r.run(initPromiseCmd...)
+
+				var form struct {
+					Kind   string           `json:"kind"`
+					Fields []map[string]any `json:"fields"`
+				}
+				Expect(json.Unmarshal([]byte(cat(formPath)), &form)).To(Succeed())
+				Expect(form.Kind).To(Equal("database"))
+				Expect(form.Fields).To(ContainElements(
+					map[string]any{"name": "numberOfInstances", "type": "integer", "required": true},
+					map[string]any{"name": "postgresql.version", "type": "string", "enum": []any{"11", "12", "13", "14", "15", "16"}, "required": true},
+					map[string]any{"name": "volume.size", "type": "string", "required": true},
+				))
+				Expect(form.Fields).NotTo(ContainElement(HaveKeyWithValue("name", "volume")))
+			})
+		})
+
+		Describe("--operator-namespace", func() {
+			It("puts the dependencies without a namespace in the operator namespace", func() {
+				r.flags["--operator-namespace"] = "operator-system"
+				r.run(initPromiseCmd...)
+
+				var dependencies v1alpha1.Dependencies
+				Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "dependencies.yaml"))), &dependencies)).To(Succeed())
+				namespaces := map[string]string{}
+				for _, dep := range dependencies {
+					namespaces[dep.GetName()] = dep.GetNamespace()
+				}
+				Expect(namespaces).To(HaveKeyWithValue("operator-deployment", "defined-namespace"))
+				Expect(namespaces).To(HaveKeyWithValue("operator-sa", "operator-system"))
+				Expect(namespaces).To(HaveKeyWithValue("postgresqls.acid.zalan.do", "operator-system"))
+			})
+
+			It("errors on an invalid namespace", func() {
+				r.exitCode = 1
+				r.flags["--operator-namespace"] = "Operator_System"
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say(`invalid --operator-namespace "Operator_System"`))
+			})
+		})
+
+		Describe("--validate-only", func() {
+			It("checks the promise without writing any file", func() {
+				r.flags["--validate-only"] = ""
+				session := r.run(initPromiseCmd...)
+				Expect(session.Out).To(gbytes.Say("Validation passed: API databases.myorg.com and 7 dependencies; no files written"))
+				Expect(os.ReadDir(workingDir)).To(BeEmpty())
+			})
+
+			It("fails when the generated schema is not structural", func() {
+				r.exitCode = 1
+				r.flags["--validate-only"] = ""
+				r.flags["--set"] = "spec.versions[0].schema.openAPIV3Schema.properties.spec.properties.teamId.type="
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say(`validation failed: the schema of version v1Stored of CRD databases.myorg.com is not structural: .*properties\[teamId\].type: Required value`))
+				Expect(os.ReadDir(workingDir)).To(BeEmpty())
+			})
+		})
+
+		Describe("--with-destination-example", func() {
+			readExample := func() []map[string]any {
+				content := cat(filepath.Join(workingDir, "examples", "destination.yaml"))
+				Expect(content).To(HavePrefix("# EXAMPLE ONLY: this file is not part of the Promise and is never applied"))
+				var objects []map[string]any
+				for _, document := range strings.Split(content, "---\n") {
+					var obj map[string]any
+					Expect(yaml.Unmarshal([]byte(document), &obj)).To(Succeed())
+					objects = append(objects, obj)
+				}
+				return objects
+			}
+
+			It("writes an example Destination and GitStateStore labelled with the destination selectors", func() {
+				r.flags["--with-destination-example"] = ""
+				r.flags["--destination-selector"] = "env=dev"
+				r.run(initPromiseCmd...)
+
+				objects := readExample()
+				Expect(objects).To(HaveLen(2))
+				Expect(objects[0]).To(HaveKeyWithValue("kind", "GitStateStore"))
+				Expect(objects[0]).To(HaveKeyWithValue("metadata", map[string]any{"name": "postgresql-state-store"}))
+				Expect(objects[0]["spec"]).To(HaveKeyWithValue("url", "https://github.com/myorg/kratix-state.git"))
+				Expect(objects[1]).To(HaveKeyWithValue("kind", "Destination"))
+				Expect(objects[1]["metadata"]).To(HaveKeyWithValue("labels", map[string]any{"env": "dev"}))
+				Expect(objects[1]["spec"]).To(HaveKeyWithValue("stateStoreRef", map[string]any{"kind": "GitStateStore", "name": "postgresql-state-store"}))
+
+				Expect(getDependencies(workingDir, true)).NotTo(ContainElement(WithTransform(func(dep v1alpha1.Dependency) string { return dep.GetKind() }, Equal("Destination"))))
+			})
+
+			It("writes a BucketStateStore with --destination-example-store bucket", func() {
+				r.flags["--with-destination-example"] = ""
+				r.flags["--destination-example-store"] = "bucket"
+				r.run(initPromiseCmd...)
+
+				objects := readExample()
+				Expect(objects[0]).To(HaveKeyWithValue("kind", "BucketStateStore"))
+				Expect(objects[0]["spec"]).To(HaveKeyWithValue("bucketName", "kratix"))
+				Expect(objects[1]["spec"]).To(HaveKeyWithValue("stateStoreRef", map[string]any{"kind": "BucketStateStore", "name": "postgresql-state-store"}))
+			})
+
+			It("errors on an unknown store type", func() {
+				r.exitCode = 1
+				r.flags["--destination-example-store"] = "s3"
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say(`invalid --destination-example-store "s3": must be one of git, bucket`))
+			})
+		})
+
+		Describe("--emit-validation-tests", func() {
+			It("writes a Chainsaw test rejecting the requests breaking the schema", func() {
+				r.flags["--emit-validation-tests"] = ""
+				r.run(initPromiseCmd...)
+
+				var test map[string]any
+				Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "tests", "chainsaw-validation-test.yaml"))), &test)).To(Succeed())
+				Expect(test).To(HaveKeyWithValue("metadata", map[string]any{"name": "postgresql-validation"}))
+
+				steps, _, _ := unstructured.NestedSlice(test, "spec", "steps")
+				stepsByName := map[string]map[string]any{}
+				for _, step := range steps {
+					stepsByName[step.(map[string]any)["name"].(string)] = step.(map[string]any)
+				}
+				Expect(stepsByName).To(SatisfyAll(
+					HaveKey("accepts a valid database"),
+					HaveKey("rejects a database where spec.teamId is missing"),
+					HaveKey("rejects a database where spec.numberOfInstances has the wrong type"),
+					HaveKey("rejects a database where spec.numberOfInstances is below the minimum"),
+					HaveKey("rejects a database where spec.connectionPooler.mode is not in the enum"),
+				))
+
+				applyOf := func(name string) map[string]any {
+					apply, _, _ := unstructured.NestedMap(stepsByName[name]["try"].([]any)[0].(map[string]any), "apply")
+					Expect(apply).To(HaveKeyWithValue("dryRun", true))
+					return apply
+				}
+				valid := applyOf("accepts a valid database")
+				Expect(valid).NotTo(HaveKey("expect"))
+
+				missing := applyOf("rejects a database where spec.teamId is missing")
+				Expect(missing["expect"]).To(Equal([]any{map[string]any{"check": map[string]any{"($error != null)": true}}}))
+				spec, _, _ := unstructured.NestedMap(missing, "resource", "spec")
+				Expect(spec).NotTo(HaveKey("teamId"))
+				Expect(spec).To(HaveKey("numberOfInstances"))
+
+				belowMinimum := applyOf("rejects a database where spec.numberOfInstances is below the minimum")
+				numberOfInstances, _, _ := unstructured.NestedFieldNoCopy(belowMinimum, "resource", "spec", "numberOfInstances")
+				Expect(numberOfInstances).To(BeNumerically("==", -1))
+
+				notInEnum := applyOf("rejects a database where spec.connectionPooler.mode is not in the enum")
+				pooler, _, _ := unstructured.NestedMap(notInEnum, "resource", "spec", "connectionPooler")
+				Expect(pooler).To(HaveKeyWithValue("mode", "not-in-enum"))
+			})
+
+			It("writes both Chainsaw tests with --emit-test-resource", func() {
+				r.flags["--emit-validation-tests"] = ""
+				r.flags["--emit-test-resource"] = ""
+				r.run(initPromiseCmd...)
+
+				Expect(filepath.Join(workingDir, "tests", "chainsaw-test.yaml")).To(BeAnExistingFile())
+				Expect(filepath.Join(workingDir, "tests", "chainsaw-validation-test.yaml")).To(BeAnExistingFile())
+			})
+		})
+
+		Describe("--emit-test-resource", func() {
+			It("writes a Chainsaw test requesting an example resource", func() {
+				r.flags["--emit-test-resource"] = ""
+				r.run(initPromiseCmd...)
+
+				var test map[string]any
+				Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "tests", "chainsaw-test.yaml"))), &test)).To(Succeed())
+				Expect(test).To(HaveKeyWithValue("apiVersion", "chainsaw.kyverno.io/v1alpha1"))
+				Expect(test).To(HaveKeyWithValue("kind", "Test"))
+
+				steps, _, _ := unstructured.NestedSlice(test, "spec", "steps")
+				Expect(steps).To(HaveLen(1))
+				try := steps[0].(map[string]any)["try"].([]any)
+				Expect(try).To(HaveLen(2))
+
+				resource, _, _ := unstructured.NestedMap(try[0].(map[string]any), "apply", "resource")
+				Expect(resource).To(HaveKeyWithValue("apiVersion", "myorg.com/v1Stored"))
+				Expect(resource).To(HaveKeyWithValue("kind", "database"))
+				Expect(resource).To(HaveKeyWithValue("metadata", map[string]any{"name": "example-postgresql"}))
+				Expect(resource["spec"]).To(SatisfyAll(
+					HaveKeyWithValue("teamId", "example"),
+					HaveKeyWithValue("numberOfInstances", BeNumerically("==", 0)),
+					HaveKeyWithValue("postgresql", map[string]any{"version": "11"}),
+				))
+
+				status, _, _ := unstructured.NestedMap(try[1].(map[string]any), "assert", "resource", "status")
+				Expect(status).To(Equal(map[string]any{
+					"(conditions[?type == 'Ready'])": []any{map[string]any{"status": "True"}},
+				}))
+			})
+		})
+
+		Describe("--record-source", func() {
+			var promiseDir string
+
+			BeforeEach(func() {
+				promiseDir = filepath.Join(workingDir, "promises", "postgresql")
+				r.flags["--dir"] = promiseDir
+				r.flags["--record-source"] = ""
+				r.flags["--owner"] = "@myorg/platform"
+				r.run(initPromiseCmd...)
+			})
+
+			It("records the operator manifests and the flags relative to the output directory", func() {
+				manifests, err := filepath.Abs("assets/operator")
+				Expect(err).NotTo(HaveOccurred())
+				relativeManifests, err := filepath.Rel(promiseDir, manifests)
+				Expect(err).NotTo(HaveOccurred())
+
+				sourceContent, err := os.ReadFile(filepath.Join(promiseDir, ".kratix-source.yaml"))
+				Expect(err).NotTo(HaveOccurred())
+				var source map[string]any
+				Expect(yaml.Unmarshal(sourceContent, &source)).To(Succeed())
+				Expect(source).To(SatisfyAll(
+					HaveKeyWithValue("command", "init operator-promise"),
+					HaveKeyWithValue("name", "postgresql"),
+					HaveKeyWithValue("args", ConsistOf(
+						"--api-schema-from=postgresqls.acid.zalan.do",
+						"--group=myorg.com",
+						"--kind=database",
+						"--operator-manifests="+relativeManifests,
+						"--owner=@myorg/platform",
+						"--split=true",
+					)),
+				))
+			})
+
+			It("regenerates the recorded promises with kratix regenerate", func() {
+				apiFile := filepath.Join(promiseDir, "api.yaml")
+				Expect(os.WriteFile(apiFile, []byte("edited"), 0644)).To(Succeed())
+
+				session := (&runner{exitCode: 0, timeout: 10 * time.Second}).run("regenerate", "--root", workingDir)
+				Expect(session.Out).To(gbytes.Say("Regenerating postgresql in " + promiseDir))
+				Expect(session.Out).To(gbytes.Say("Regenerated 1 Promises"))
+
+				apiContent, err := os.ReadFile(apiFile)
+				Expect(err).NotTo(HaveOccurred())
+				var apiCRD apiextensionsv1.CustomResourceDefinition
+				Expect(yaml.Unmarshal(apiContent, &apiCRD)).To(Succeed())
+				expectCRDToMatchOperatorCRD(apiCRD)
+				Expect(filepath.Join(promiseDir, "CODEOWNERS")).To(BeAnExistingFile())
+			})
+
+			It("fails when there is no record under the root", func() {
+				session := withExitCode(1).run("regenerate", "--root", filepath.Join(workingDir, "promises", "postgresql", "workflows"))
+				Expect(session.Err).To(gbytes.Say("no .kratix-source.yaml found under"))
+			})
+		})
+
+		Describe("--print-config", func() {
+			It("prints the resolved configuration to stderr", func() {
+				r.flags["--print-config"] = ""
+				session := r.run(initPromiseCmd...)
+
+				var config map[string]any
+				Expect(yaml.Unmarshal(session.Err.Contents(), &config)).To(Succeed())
+				Expect(config).To(SatisfyAll(
+					HaveKeyWithValue("promiseName", "postgresql"),
+					HaveKeyWithValue("crd", "postgresqls.acid.zalan.do"),
+					HaveKeyWithValue("operatorGroup", "acid.zalan.do"),
+					HaveKeyWithValue("operatorVersion", "v1Stored"),
+					HaveKeyWithValue("plural", "databases"),
+					HaveKeyWithValue("outputDir", workingDir),
+					HaveKeyWithValue("images", map[string]any{
+						"resource/configure/from-api-to-operator": "ghcr.io/syntasso/kratix-cli/from-api-to-operator:v0.1.0"}),
+					HaveKeyWithValue("toggles", SatisfyAll(
+						HaveKeyWithValue("split", true),
+						HaveKeyWithValue("force", false),
+						Not(HaveKey("print-config")),
+					)),
+				))
+			})
+		})
+
+		Describe("--dependency-patch", func() {
+			It("applies the patches to the matching dependencies", func() {
+				r.flags["--dependency-patch"] = "assets/dependency-patches/patches.yaml"
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say("Warning: no dependency matches the patch targeting ServiceAccount missing"))
+
+				var dependencies v1alpha1.Dependencies
+				Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "dependencies.yaml"))), &dependencies)).To(Succeed())
+				deployment := findDependency(dependencies, "Deployment", "operator-deployment")
+				replicas, _, _ := unstructured.NestedInt64(deployment.Object, "spec", "replicas")
+				Expect(replicas).To(Equal(int64(3)))
+
+				containers, _, _ := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "containers")
+				Expect(containers).To(HaveLen(1))
+				Expect(containers[0]).To(SatisfyAll(
+					HaveKeyWithValue("image", "busybox"),
+					HaveKeyWithValue("env", []any{map[string]any{"name": "LOG_LEVEL", "value": "debug"}}),
+				))
+			})
+
+			It("errors on a patch without a target kind", func() {
+				patchFile := filepath.Join(workingDir, "patches.yaml")
+				Expect(os.WriteFile(patchFile, []byte("- target: {name: operator-deployment}\n  patch: '[]'\n"), 0644)).To(Succeed())
+				r.exitCode = 1
+				r.flags["--dependency-patch"] = patchFile
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say("invalid dependency patch 0 in .*: target.kind is required"))
+			})
+		})
+
+		Describe("--normalize-images", func() {
+			It("fully qualifies the images of the dependencies and the pipeline", func() {
+				r.flags["--normalize-images"] = ""
+				r.flags["--verbose"] = ""
+				session := r.run(append(initPromiseCmd, "--pipeline-step", "mapper=myorg/mapper:v1")...)
+				Expect(string(session.Err.Contents())).To(SatisfyAll(
+					ContainSubstring("Normalized the image of Deployment operator-deployment: busybox -> docker.io/library/busybox"),
+					ContainSubstring("Normalized the image of pipeline step mapper: myorg/mapper:v1 -> docker.io/myorg/mapper:v1"),
+				))
+
+				var dependencies v1alpha1.Dependencies
+				Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "dependencies.yaml"))), &dependencies)).To(Succeed())
+				deployment := findDependency(dependencies, "Deployment", "operator-deployment")
+				containers, _, _ := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "containers")
+				Expect(containers[0]).To(HaveKeyWithValue("image", "docker.io/library/busybox"))
+
+				Expect(getPipelines(workingDir)[0].Spec.Containers[0].Image).To(Equal("docker.io/myorg/mapper:v1"))
+			})
+		})
+
+		Describe("--order-by-kind", func() {
+			syncWaves := func(dir string) map[string]string {
+				waves := map[string]string{}
+				for _, dep := range getDependencies(dir, true) {
+					waves[dep.GetKind()+" "+dep.GetName()] = dep.GetAnnotations()["argocd.argoproj.io/sync-wave"]
+				}
+				return waves
+			}
+
+			It("annotates the dependencies with the sync wave of their kind", func() {
+				r.flags["--order-by-kind"] = ""
+				r.run(initPromiseCmd...)
+
+				Expect(syncWaves(workingDir)).To(Equal(map[string]string{
+					"ServiceAccount operator-sa":                                    "1",
+					"ServiceAccount subdir-sa":                                      "1",
+					"ClusterRole pod-reader":                                        "1",
+					"Deployment operator-deployment":                                "3",
+					"CustomResourceDefinition postgresteams.acid.zalan.do":          "0",
+					"CustomResourceDefinition postgresqls.acid.zalan.do":            "0",
+					"CustomResourceDefinition operatorconfigurations.acid.zalan.do": "0",
+				}))
+			})
+
+			It("annotates the dependencies generated by the other flags", func() {
+				r.flags["--order-by-kind"] = ""
+				r.flags["--with-network-policy"] = ""
+				r.run(initPromiseCmd...)
+
+				waves := syncWaves(workingDir)
+				Expect(waves).To(HaveLen(8))
+				Expect(waves).To(HaveKeyWithValue("NetworkPolicy operator-deployment-network-policy", "4"))
+			})
+
+			It("reads the waves of --sync-waves over the default ones, keeping existing annotations", func() {
+				patches := filepath.Join(workingDir, "patches.yaml")
+				promiseDir := filepath.Join(workingDir, "promise")
+				Expect(os.WriteFile(patches, []byte(`- target:
+    kind: ClusterRole
+    name: pod-reader
+  patch: |
+    metadata:
+      annotations:
+        argocd.argoproj.io/sync-wave: "-5"
+`), 0644)).To(Succeed())
+				r.flags["--order-by-kind"] = ""
+				r.flags["--sync-waves"] = "assets/sync-waves/waves.yaml"
+				r.flags["--dependency-patch"] = patches
+				r.flags["--dir"] = promiseDir
+				r.run(initPromiseCmd...)
+
+				waves := syncWaves(promiseDir)
+				Expect(waves).To(HaveKeyWithValue("Deployment operator-deployment", "5"))
+				Expect(waves).To(HaveKeyWithValue("ClusterRole pod-reader", "-5"))
+				Expect(waves).To(HaveKeyWithValue("ServiceAccount operator-sa", "1"))
+			})
+
+			It("errors when --sync-waves is set without --order-by-kind", func() {
+				r.exitCode = 1
+				r.flags["--sync-waves"] = "assets/sync-waves/waves.yaml"
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say(`--sync-waves requires --order-by-kind`))
+			})
+
+			It("errors when --sync-waves is not a mapping of kinds to waves", func() {
+				r.exitCode = 1
+				r.flags["--order-by-kind"] = ""
+				r.flags["--sync-waves"] = "assets/operator/account.yaml"
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say(`failed to parse --sync-waves assets/operator/account.yaml: expected a mapping of kinds to integer waves`))
+			})
+		})
+
+		Describe("--verify-dependencies", func() {
+			It("reports all the problems of the dependencies at once", func() {
+				r.exitCode = 1
+				r.flags["--operator-manifests"] = "assets/operator-invalid"
+				r.flags["--dependencies-only"] = ""
+				r.flags["--verify-dependencies"] = ""
+				delete(r.flags, "--api-schema-from")
+				delete(r.flags, "--split")
+				session := r.run(initPromiseCmd...)
+				Eventually(session.Err).Should(gbytes.Say("--verify-dependencies found 4 problems in the dependencies:"))
+				Expect(string(session.Err.Contents())).To(SatisfyAll(
+					ContainSubstring("Deployment Broken_Operator: invalid metadata.name"),
+					ContainSubstring("Deployment Broken_Operator: spec.selector is required"),
+					ContainSubstring("Deployment Broken_Operator: spec.template.spec.containers[0].image is required"),
+					ContainSubstring("ClusterRole system:broken-operator: rules[0].verbs must not be empty"),
+					Not(ContainSubstring("ConfigMap broken-config")),
+				))
+			})
+
+			It("passes on valid dependencies", func() {
+				r.flags["--verify-dependencies"] = ""
+				r.run(append(initPromiseCmd, "--validate-only")...)
+			})
+		})
+
+		Describe("--redact-secrets", func() {
+			BeforeEach(func() {
+				r.flags["--operator-manifests"] = "assets/operator-secrets"
+				r.flags["--api-schema-from"] = "caches.example.com"
+			})
+
+			readSecret := func() *v1alpha1.Dependency {
+				var dependencies v1alpha1.Dependencies
+				Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "dependencies.yaml"))), &dependencies)).To(Succeed())
+				return findDependency(dependencies, "Secret", "cache-operator-credentials")
+			}
+
+			It("blanks the values of the Secrets, keeping their keys", func() {
+				r.flags["--redact-secrets"] = ""
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say("Warning: SECRET REDACTED: the values of the keys password, username of Secret cache-system/cache-operator-credentials were removed"))
+
+				secret := readSecret()
+				Expect(secret.Object["data"]).To(Equal(map[string]any{"password": ""}))
+				Expect(secret.Object["stringData"]).To(Equal(map[string]any{"username": ""}))
+				Expect(secret.GetAnnotations()).To(HaveKey("kratix.io/redacted"))
+			})
+
+			It("warns about the Secrets when not set", func() {
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say("Warning: Secret cache-system/cache-operator-credentials is shipped in the dependencies with the values of its keys password, username; use --redact-secrets to remove them"))
+				Expect(readSecret().Object["data"]).To(Equal(map[string]any{"password": "c3VwZXItc2VjcmV0"}))
+			})
+		})
+
+		Describe("--transform", func() {
+			BeforeEach(func() {
+				r.flags["--operator-manifests"] = "assets/operator-secrets"
+				r.flags["--api-schema-from"] = "caches.example.com"
+			})
+
+			It("pipes the dependencies through the transform", func() {
+				r.flags["--transform"] = "assets/transforms/mirror-images"
+				r.run(initPromiseCmd...)
+
+				var dependencies v1alpha1.Dependencies
+				Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "dependencies.yaml"))), &dependencies)).To(Succeed())
+				Expect(dependencies).To(HaveLen(4))
+				Expect(findDependency(dependencies, "ConfigMap", "added-by-transform")).NotTo(BeNil())
+				deployment := findDependency(dependencies, "Deployment", "cache-operator")
+				containers, _, _ := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "containers")
+				Expect(containers[0]).To(HaveKeyWithValue("image", "mirror.example.org/cache-operator:v0.1.0"))
+			})
+
+			It("fails when the transform exits with a non-zero code", func() {
+				r.exitCode = 1
+				r.flags["--transform"] = "false"
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say(`--transform "false" failed: exit status 1`))
+			})
+
+			It("fails when the output of the transform cannot be parsed", func() {
+				r.exitCode = 1
+				r.flags["--transform"] = "echo not-an-object"
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say(`failed to parse the output of --transform "echo not-an-object"`))
+			})
+		})
+
+		Describe("--layout", func() {
+			It("writes every file to the output directory with flat", func() {
+				r.run(append(initPromiseCmd, "--layout", "flat", "--workflow", "resource/delete=myorg/cleanup:v1")...)
+
+				entries, err := os.ReadDir(workingDir)
+				Expect(err).ToNot(HaveOccurred())
+				var names []string
+				for _, entry := range entries {
+					Expect(entry.IsDir()).To(BeFalse())
+					names = append(names, entry.Name())
+				}
+				Expect(names).To(ConsistOf("README.md", "api.yaml", "dependencies.yaml", "example-resource.yaml",
+					"workflow-resource-configure.yaml", "workflow-resource-delete.yaml"))
+
+				promisePath := filepath.Join(workingDir, "promise.yaml")
+				withExitCode(0).run("build", "promise", "postgresql", "--dir", workingDir, "--output", promisePath)
+				var promise v1alpha1.Promise
+				Expect(yaml.Unmarshal([]byte(cat(promisePath)), &promise)).To(Succeed())
+				Expect(promise.Spec.Workflows.Resource.Configure).To(HaveLen(1))
+				Expect(promise.Spec.Workflows.Resource.Delete).To(HaveLen(1))
+			})
+
+			It("errors on an unknown layout", func() {
+				r.exitCode = 1
+				session := r.run(append(initPromiseCmd, "--layout", "tree")...)
+				Expect(session.Err).To(gbytes.Say(`invalid --layout "tree": must be one of nested, flat`))
+			})
+		})
+
+		Describe("--split-dependencies-by-scope", func() {
+			readKinds := func(fileName string) []string {
+				var dependencies v1alpha1.Dependencies
+				Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, fileName))), &dependencies)).To(Succeed())
+				var kinds []string
+				for _, dep := range dependencies {
+					kinds = append(kinds, dep.GetKind())
+				}
+				return kinds
+			}
+
+			It("writes the dependencies to one file per scope", func() {
+				r.flags["--split-dependencies-by-scope"] = ""
+				r.run(initPromiseCmd...)
+				Expect(filepath.Join(workingDir, "dependencies.yaml")).NotTo(BeAnExistingFile())
+				Expect(readKinds("dependencies-cluster.yaml")).To(Equal([]string{
+					"ClusterRole", "CustomResourceDefinition", "CustomResourceDefinition", "CustomResourceDefinition"}))
+				Expect(readKinds("dependencies-namespaced.yaml")).To(Equal([]string{"ServiceAccount", "Deployment", "ServiceAccount"}))
+			})
+
+			It("errors with --preserve-comments", func() {
+				r.exitCode = 1
+				r.flags["--split-dependencies-by-scope"] = ""
+				r.flags["--preserve-comments"] = ""
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say("--split-dependencies-by-scope and --preserve-comments cannot be used together"))
+			})
+		})
+
+		Describe("--require-scope", func() {
+			DescribeTable("keeps the scope of the operator CRD",
+				func(manifests, crdName string, scope apiextensionsv1.ResourceScope) {
+					r.flags["--operator-manifests"] = manifests
+					r.flags["--api-schema-from"] = crdName
+					r.flags["--require-scope"] = string(scope)
+					r.flags["--verbose"] = ""
+					session := r.run(initPromiseCmd...)
+					Expect(session.Err).To(gbytes.Say("CRD %s is %s-scoped; the generated API keeps the same scope", crdName, scope))
+
+					var crd apiextensionsv1.CustomResourceDefinition
+					Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "api.yaml"))), &crd)).To(Succeed())
+					Expect(crd.Spec.Scope).To(Equal(scope))
+				},
+				Entry("for a Namespaced CRD", "assets/operator", "postgresqls.acid.zalan.do", apiextensionsv1.NamespaceScoped),
+				Entry("for a Cluster CRD", "assets/operator-cluster-scoped", "clusterissuers.example.com", apiextensionsv1.ClusterScoped),
+			)
+
+			DescribeTable("errors when the scope does not match",
+				func(manifests, crdName, requiredScope, expectedErr string) {
+					r.exitCode = 1
+					r.flags["--operator-manifests"] = manifests
+					r.flags["--api-schema-from"] = crdName
+					r.flags["--require-scope"] = requiredScope
+					session := r.run(initPromiseCmd...)
+					Expect(session.Err).To(gbytes.Say(expectedErr))
+					Expect(filepath.Join(workingDir, "api.yaml")).NotTo(BeAnExistingFile())
+				},
+				Entry("for a Namespaced CRD", "assets/operator", "postgresqls.acid.zalan.do", "Cluster",
+					"CRD postgresqls.acid.zalan.do is Namespaced-scoped but --require-scope is Cluster"),
+				Entry("for a Cluster CRD", "assets/operator-cluster-scoped", "clusterissuers.example.com", "Namespaced",
+					"CRD clusterissuers.example.com is Cluster-scoped but --require-scope is Namespaced"),
+			)
+
+			It("errors on an unknown scope", func() {
+				r.exitCode = 1
+				r.flags["--require-scope"] = "namespaced"
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say(`invalid --require-scope "namespaced": must be one of Namespaced, Cluster`))
+			})
+		})
+
+		When("the plural has uppercase letters", func() {
+			BeforeEach(func() {
+				r.flags["--plural"] = "Databases"
+			})
+
+			It("warns that the CRD name is invalid", func() {
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say("Warning: invalid CRD name Databases.myorg.com: must be the lowercase <plural>.<group>; set --plural databases"))
+			})
+
+			It("errors with --strict-crd-name", func() {
+				r.exitCode = 1
+				r.flags["--strict-crd-name"] = ""
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say("Error: invalid CRD name Databases.myorg.com: must be the lowercase <plural>.<group>; set --plural databases"))
+				Expect(filepath.Join(workingDir, "api.yaml")).NotTo(BeAnExistingFile())
+			})
+		})
+
+		When("the operator CRD has kubebuilder annotations", func() {
+			BeforeEach(func() {
+				r.flags["--operator-manifests"] = "assets/operator-annotations"
+				r.flags["--api-schema-from"] = "widgets.example.com"
+			})
+
+			readCRD := func() apiextensionsv1.CustomResourceDefinition {
+				var crd apiextensionsv1.CustomResourceDefinition
+				Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "api.yaml"))), &crd)).To(Succeed())
+				return crd
+			}
+
+			It("removes them from the generated API", func() {
+				r.run(initPromiseCmd...)
+				Expect(readCRD().Annotations).To(Equal(map[string]string{"example.com/owner": "widget-team"}))
+
+				var dependencies v1alpha1.Dependencies
+				Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "dependencies.yaml"))), &dependencies)).To(Succeed())
+				Expect(findDependency(dependencies, "CustomResourceDefinition", "widgets.example.com").GetAnnotations()).To(
+					HaveKeyWithValue("controller-gen.kubebuilder.io/version", "v0.15.0"))
+			})
+
+			It("keeps them with --preserve-crd-annotations", func() {
+				r.flags["--preserve-crd-annotations"] = ""
+				r.run(initPromiseCmd...)
+				Expect(readCRD().Annotations).To(Equal(map[string]string{
+					"controller-gen.kubebuilder.io/version": "v0.15.0",
+					"kubebuilder.io/storage-version":        "v1",
+					"example.com/owner":                     "widget-team",
+				}))
+			})
+		})
+
+		When("the dependencies have annotations", func() {
+			BeforeEach(func() {
+				r.flags["--operator-manifests"] = "assets/operator-dependency-annotations"
+				r.flags["--api-schema-from"] = "widgets.example.com"
+			})
+
+			readDeploymentAnnotations := func() map[string]string {
+				var dependencies v1alpha1.Dependencies
+				Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "dependencies.yaml"))), &dependencies)).To(Succeed())
+				return findDependency(dependencies, "Deployment", "widget-operator").GetAnnotations()
+			}
+
+			It("removes the last-applied-configuration annotation", func() {
+				r.run(initPromiseCmd...)
+				Expect(readDeploymentAnnotations()).To(Equal(map[string]string{
+					"foo.operator.io/owner":    "widget-operator",
+					"foo.operator.io/revision": "3",
+					"example.com/team":         "widgets",
+				}))
+			})
+
+			It("removes the annotations matching --strip-annotation", func() {
+				r.run(append(initPromiseCmd, "--strip-annotation", "foo.operator.io/", "--strip-annotation", "example.com/team")...)
+				Expect(readDeploymentAnnotations()).To(BeEmpty())
+			})
+
+			It("only matches a prefix ending in /", func() {
+				r.run(append(initPromiseCmd, "--strip-annotation", "foo.operator.io")...)
+				Expect(readDeploymentAnnotations()).To(HaveLen(3))
+			})
+		})
+
+		When("--operator-manifests is set multiple times", func() {
+			readDependencies := func() v1alpha1.Dependencies {
+				var dependencies v1alpha1.Dependencies
+				Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "dependencies.yaml"))), &dependencies)).To(Succeed())
+				return dependencies
+			}
+
+			It("combines the sources and picks the CRD from any of them", func() {
+				r.flags["--api-schema-from"] = "caches.example.com"
+				r.run(append(initPromiseCmd, "--operator-manifests", "assets/operator-metrics")...)
+
+				var apiCRD apiextensionsv1.CustomResourceDefinition
+				Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "api.yaml"))), &apiCRD)).To(Succeed())
+				Expect(apiCRD.Spec.Names.Kind).To(Equal("database"))
+
+				dependencies := readDependencies()
+				Expect(dependencies).To(HaveLen(10))
+				Expect(findDependency(dependencies, "Deployment", "operator-deployment")).NotTo(BeNil())
+				Expect(findDependency(dependencies, "Service", "cache-operator-metrics")).NotTo(BeNil())
+			})
+
+			It("keeps the documents found in several sources once", func() {
+				session := r.run(append(initPromiseCmd, "--operator-manifests", "assets/operator")...)
+				Expect(session.Err).NotTo(gbytes.Say("Warning"))
+
+				dependencies := readDependencies()
+				Expect(dependencies).To(HaveLen(7))
+			})
+
+			It("keeps the document of the first source when the sources differ", func() {
+				delete(r.flags, "--operator-manifests")
+				r.flags["--api-schema-from"] = "caches.example.com"
+				session := r.run(append(initPromiseCmd,
+					"--operator-manifests", "assets/operator-metrics",
+					"--operator-manifests", "assets/operator-helm-rendered")...)
+				Expect(session.Err).To(gbytes.Say("Warning: Deployment cache-operator is defined differently by several --operator-manifests: keeping the first one"))
+
+				dependencies := readDependencies()
+				Expect(dependencies).To(HaveLen(4))
+				Expect(findDependency(dependencies, "Job", "cache-operator-migrate")).NotTo(BeNil())
+				Expect(findDependency(dependencies, "Deployment", "cache-operator").GetAnnotations()).NotTo(HaveKey("example.com/team"))
+			})
+		})
+
+		Describe("--max-dependencies-bytes", func() {
+			It("fails without writing any file when the dependencies are larger than the limit", func() {
+				r.exitCode = 1
+				r.flags["--max-dependencies-bytes"] = "1000"
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say(`the 7 dependencies are \d+ bytes, over the --max-dependencies-bytes limit of 1000: .*raise the limit`))
+				Expect(os.ReadDir(workingDir)).To(BeEmpty())
+			})
+
+			It("counts the generated pipeline ConfigMap and webhook", func() {
+				r.exitCode = 1
+				r.flags["--max-dependencies-bytes"] = "1000"
+				r.flags["--pipeline-configmap"] = "assets/pipeline-config/config.yaml"
+				r.flags["--with-validating-webhook"] = ""
+				r.flags["--webhook-service"] = "platform/database-validator"
+				r.flags["--webhook-inject-ca-from"] = "platform/database-validator-cert"
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say(`the 9 dependencies are \d+ bytes, over the --max-dependencies-bytes limit of 1000`))
+				Expect(os.ReadDir(workingDir)).To(BeEmpty())
+			})
+
+			It("disables the check with 0", func() {
+				r.flags["--max-dependencies-bytes"] = "0"
+				r.flags["--dependencies-only"] = ""
+				session := r.run(initPromiseCmd...)
+				Expect(session.Out).To(gbytes.Say("Dependencies generated successfully"))
+			})
+
+			It("errors on a negative limit", func() {
+				r.exitCode = 1
+				r.flags["--max-dependencies-bytes"] = "-1"
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say("invalid --max-dependencies-bytes -1: must not be negative"))
+			})
+		})
+
+		When("the operator manifests are rendered by Helm", func() {
+			BeforeEach(func() {
+				r.flags["--operator-manifests"] = "assets/operator-helm-rendered"
+				r.flags["--api-schema-from"] = "caches.example.com"
+			})
+
+			readDependencies := func() v1alpha1.Dependencies {
+				var dependencies v1alpha1.Dependencies
+				Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "dependencies.yaml"))), &dependencies)).To(Succeed())
+				return dependencies
+			}
+
+			It("generates the API from the CRD under crds/ and removes the Helm annotations", func() {
+				r.run(initPromiseCmd...)
+
+				var apiCRD apiextensionsv1.CustomResourceDefinition
+				Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "api.yaml"))), &apiCRD)).To(Succeed())
+				Expect(apiCRD.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"].Properties).To(HaveKey("size"))
+
+				dependencies := readDependencies()
+				Expect(dependencies).To(HaveLen(3))
+				Expect(findDependency(dependencies, "CustomResourceDefinition", "caches.example.com").GetAnnotations()).To(Equal(map[string]string{
+					"helm.sh/resource-policy": "keep",
+				}))
+				Expect(findDependency(dependencies, "Deployment", "cache-operator").GetAnnotations()).To(Equal(map[string]string{
+					"example.com/team": "caches",
+				}))
+				Expect(findDependency(dependencies, "Job", "cache-operator-migrate").GetAnnotations()).To(BeEmpty())
+			})
+
+			It("keeps the Helm annotations with --preserve-helm-annotations", func() {
+				r.flags["--preserve-helm-annotations"] = ""
+				r.run(initPromiseCmd...)
+
+				dependencies := readDependencies()
+				Expect(findDependency(dependencies, "Deployment", "cache-operator").GetAnnotations()).To(HaveKeyWithValue("meta.helm.sh/release-name", "cache"))
+				Expect(findDependency(dependencies, "Job", "cache-operator-migrate").GetAnnotations()).To(HaveKeyWithValue("helm.sh/hook", "pre-upgrade"))
+			})
+		})
+
+		Describe("--plural-dictionary", func() {
+			var dictionaryPath string
+
+			BeforeEach(func() {
+				dictionaryPath = filepath.Join(workingDir, "plurals.yaml")
+				Expect(os.WriteFile(dictionaryPath, []byte("Database: databasen\nproxy: proxies\n"), 0644)).To(Succeed())
+				r.flags["--plural-dictionary"] = dictionaryPath
+			})
+
+			readCRD := func() apiextensionsv1.CustomResourceDefinition {
+				var crd apiextensionsv1.CustomResourceDefinition
+				Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "api.yaml"))), &crd)).To(Succeed())
+				return crd
+			}
+
+			It("uses the plural of the kind from the dictionary, ignoring case", func() {
+				r.run(initPromiseCmd...)
+				crd := readCRD()
+				Expect(crd.Spec.Names.Plural).To(Equal("databasen"))
+				Expect(crd.Name).To(Equal("databasen.myorg.com"))
+			})
+
+			It("falls back to the default plural for kinds missing from the dictionary", func() {
+				r.flags["--kind"] = "cache"
+				r.run(initPromiseCmd...)
+				Expect(readCRD().Spec.Names.Plural).To(Equal("caches"))
+			})
+
+			It("gives precedence to --plural", func() {
+				r.flags["--plural"] = "dbs"
+				r.run(initPromiseCmd...)
+				Expect(readCRD().Spec.Names.Plural).To(Equal("dbs"))
+			})
 
-	Describe("generating a promise from an operator", func() {
+			It("errors when the dictionary is not a mapping of kinds to plurals", func() {
+				Expect(os.WriteFile(dictionaryPath, []byte("- databases\n"), 0644)).To(Succeed())
+				r.exitCode = 1
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say("failed to parse plural dictionary %s: expected a mapping of kinds to plurals", dictionaryPath))
+			})
+		})
 
-		Describe("the generated files", func() {
-			var generatedFiles []string
+		Describe("--requires", func() {
 			BeforeEach(func() {
-				session = r.run(initPromiseCmd...)
-				fileEntries, err := os.ReadDir(workingDir)
-				generatedFiles = []string{}
-				for _, fileEntry := range fileEntries {
-					generatedFiles = append(generatedFiles, fileEntry.Name())
+				r.flags["--requires"] = "cert-manager:v1.0.0"
+			})
+
+			It("writes the required Promises to required-promises.yaml", func() {
+				r.run(initPromiseCmd...)
+				var requiredPromises []v1alpha1.RequiredPromise
+				Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "required-promises.yaml"))), &requiredPromises)).To(Succeed())
+				Expect(requiredPromises).To(Equal([]v1alpha1.RequiredPromise{{Name: "cert-manager", Version: "v1.0.0"}}))
+
+				builtPromisePath := filepath.Join(workingDir, "built-promise.yaml")
+				withExitCode(0).run("build", "promise", "postgresql", "--dir", workingDir, "--output", builtPromisePath)
+				var promise v1alpha1.Promise
+				Expect(yaml.Unmarshal([]byte(cat(builtPromisePath)), &promise)).To(Succeed())
+				Expect(promise.Spec.RequiredPromises).To(Equal(requiredPromises))
+			})
+
+			It("writes the required Promises to the Promise without --split", func() {
+				delete(r.flags, "--split")
+				r.run(initPromiseCmd...)
+				var promise v1alpha1.Promise
+				Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "promise.yaml"))), &promise)).To(Succeed())
+				Expect(promise.Spec.RequiredPromises).To(Equal([]v1alpha1.RequiredPromise{{Name: "cert-manager", Version: "v1.0.0"}}))
+				Expect(filepath.Join(workingDir, "required-promises.yaml")).NotTo(BeAnExistingFile())
+			})
+
+			It("errors on an invalid required Promise", func() {
+				r.exitCode = 1
+				r.flags["--requires"] = "cert-manager"
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say(`invalid required Promise "cert-manager": expected NAME:VERSION`))
+			})
+		})
+
+		Describe("--crd-api-version", func() {
+			It("sets the apiVersion of the generated CRD", func() {
+				r.flags["--crd-api-version"] = "v1"
+				r.run(initPromiseCmd...)
+
+				var crd apiextensionsv1.CustomResourceDefinition
+				Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "api.yaml"))), &crd)).To(Succeed())
+				Expect(crd.APIVersion).To(Equal("apiextensions.k8s.io/v1"))
+				Expect(crd.Kind).To(Equal("CustomResourceDefinition"))
+			})
+
+			It("errors on an unsupported version", func() {
+				r.exitCode = 1
+				r.flags["--crd-api-version"] = "v1beta1"
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say(`invalid --crd-api-version "v1beta1": must be one of v1`))
+				Expect(filepath.Join(workingDir, "api.yaml")).NotTo(BeAnExistingFile())
+			})
+		})
+
+		Describe("--write-concurrency", func() {
+			It("writes the same files as the sequential writer", func() {
+				r.run(initPromiseCmd...)
+				sequential := cat(filepath.Join(workingDir, "api.yaml"))
+				Expect(os.RemoveAll(workingDir)).To(Succeed())
+
+				r.flags["--write-concurrency"] = "4"
+				r.run(initPromiseCmd...)
+				Expect(cat(filepath.Join(workingDir, "api.yaml"))).To(Equal(sequential))
+				for _, file := range []string{"dependencies.yaml", "example-resource.yaml", "README.md",
+					filepath.Join("workflows", "resource", "configure", "workflow.yaml")} {
+					Expect(filepath.Join(workingDir, file)).To(BeAnExistingFile())
 				}
-				Expect(err).ToNot(HaveOccurred())
 			})
 
-			It("includes a dependencies.yaml file with the contents of the operator manifests", func() {
-				Expect(generatedFiles).To(ContainElement("dependencies.yaml"))
+			It("errors when the concurrency is below 1", func() {
+				r.exitCode = 1
+				r.flags["--write-concurrency"] = "0"
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say("invalid --write-concurrency 0: must be at least 1"))
+			})
+		})
 
-				var dependencies v1alpha1.Dependencies
-				depsContent, err := os.ReadFile(filepath.Join(workingDir, "dependencies.yaml"))
+		Describe("--with-conditions", func() {
+			It("adds the standard conditions to the status and enables the status subresource", func() {
+				r.flags["--operator-manifests"] = "assets/operator-anchors"
+				r.flags["--api-schema-from"] = "caches.example.com"
+				r.run(append(initPromiseCmd, "--with-conditions", "--status-field", "phase")...)
+
+				apiContent, err := os.ReadFile(filepath.Join(workingDir, "api.yaml"))
 				Expect(err).ToNot(HaveOccurred())
-				Expect(yaml.Unmarshal(depsContent, &dependencies)).To(Succeed())
-				expectDependenciesToMatchOperatorManifests(dependencies)
+				var apiCRD apiextensionsv1.CustomResourceDefinition
+				Expect(yaml.Unmarshal(apiContent, &apiCRD)).To(Succeed())
+				version := apiCRD.Spec.Versions[0]
+				status := version.Schema.OpenAPIV3Schema.Properties["status"]
+				Expect(status.Properties).To(HaveKey("phase"))
+
+				conditions := status.Properties["conditions"]
+				Expect(conditions.Type).To(Equal("array"))
+				Expect(conditions.XListMapKeys).To(Equal([]string{"type"}))
+				condition := conditions.Items.Schema
+				Expect(condition.Required).To(ConsistOf("type", "status", "reason", "message", "lastTransitionTime"))
+				Expect(condition.Properties).To(HaveLen(6))
+				Expect(condition.Properties["lastTransitionTime"].Format).To(Equal("date-time"))
+				Expect(condition.Properties["status"].Enum).To(HaveLen(3))
+				Expect(version.Subresources.Status).NotTo(BeNil())
 			})
 
-			It("includes an api.yaml file with the api-schema-from CRD", func() {
-				Expect(generatedFiles).To(ContainElement("api.yaml"))
+			It("errors when the status is a map in the operator CRD", func() {
+				r.exitCode = 1
+				session := r.run(append(initPromiseCmd, "--with-conditions")...)
+				Expect(session.Err).To(gbytes.Say("cannot add status.conditions: status is a map in the operator CRD"))
+			})
+		})
+
+		Describe("--status-field", func() {
+			var apiCRD apiextensionsv1.CustomResourceDefinition
+
+			When("the paths do not exist in the operator CRD", func() {
+				BeforeEach(func() {
+					r.flags["--operator-manifests"] = "assets/operator-anchors"
+					r.flags["--api-schema-from"] = "caches.example.com"
+					r.run(append(initPromiseCmd, "--status-field", "phase", "--status-field", "status.replicas.ready")...)
+
+					apiContent, err := os.ReadFile(filepath.Join(workingDir, "api.yaml"))
+					Expect(err).ToNot(HaveOccurred())
+					Expect(yaml.Unmarshal(apiContent, &apiCRD)).To(Succeed())
+				})
+
+				It("adds them as strings under the status and enables the status subresource", func() {
+					version := apiCRD.Spec.Versions[0]
+					status := version.Schema.OpenAPIV3Schema.Properties["status"]
+					Expect(status.Type).To(Equal("object"))
+					Expect(status.Properties["phase"].Type).To(Equal("string"))
+					Expect(status.Properties["replicas"].Type).To(Equal("object"))
+					Expect(status.Properties["replicas"].Properties["ready"].Type).To(Equal("string"))
+					Expect(version.Subresources).NotTo(BeNil())
+					Expect(version.Subresources.Status).NotTo(BeNil())
+				})
+			})
+
+			When("the status is a map in the operator CRD", func() {
+				BeforeEach(func() {
+					r.run(append(initPromiseCmd, "--status-field", "PostgresClusterStatus")...)
+
+					apiContent, err := os.ReadFile(filepath.Join(workingDir, "api.yaml"))
+					Expect(err).ToNot(HaveOccurred())
+					Expect(yaml.Unmarshal(apiContent, &apiCRD)).To(Succeed())
+				})
+
+				It("keeps the existing status schema", func() {
+					status := apiCRD.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["status"]
+					Expect(status.Properties).To(BeEmpty())
+					Expect(status.AdditionalProperties.Schema.Type).To(Equal("string"))
+				})
+			})
+
+			When("the path is invalid", func() {
+				It("errors", func() {
+					r.exitCode = 1
+					session := r.run(append(initPromiseCmd, "--status-field", "replicas..ready")...)
+					Expect(session.Err).To(gbytes.Say(`invalid status field "replicas..ready": "" is not a valid property name`))
+				})
+			})
+
+			When("the path goes through a non-object property", func() {
+				It("errors", func() {
+					r.exitCode = 1
+					session := r.run(append(initPromiseCmd, "--status-field", "PostgresClusterStatus.phase")...)
+					Expect(session.Err).To(gbytes.Say(`cannot add "phase" to a property of type string`))
+				})
+			})
+		})
+
+		Describe("--print-crd-diff", func() {
+			It("prints the diff between the operator CRD and the generated API", func() {
+				session := r.run(append(initPromiseCmd, "--print-crd-diff")...)
+				Expect(session.Err).To(gbytes.Say(`--- postgresqls.acid.zalan.do\n\+\+\+ api.yaml\n`))
+				Expect(session.Err).To(gbytes.Say(`-  group: acid.zalan.do\n`))
+				Expect(session.Err).To(gbytes.Say(`\+  group: myorg.com\n`))
+				Expect(filepath.Join(workingDir, "api.yaml")).To(BeAnExistingFile())
+			})
+		})
+
+		Describe("--preserve-unknown-fields", func() {
+			It("preserves unknown fields at the root of the schema in api.yaml", func() {
+				r.run(append(initPromiseCmd, "--preserve-unknown-fields")...)
 
 				apiContent, err := os.ReadFile(filepath.Join(workingDir, "api.yaml"))
 				Expect(err).ToNot(HaveOccurred())
+				var apiCRD apiextensionsv1.CustomResourceDefinition
+				Expect(yaml.Unmarshal(apiContent, &apiCRD)).To(Succeed())
+				schema := apiCRD.Spec.Versions[0].Schema.OpenAPIV3Schema
+				Expect(schema.XPreserveUnknownFields).To(HaveValue(BeTrue()))
+				Expect(schema.Properties).To(HaveKey("spec"))
+			})
+		})
+
+		Describe("--property-from", func() {
+			It("adds the schema inferred from the sample to api.yaml", func() {
+				r.run(append(initPromiseCmd, "--property-from", ".spec.monitoring=assets/operator-samples/postgresql.yaml")...)
 
+				apiContent, err := os.ReadFile(filepath.Join(workingDir, "api.yaml"))
+				Expect(err).ToNot(HaveOccurred())
 				var apiCRD apiextensionsv1.CustomResourceDefinition
 				Expect(yaml.Unmarshal(apiContent, &apiCRD)).To(Succeed())
-				expectCRDToMatchOperatorCRD(apiCRD)
+				monitoring := apiCRD.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"].Properties["monitoring"]
+				Expect(monitoring.Type).To(Equal("object"))
+				Expect(monitoring.Properties["enabled"].Type).To(Equal("boolean"))
+				Expect(monitoring.Properties["port"].Type).To(Equal("integer"))
+				Expect(monitoring.Properties["exporters"].Items.Schema.Properties["image"].Type).To(Equal("string"))
 			})
 
-			It("includes a workflow", func() {
-				expectedWorkflowFilepath := filepath.Join(workingDir, "workflows", "resource", "configure", "workflow.yaml")
-				Expect(expectedWorkflowFilepath).To(BeAnExistingFile())
+			When("the sample has no value at the JSONPath", func() {
+				It("errors", func() {
+					r.exitCode = 1
+					session := r.run(append(initPromiseCmd, "--property-from", ".spec.backup=assets/operator-samples/postgresql.yaml")...)
+					Expect(session.Err).To(gbytes.Say(`no value at .spec.backup in assets/operator-samples/postgresql.yaml`))
+				})
+			})
+		})
 
-				workflowContent, err := os.ReadFile(expectedWorkflowFilepath)
+		Describe("--enum", func() {
+			It("restricts the property to the values in api.yaml", func() {
+				r.run(append(initPromiseCmd, "--enum", "spec.teamId=acid,platform", "--enum", "spec.numberOfInstances=1,3")...)
+
+				apiContent, err := os.ReadFile(filepath.Join(workingDir, "api.yaml"))
 				Expect(err).ToNot(HaveOccurred())
+				var apiCRD apiextensionsv1.CustomResourceDefinition
+				Expect(yaml.Unmarshal(apiContent, &apiCRD)).To(Succeed())
+				spec := apiCRD.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"]
+				Expect(spec.Properties["teamId"].Enum).To(Equal([]apiextensionsv1.JSON{{Raw: []byte(`"acid"`)}, {Raw: []byte(`"platform"`)}}))
+				Expect(spec.Properties["numberOfInstances"].Enum).To(Equal([]apiextensionsv1.JSON{{Raw: []byte(`1`)}, {Raw: []byte(`3`)}}))
+			})
 
-				var pipelines []v1alpha1.Pipeline
-				Expect(yaml.Unmarshal(workflowContent, &pipelines)).To(Succeed())
+			When("the property does not exist", func() {
+				It("errors", func() {
+					r.exitCode = 1
+					session := r.run(append(initPromiseCmd, "--enum", "spec.tier=gold")...)
+					Expect(session.Err).To(gbytes.Say(`invalid --enum "spec.tier=gold": tier is not a property of the CRD`))
+				})
+			})
+		})
 
-				expectPipelinesToMatchOperatorPipelines(pipelines)
+		Describe("--printer-column", func() {
+			It("appends the columns to the columns of the operator CRD", func() {
+				r.run(append(initPromiseCmd, "--printer-column", "Instances:.spec.numberOfInstances:integer", "--printer-column", "Owner:.metadata.labels['team']:string")...)
+
+				apiContent, err := os.ReadFile(filepath.Join(workingDir, "api.yaml"))
+				Expect(err).ToNot(HaveOccurred())
+				var apiCRD apiextensionsv1.CustomResourceDefinition
+				Expect(yaml.Unmarshal(apiContent, &apiCRD)).To(Succeed())
+				columns := apiCRD.Spec.Versions[0].AdditionalPrinterColumns
+				Expect(columns).To(HaveLen(10))
+				Expect(columns[0].Name).To(Equal("Team"))
+				Expect(columns[8:]).To(Equal([]apiextensionsv1.CustomResourceColumnDefinition{
+					{Name: "Instances", Type: "integer", JSONPath: ".spec.numberOfInstances"},
+					{Name: "Owner", Type: "string", JSONPath: ".metadata.labels['team']"},
+				}))
 			})
 
-			It("includes an example resource request", func() {
-				Expect(generatedFiles).To(ContainElement("example-resource.yaml"))
-				expectExampleResourceToMatchOperatorResource(workingDir)
+			DescribeTable("errors on invalid columns",
+				func(column, expectedErr string) {
+					r.exitCode = 1
+					session := r.run(append(initPromiseCmd, "--printer-column", column)...)
+					Expect(session.Err).To(gbytes.Say(expectedErr))
+				},
+				Entry("without a type", "Size:.spec.size", `invalid --printer-column "Size:.spec.size": expected NAME:JSONPATH:TYPE`),
+				Entry("with an unknown type", "Size:.spec.size:object", `invalid --printer-column "Size:.spec.size:object": type must be one of integer, number, string, boolean, date`),
+				Entry("with an invalid JSONPath", "Size:.spec[:string", `invalid --printer-column "Size:.spec\[:string": invalid JSONPath`),
+				Entry("with the name of an operator column", "Team:.spec.teamId:string", `invalid --printer-column "Team:.spec.teamId:string": the generated CRD already has a column named Team`),
+			)
+		})
+
+		Describe("--deprecate-version", func() {
+			It("marks the version as deprecated in api.yaml", func() {
+				r.run(append(initPromiseCmd, "--deprecate-version", "v1Stored=use v2 instead")...)
+
+				apiContent, err := os.ReadFile(filepath.Join(workingDir, "api.yaml"))
+				Expect(err).ToNot(HaveOccurred())
+				var apiCRD apiextensionsv1.CustomResourceDefinition
+				Expect(yaml.Unmarshal(apiContent, &apiCRD)).To(Succeed())
+				Expect(apiCRD.Spec.Versions[0].Deprecated).To(BeTrue())
+				Expect(apiCRD.Spec.Versions[0].DeprecationWarning).To(HaveValue(Equal("use v2 instead")))
 			})
 
-			It("includes a README.md file", func() {
-				Expect(generatedFiles).To(ContainElement("README.md"))
-				readmeContents, err := os.ReadFile(filepath.Join(workingDir, "README.md"))
+			It("leaves the warning unset without a message", func() {
+				r.run(append(initPromiseCmd, "--deprecate-version", "v1Stored")...)
+
+				apiContent, err := os.ReadFile(filepath.Join(workingDir, "api.yaml"))
 				Expect(err).ToNot(HaveOccurred())
-				Expect(string(readmeContents)).To(ContainSubstring("init operator-promise postgresql --operator-manifests assets/operator --api-schema-from postgresqls.acid.zalan.do --group myorg.com --kind database"))
+				var apiCRD apiextensionsv1.CustomResourceDefinition
+				Expect(yaml.Unmarshal(apiContent, &apiCRD)).To(Succeed())
+				Expect(apiCRD.Spec.Versions[0].Deprecated).To(BeTrue())
+				Expect(apiCRD.Spec.Versions[0].DeprecationWarning).To(BeNil())
+			})
+
+			When("the version is not kept in the generated CRD", func() {
+				It("errors", func() {
+					r.exitCode = 1
+					session := r.run(append(initPromiseCmd, "--deprecate-version", "v1beta1")...)
+					Expect(session.Err).To(gbytes.Say(`invalid --deprecate-version "v1beta1": version v1beta1 is not kept in the generated CRD, which has versions v1Stored`))
+				})
 			})
 		})
 
-		When("a version is provided", func() {
+		When("the root schema of the CRD has no properties", func() {
+			It("generates the API with the kind, apiVersion and metadata properties", func() {
+				r.flags["--operator-manifests"] = "assets/operator-preserve-unknown"
+				r.flags["--api-schema-from"] = "configs.example.com"
+				r.run(initPromiseCmd...)
+
+				var apiCRD apiextensionsv1.CustomResourceDefinition
+				Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "api.yaml"))), &apiCRD)).To(Succeed())
+				schema := apiCRD.Spec.Versions[0].Schema.OpenAPIV3Schema
+				Expect(*schema.XPreserveUnknownFields).To(BeTrue())
+				Expect(schema.Properties).To(SatisfyAll(HaveLen(3), HaveKey("kind"), HaveKey("apiVersion"), HaveKey("metadata")))
+			})
+		})
+
+		Describe("--wrap-spec", func() {
 			BeforeEach(func() {
-				r.flags["--version"] = "v2beta1"
-				session = r.run(initPromiseCmd...)
+				r.flags["--operator-manifests"] = "assets/operator-root-fields"
+				r.flags["--api-schema-from"] = "settings.example.com"
+				r.flags["--wrap-spec"] = ""
 			})
 
-			It("sets the api version to the provided version", func() {
+			It("moves the root properties of the operator CRD under spec", func() {
+				r.run(initPromiseCmd...)
+
 				apiContent, err := os.ReadFile(filepath.Join(workingDir, "api.yaml"))
 				Expect(err).ToNot(HaveOccurred())
+				var apiCRD apiextensionsv1.CustomResourceDefinition
+				Expect(yaml.Unmarshal(apiContent, &apiCRD)).To(Succeed())
+				schema := apiCRD.Spec.Versions[0].Schema.OpenAPIV3Schema
+				Expect(schema.Properties).NotTo(HaveKey("data"))
+				Expect(schema.Required).To(BeEmpty())
+				Expect(schema.Properties["spec"].Properties).To(SatisfyAll(HaveKey("data"), HaveKey("immutable")))
+				Expect(schema.Properties["spec"].Required).To(Equal([]string{"data"}))
+
+				Expect(cat(filepath.Join(workingDir, "example-resource.yaml"))).To(ContainSubstring("spec:\n  data: '# type object'\n"))
+				Expect(getPipelines(workingDir)[0].Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "OPERATOR_ROOT_FIELDS", Value: "data,immutable"}))
+			})
 
+			It("leaves CRDs with their fields under spec as they are", func() {
+				r.flags["--operator-manifests"] = "assets/operator"
+				r.flags["--api-schema-from"] = "postgresqls.acid.zalan.do"
+				r.run(initPromiseCmd...)
+
+				apiContent, err := os.ReadFile(filepath.Join(workingDir, "api.yaml"))
+				Expect(err).ToNot(HaveOccurred())
 				var apiCRD apiextensionsv1.CustomResourceDefinition
 				Expect(yaml.Unmarshal(apiContent, &apiCRD)).To(Succeed())
+				expectCRDToMatchOperatorCRD(apiCRD)
+				Expect(getPipelines(workingDir)[0].Spec.Containers[0].Env).NotTo(ContainElement(HaveField("Name", "OPERATOR_ROOT_FIELDS")))
+			})
+		})
 
-				Expect(apiCRD.Spec.Versions).To(HaveLen(1))
-				Expect(apiCRD.Spec.Versions[0].Name).To(Equal("v2beta1"))
-				Expect(apiCRD.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["apiVersion"].Enum).To(HaveLen(1))
-				Expect(apiCRD.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["apiVersion"].Enum[0].Raw).To(BeEquivalentTo(`"myorg.com/v2beta1"`))
+		When("the operator manifests contain webhook configurations", func() {
+			BeforeEach(func() {
+				r.flags["--operator-manifests"] = "assets/operator-webhooks"
+				r.flags["--api-schema-from"] = "widgets.example.com"
+			})
+
+			readDependencies := func() v1alpha1.Dependencies {
+				depsContent, err := os.ReadFile(filepath.Join(workingDir, "dependencies.yaml"))
+				Expect(err).ToNot(HaveOccurred())
+				var dependencies v1alpha1.Dependencies
+				Expect(yaml.Unmarshal(depsContent, &dependencies)).To(Succeed())
+				return dependencies
+			}
+
+			It("warns about webhooks calling a Service missing from the dependencies", func() {
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say("Warning: webhook vwidget.example.com in ValidatingWebhookConfiguration widget-validating-webhook calls Service widget-system/widget-validating-webhook, which is not part of the dependencies"))
+				Expect(session.Err).NotTo(gbytes.Say("mwidget.example.com"))
+
+				dependencies := readDependencies()
+				Expect(findDependency(dependencies, "ValidatingWebhookConfiguration", "widget-validating-webhook")).NotTo(BeNil())
+				Expect(findDependency(dependencies, "MutatingWebhookConfiguration", "widget-mutating-webhook")).NotTo(BeNil())
+			})
+
+			It("removes them with --drop-webhook-configs", func() {
+				r.flags["--drop-webhook-configs"] = ""
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).NotTo(gbytes.Say("Warning"))
+				Expect(session.Err).To(gbytes.Say("Dropped MutatingWebhookConfiguration widget-mutating-webhook from the dependencies"))
+				Expect(session.Out).NotTo(gbytes.Say("Dropped"))
+
+				dependencies := readDependencies()
+				Expect(dependencies).To(HaveLen(2))
+				Expect(findDependency(dependencies, "ValidatingWebhookConfiguration", "widget-validating-webhook")).To(BeNil())
+				Expect(findDependency(dependencies, "MutatingWebhookConfiguration", "widget-mutating-webhook")).To(BeNil())
 			})
 		})
 
-		When("there is no matching CRD in the manifests directory", func() {
+		When("the operator manifests use removed apiVersions", func() {
 			BeforeEach(func() {
-				r.flags["--api-schema-from"] = "does-not-exist"
+				r.flags["--operator-manifests"] = "assets/operator-deprecated"
+				r.flags["--api-schema-from"] = "gadgets.example.com"
 			})
 
-			It("returns an error", func() {
+			It("warns about the dependencies with a removed apiVersion", func() {
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say("Warning: ClusterRole gadget-operator has the apiVersion rbac.authorization.k8s.io/v1beta1, which is no longer served by Kubernetes"))
+				Expect(session.Err).To(gbytes.Say("Warning: PodDisruptionBudget gadget-operator has the apiVersion policy/v1beta1"))
+			})
+
+			It("rewrites the apiVersions with --rewrite-apiversion", func() {
+				session := r.run(append(initPromiseCmd,
+					"--rewrite-apiversion", "rbac.authorization.k8s.io/v1beta1=rbac.authorization.k8s.io/v1",
+					"--rewrite-apiversion", "apps/v1beta2=apps/v1",
+					"--rewrite-apiversion", "extensions/v1beta1=networking.k8s.io/v1")...)
+				Expect(session.Err).To(gbytes.Say("Warning: no dependency has the apiVersion extensions/v1beta1; ignoring the rewrite to networking.k8s.io/v1"))
+				Expect(session.Err).To(gbytes.Say("Warning: PodDisruptionBudget gadget-operator has the apiVersion policy/v1beta1"))
+				Expect(session.Err).NotTo(gbytes.Say("ClusterRole"))
+
+				var dependencies v1alpha1.Dependencies
+				Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "dependencies.yaml"))), &dependencies)).To(Succeed())
+				Expect(findDependency(dependencies, "ClusterRole", "gadget-operator").GetAPIVersion()).To(Equal("rbac.authorization.k8s.io/v1"))
+				Expect(findDependency(dependencies, "ClusterRoleBinding", "gadget-operator").GetAPIVersion()).To(Equal("rbac.authorization.k8s.io/v1"))
+				Expect(findDependency(dependencies, "Deployment", "gadget-operator").GetAPIVersion()).To(Equal("apps/v1"))
+				Expect(findDependency(dependencies, "PodDisruptionBudget", "gadget-operator").GetAPIVersion()).To(Equal("policy/v1beta1"))
+			})
+
+			It("errors on an invalid rewrite", func() {
+				r.exitCode = 1
+				session := r.run(append(initPromiseCmd, "--rewrite-apiversion", "apps/v1beta2")...)
+				Expect(session.Err).To(gbytes.Say(`invalid apiVersion rewrite "apps/v1beta2": expected OLD=NEW`))
+			})
+		})
+
+		When("--preserve-comments is set", func() {
+			BeforeEach(func() {
+				r.flags["--operator-manifests"] = "assets/operator-comments"
+				r.flags["--api-schema-from"] = "widgets.example.com"
+				r.flags["--preserve-comments"] = ""
+			})
+
+			It("keeps the comments of the operator manifests in the dependencies", func() {
+				r.run(initPromiseCmd...)
+
+				depsContent := cat(filepath.Join(workingDir, "dependencies.yaml"))
+				Expect(depsContent).To(ContainSubstring("- # Source: https://example.com/widget-operator/v1.2.0/crds.yaml\n  apiVersion: apiextensions.k8s.io/v1\n"))
+				Expect(depsContent).To(ContainSubstring("scope: Namespaced # widgets live in the namespace of their owner\n"))
+				Expect(depsContent).To(ContainSubstring("- # The operator runs in its own namespace\n  apiVersion: apps/v1\n"))
+				Expect(depsContent).To(ContainSubstring("    # a single replica holds the leader lease\n    replicas: 1\n"))
+				Expect(depsContent).To(ContainSubstring("image: example.com/widget-operator:v1.2.0 # pinned by the release process\n"))
+
+				var dependencies v1alpha1.Dependencies
+				Expect(yaml.Unmarshal([]byte(depsContent), &dependencies)).To(Succeed())
+				Expect(dependencies).To(HaveLen(2))
+			})
+
+			It("errors without --split", func() {
+				delete(r.flags, "--split")
 				r.exitCode = 1
 				session := r.run(initPromiseCmd...)
-				Expect(session.Err).To(gbytes.Say(`Error: no CRD found matching name: does-not-exist`))
+				Expect(session.Err).To(gbytes.Say("--preserve-comments requires --split or --dependencies-only"))
+			})
+		})
+
+		When("the operator manifests are in a git repository", func() {
+			BeforeEach(func() {
+				repoDir := filepath.Join(workingDir, "operator-repo")
+				Expect(os.MkdirAll(filepath.Join(repoDir, "config"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(repoDir, "config", "manifests.yaml"), []byte(cat("assets/operator-anchors/manifests.yaml")), 0644)).To(Succeed())
+				for _, args := range [][]string{
+					{"init", "--quiet"},
+					{"add", "."},
+					{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "operator"},
+					{"tag", "v1.0.0"},
+				} {
+					gitCmd := exec.Command("git", args...)
+					gitCmd.Dir = repoDir
+					Expect(gitCmd.Run()).To(Succeed())
+				}
+
+				r.flags["--dir"] = filepath.Join(workingDir, "promise")
+				r.flags["--operator-manifests"] = "git::file://" + repoDir + "//config?ref=v1.0.0"
+				r.flags["--api-schema-from"] = "caches.example.com"
+				r.timeout = 10 * time.Second
+			})
+
+			It("generates the Promise from the manifests at the given ref and path", func() {
+				r.run(initPromiseCmd...)
+
+				depsContent, err := os.ReadFile(filepath.Join(workingDir, "promise", "dependencies.yaml"))
+				Expect(err).ToNot(HaveOccurred())
+				var dependencies v1alpha1.Dependencies
+				Expect(yaml.Unmarshal(depsContent, &dependencies)).To(Succeed())
+				Expect(findDependency(dependencies, "CustomResourceDefinition", "caches.example.com")).NotTo(BeNil())
+				Expect(findDependency(dependencies, "Deployment", "cache-operator")).NotTo(BeNil())
+			})
+		})
+
+		When("the operator manifests use YAML anchors and merge keys", func() {
+			BeforeEach(func() {
+				r.flags["--operator-manifests"] = "assets/operator-anchors"
+				r.flags["--api-schema-from"] = "caches.example.com"
+				r.run(initPromiseCmd...)
+			})
+
+			It("fully materializes the anchored content in the dependencies", func() {
+				depsContent, err := os.ReadFile(filepath.Join(workingDir, "dependencies.yaml"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(depsContent)).NotTo(ContainSubstring("*"))
+				Expect(string(depsContent)).NotTo(ContainSubstring("<<"))
+
+				var dependencies v1alpha1.Dependencies
+				Expect(yaml.Unmarshal(depsContent, &dependencies)).To(Succeed())
+				deployment := findDependency(dependencies, "Deployment", "cache-operator")
+				Expect(deployment).NotTo(BeNil())
+
+				selector, _, _ := unstructured.NestedStringMap(deployment.Object, "spec", "selector", "matchLabels")
+				Expect(selector).To(Equal(map[string]string{"app": "cache-operator"}))
+
+				containers, _, _ := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "containers")
+				Expect(containers).To(HaveLen(2))
+				Expect(containers[1]).To(Equal(map[string]any{
+					"name":  "sidecar",
+					"image": "example.com/cache-operator:v1.0.0",
+					"resources": map[string]any{
+						"limits": map[string]any{"cpu": "100m"},
+					},
+				}))
+			})
+		})
+
+		When("a --post-hook is provided", func() {
+			It("runs the hook in the output directory once the files are written", func() {
+				r.flags["--post-hook"] = "touch hook-was-here"
+				r.run(initPromiseCmd...)
+				Expect(filepath.Join(workingDir, "hook-was-here")).To(BeAnExistingFile())
+				Expect(filepath.Join(workingDir, "api.yaml")).To(BeAnExistingFile())
+			})
+
+			It("runs the hook through the shell, with quoted arguments and the output directory last", func() {
+				r.flags["--post-hook"] = `printf '%s\n' "quoted arg" > "hook was here"`
+				r.run(initPromiseCmd...)
+				outputDir, err := filepath.Abs(workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cat(filepath.Join(workingDir, "hook was here"))).To(Equal("quoted arg\n" + outputDir + "\n"))
+			})
+
+			It("fails when the hook exits with a non-zero code", func() {
+				r.flags["--post-hook"] = "false"
+				r.exitCode = 1
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say(`Error: post-hook "false" failed: exit status 1`))
+			})
+		})
+
+		When("--sign-with is provided", func() {
+			BeforeEach(func() {
+				r.flags["--sign-with"] = "cosign.key"
+			})
+
+			It("signs the digests of the generated files with cosign", func() {
+				session := r.run(initPromiseCmd...)
+				Expect(session.Out).To(gbytes.Say("fake-cosign sign-blob --yes --key cosign.key --output-signature promise.sha256.sig promise.sha256"))
+
+				apiContent, err := os.ReadFile(filepath.Join(workingDir, "api.yaml"))
+				Expect(err).ToNot(HaveOccurred())
+				digests := cat(filepath.Join(workingDir, "promise.sha256"))
+				Expect(digests).To(ContainSubstring(fmt.Sprintf("%x  api.yaml\n", sha256.Sum256(apiContent))))
+				Expect(digests).To(ContainSubstring("  workflows/resource/configure/workflow.yaml\n"))
+				Expect(cat(filepath.Join(workingDir, "promise.sha256.sig"))).To(Equal("fake-signature\n"))
+			})
+
+			It("fails when signing fails", func() {
+				r.exitCode = 1
+				r.env = map[string]string{"FAKE_COSIGN_FAIL": "true"}
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say("failed to sign the Promise with cosign.key: exit status 1"))
+			})
+
+			It("fails without the cosign CLI", func() {
+				r.exitCode = 1
+				r.noPath = true
+				session := r.run(initPromiseCmd...)
+				Expect(session.Err).To(gbytes.Say("--sign-with requires the cosign CLI in PATH"))
 			})
 		})
 	})
@@ -197,6 +2847,22 @@ var _ = Describe("InitOperatorPromise", func() {
 			})
 		})
 
+		It("embeds the same dependencies as the dependencies.yaml written with --split", func() {
+			var promise v1alpha1.Promise
+			Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "promise.yaml"))), &promise)).To(Succeed())
+
+			splitDir := filepath.Join(workingDir, "split")
+			r.flags["--dir"] = splitDir
+			r.run(append(initPromiseCmd, "--split")...)
+			var dependencies v1alpha1.Dependencies
+			Expect(yaml.Unmarshal([]byte(cat(filepath.Join(splitDir, "dependencies.yaml"))), &dependencies)).To(Succeed())
+
+			Expect(promise.Spec.Dependencies).To(HaveLen(len(dependencies)))
+			for i := range dependencies {
+				Expect(promise.Spec.Dependencies[i].Object).To(Equal(dependencies[i].Object))
+			}
+		})
+
 		It("includes an example resource request", func() {
 			Expect(filepath.Join(workingDir, "example-resource.yaml")).To(BeAnExistingFile())
 			expectExampleResourceToMatchOperatorResource(workingDir)
@@ -304,6 +2970,49 @@ func expectDependenciesToMatchOperatorManifests(dependencies v1alpha1.Dependenci
 	))
 }
 
+const unreachableKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: unreachable
+  cluster:
+    server: https://127.0.0.1:1
+contexts:
+- name: unreachable
+  context:
+    cluster: unreachable
+    user: unreachable
+current-context: unreachable
+users:
+- name: unreachable
+  user:
+    token: not-a-token
+`
+
+func selfSignedCertificate() []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "database-validator"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).NotTo(HaveOccurred())
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate})
+}
+
+func findDependency(dependencies v1alpha1.Dependencies, kind, name string) *v1alpha1.Dependency {
+	for i := range dependencies {
+		if dependencies[i].GetKind() == kind && dependencies[i].GetName() == name {
+			return &dependencies[i]
+		}
+	}
+	return nil
+}
+
 func expectPipelinesToMatchOperatorPipelines(pipelines []v1alpha1.Pipeline) {
 	ExpectWithOffset(1, pipelines).To(HaveLen(1))
 	pipeline := pipelines[0]
@@ -37,6 +37,99 @@ var _ = Describe("init", func() {
 		})
 	})
 
+	When("--kratix-api-version is set", func() {
+		It("generates the Promise with the given version", func() {
+			r.run("init", "promise", "postgresql", "--group", "myorg.com", "--kind", "Database", "--kratix-api-version", "v1alpha1")
+			Expect(os.ReadFile(filepath.Join(workingDir, "promise.yaml"))).To(ContainSubstring("apiVersion: platform.kratix.io/v1alpha1\nkind: Promise\n"))
+		})
+
+		It("errors on an unsupported version", func() {
+			session := withExitCode(1).run("init", "promise", "postgresql", "--group", "myorg.com", "--kind", "Database", "--kratix-api-version", "v1")
+			Expect(session.Err).To(gbytes.Say(`invalid --kratix-api-version "v1": must be one of v1alpha1`))
+		})
+	})
+
+	Describe("--gvk", func() {
+		readCRD := func() apiextensionsv1.CustomResourceDefinition {
+			var promise v1alpha1.Promise
+			Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "promise.yaml"))), &promise)).To(Succeed())
+			crd, err := promise.GetAPIAsCRD()
+			Expect(err).NotTo(HaveOccurred())
+			return *crd
+		}
+
+		It("sets the group, version and kind at once", func() {
+			r.run("init", "promise", "postgresql", "--gvk", "myorg.com/v1beta1/Database")
+			crd := readCRD()
+			Expect(crd.Spec.Group).To(Equal("myorg.com"))
+			Expect(crd.Spec.Versions[0].Name).To(Equal("v1beta1"))
+			Expect(crd.Spec.Names.Kind).To(Equal("Database"))
+			Expect(crd.Spec.Names.Plural).To(Equal("databases"))
+		})
+
+		It("takes the individual flags over the parts of --gvk", func() {
+			r.run("init", "promise", "postgresql", "--gvk", "myorg.com/v1beta1/Database", "--version", "v2")
+			crd := readCRD()
+			Expect(crd.Spec.Group).To(Equal("myorg.com"))
+			Expect(crd.Spec.Versions[0].Name).To(Equal("v2"))
+		})
+
+		DescribeTable("errors on a malformed --gvk",
+			func(value string) {
+				session := withExitCode(1).run("init", "promise", "postgresql", "--gvk", value)
+				Expect(session.Err).To(gbytes.Say(`invalid --gvk %q: expected GROUP/VERSION/KIND, e.g. myorg.com/v1/Database`, value))
+			},
+			Entry("with two parts", "myorg.com/Database"),
+			Entry("with an empty part", "myorg.com//Database"),
+			Entry("with four parts", "myorg.com/v1/Database/extra"),
+		)
+	})
+
+	Describe("the config file", func() {
+		readCRD := func() apiextensionsv1.CustomResourceDefinition {
+			var promise v1alpha1.Promise
+			Expect(yaml.Unmarshal([]byte(cat(filepath.Join(workingDir, "promise.yaml"))), &promise)).To(Succeed())
+			crd, err := promise.GetAPIAsCRD()
+			Expect(err).NotTo(HaveOccurred())
+			return *crd
+		}
+
+		BeforeEach(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "kratix.yaml"), []byte("group: config.com\nkind: Database\nplural: databasen\n"), 0644)).To(Succeed())
+		})
+
+		It("provides the defaults of the flags from kratix.yaml", func() {
+			r.run("init", "promise", "postgresql")
+			crd := readCRD()
+			Expect(crd.Spec.Group).To(Equal("config.com"))
+			Expect(crd.Spec.Names.Kind).To(Equal("Database"))
+			Expect(crd.Spec.Names.Plural).To(Equal("databasen"))
+		})
+
+		It("takes the flags over the config file", func() {
+			r.run("init", "promise", "postgresql", "--group", "flag.com")
+			crd := readCRD()
+			Expect(crd.Spec.Group).To(Equal("flag.com"))
+			Expect(crd.Spec.Names.Kind).To(Equal("Database"))
+		})
+
+		It("takes the parts of --gvk over the config file", func() {
+			r.run("init", "promise", "postgresql", "--gvk", "gvk.com/v1beta1/Cluster")
+			crd := readCRD()
+			Expect(crd.Spec.Group).To(Equal("gvk.com"))
+			Expect(crd.Spec.Names.Kind).To(Equal("Cluster"))
+		})
+
+		It("reads the config file given with --config", func() {
+			configFile := filepath.Join(workingDir, "team.yaml")
+			Expect(os.WriteFile(configFile, []byte("group: team.com\nkind: Cache\n"), 0644)).To(Succeed())
+			r.run("init", "promise", "redis", "--config", configFile)
+			crd := readCRD()
+			Expect(crd.Spec.Group).To(Equal("team.com"))
+			Expect(crd.Spec.Names.Kind).To(Equal("Cache"))
+		})
+	})
+
 	When("called without a subcommand", func() {
 		It("prints the help", func() {
 			session := r.run("init")
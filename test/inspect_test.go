@@ -0,0 +1,166 @@
+package integration_test
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("inspect", func() {
+	var r *runner
+
+	BeforeEach(func() {
+		r = &runner{exitCode: 0}
+	})
+
+	Describe("crd-diff", func() {
+		It("reports the breaking and compatible changes", func() {
+			session := r.run("inspect", "crd-diff", "--old", "assets/crd-diff/old.yaml", "--new", "assets/crd-diff/new.yaml")
+			Expect(string(session.Out.Contents())).To(SatisfyAll(
+				ContainSubstring("BREAKING   spec.engine: enum value \"mysql\" removed"),
+				ContainSubstring("BREAKING   spec.replicas: type changed from integer to string"),
+				ContainSubstring("BREAKING   spec.storage: property removed"),
+				ContainSubstring("BREAKING   spec.tier: required property added"),
+				ContainSubstring("compatible spec.backup.retention: property added"),
+				ContainSubstring("compatible spec.size: type changed from integer to number"),
+				ContainSubstring("The new CRD has breaking changes"),
+			))
+		})
+
+		It("reports no breaking changes for the same CRD", func() {
+			session := r.run("inspect", "crd-diff", "--old", "assets/crd-diff/old.yaml", "--new", "assets/crd-diff/old.yaml")
+			Expect(string(session.Out.Contents())).To(ContainSubstring("No breaking changes found."))
+		})
+
+		It("outputs the changes as JSON", func() {
+			session := r.run("inspect", "crd-diff", "--old", "assets/crd-diff/old.yaml", "--new", "assets/crd-diff/new.yaml", "--output", "json")
+
+			var diff struct {
+				Breaking bool `json:"breaking"`
+				Changes  []struct {
+					Path     string `json:"path"`
+					Breaking bool   `json:"breaking"`
+					Message  string `json:"message"`
+				} `json:"changes"`
+			}
+			Expect(json.Unmarshal(session.Out.Contents(), &diff)).To(Succeed())
+			Expect(diff.Breaking).To(BeTrue())
+			Expect(diff.Changes).To(HaveLen(6))
+			Expect(diff.Changes[0].Path).To(Equal("spec.backup.retention"))
+			Expect(diff.Changes[0].Breaking).To(BeFalse())
+		})
+
+		It("errors on an unknown output format", func() {
+			session := withExitCode(1).run("inspect", "crd-diff", "--old", "assets/crd-diff/old.yaml", "--new", "assets/crd-diff/new.yaml", "--output", "xml")
+			Expect(string(session.Err.Contents())).To(ContainSubstring(`invalid --output "xml": must be one of text, json`))
+		})
+	})
+
+	Describe("schema", func() {
+		var workingDir string
+
+		BeforeEach(func() {
+			var err error
+			workingDir, err = os.MkdirTemp("", "kratix-test")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(workingDir)).To(Succeed())
+		})
+
+		DescribeTable("prints the JSON Schema of the Promise API",
+			func(initArgs ...string) {
+				r.run(append([]string{"init", "promise", "postgresql", "--group", "myorg.com", "--kind", "Database", "--dir", workingDir}, initArgs...)...)
+				r.run("update", "api", "--dir", workingDir, "--property", "size:integer")
+
+				session := r.run("inspect", "schema", "--dir", workingDir)
+				var jsonSchema map[string]any
+				Expect(json.Unmarshal(session.Out.Contents(), &jsonSchema)).To(Succeed())
+				Expect(jsonSchema).To(HaveKeyWithValue("$schema", "http://json-schema.org/draft-04/schema#"))
+				Expect(jsonSchema).To(HaveKeyWithValue("title", "Database"))
+				Expect(jsonSchema).To(HaveKeyWithValue("properties", HaveKeyWithValue("spec",
+					HaveKeyWithValue("properties", HaveKeyWithValue("size", map[string]any{"type": "integer"})))))
+			},
+			Entry("from promise.yaml"),
+			Entry("from api.yaml", "--split"),
+		)
+
+		It("errors when there is no Promise", func() {
+			session := withExitCode(1).run("inspect", "schema", "--dir", workingDir)
+			Expect(string(session.Err.Contents())).To(ContainSubstring("failed to find api.yaml or promise.yaml in directory"))
+		})
+	})
+
+	Describe("properties", func() {
+		It("lists the properties of the stored version", func() {
+			session := r.run("inspect", "properties", "--dir", "assets/inspect-properties")
+			lines := strings.Split(strings.TrimSpace(string(session.Out.Contents())), "\n")
+			Expect(lines).To(HaveLen(4))
+			Expect(strings.Fields(lines[0])).To(Equal([]string{"NAME", "TYPE", "REQUIRED", "DEFAULT"}))
+			Expect(strings.Fields(lines[1])).To(Equal([]string{"engine", "string", "false", `"postgres"`}))
+			Expect(strings.Fields(lines[2])).To(Equal([]string{"replicas", "integer", "false", "1"}))
+			Expect(strings.Fields(lines[3])).To(Equal([]string{"size", "integer", "true"}))
+		})
+
+		It("outputs the properties as JSON", func() {
+			session := r.run("inspect", "properties", "--dir", "assets/inspect-properties", "--format", "json")
+
+			var properties []map[string]any
+			Expect(json.Unmarshal(session.Out.Contents(), &properties)).To(Succeed())
+			Expect(properties).To(Equal([]map[string]any{
+				{"name": "engine", "type": "string", "required": false, "default": "postgres"},
+				{"name": "replicas", "type": "integer", "required": false, "default": float64(1)},
+				{"name": "size", "type": "integer", "required": true},
+			}))
+		})
+
+		It("errors on an unknown format", func() {
+			session := withExitCode(1).run("inspect", "properties", "--dir", "assets/inspect-properties", "--format", "yaml")
+			Expect(string(session.Err.Contents())).To(ContainSubstring(`invalid --format "yaml": must be one of table, json`))
+		})
+	})
+
+	Describe("pipeline-contract", func() {
+		It("prints the env and files of the operator pipeline", func() {
+			session := r.run("inspect", "pipeline-contract")
+			Expect(string(session.Out.Contents())).To(SatisfyAll(
+				ContainSubstring("Image:  ghcr.io/syntasso/kratix-cli/from-api-to-operator:v0.1.0"),
+				ContainSubstring("Input:  /kratix/input/object.yaml (override with KRATIX_INPUT_FILE)"),
+				ContainSubstring("Output: /kratix/output/object.yaml (override with KRATIX_OUTPUT_FILE)"),
+				MatchRegexp(`OPERATOR_GROUP\s+The group of the operator CRD`),
+			))
+		})
+
+		It("matches the env of the generated pipeline", func() {
+			workingDir, err := os.MkdirTemp("", "kratix-test")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(workingDir)
+			r.run("init", "operator-promise", "postgresql", "--group", "myorg.com", "--kind", "Database", "--dir", workingDir, "--split",
+				"--operator-manifests", "assets/operator", "--api-schema-from", "postgresqls.acid.zalan.do")
+
+			session := r.run("inspect", "pipeline-contract", "--format", "json")
+			var contract struct {
+				Image string `json:"image"`
+				Env   []struct {
+					Name string `json:"name"`
+				} `json:"env"`
+			}
+			Expect(json.Unmarshal(session.Out.Contents(), &contract)).To(Succeed())
+
+			container := getPipelines(workingDir)[0].Spec.Containers[0]
+			Expect(container.Image).To(Equal(contract.Image))
+			var envNames []string
+			for _, env := range container.Env {
+				envNames = append(envNames, env.Name)
+			}
+			Expect(envNames).To(HaveLen(len(contract.Env)))
+			for _, env := range contract.Env {
+				Expect(envNames).To(ContainElement(env.Name))
+			}
+		})
+	})
+})
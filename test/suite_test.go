@@ -35,6 +35,7 @@ type runner struct {
 	flags    map[string]string
 	timeout  time.Duration
 	noPath   bool
+	env      map[string]string
 }
 
 func withExitCode(exitCode int) *runner {
@@ -60,6 +61,9 @@ func (r *runner) run(args ...string) *gexec.Session {
 		cmdPath = ""
 	}
 	cmd.Env = append(cmd.Env, "PATH="+cmdPath)
+	for key, value := range r.env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
 
 	session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
 	ExpectWithOffset(1, err).NotTo(HaveOccurred())
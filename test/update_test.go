@@ -215,6 +215,27 @@ var _ = Describe("update", func() {
 					Expect(props).To(SatisfyAll(HaveKey("keep"), HaveLen(1)))
 					Expect(props["keep"].Type).To(Equal("string"))
 				})
+
+				It("can set arbitrary values in the api", func() {
+					sess := r.run("update", "api", "-p", "port:integer",
+						"--set", "spec.versions[0].schema.openAPIV3Schema.properties.spec.properties.port.maximum=65535",
+						"--set", "spec.versions[0].schema.openAPIV3Schema.properties.spec.properties.port.description=The port")
+					Expect(sess.Out).To(gbytes.Say("Promise api updated"))
+
+					props := getCRDProperties(workingDir, true)
+					Expect(props["port"].Type).To(Equal("integer"))
+					Expect(*props["port"].Maximum).To(Equal(float64(65535)))
+					Expect(props["port"].Description).To(Equal("The port"))
+				})
+
+				It("errors when the --set path is invalid", func() {
+					r.exitCode = 1
+					sess := r.run("update", "api", "--set", "spec.versions[3].name=v2")
+					Expect(sess.Err).To(gbytes.Say(`invalid --set "spec.versions\[3\].name=v2": index 3 out of range at spec.versions`))
+
+					sess = r.run("update", "api", "--set", "spec.versions[0].schema.openAPIV3Schema.maximun=1")
+					Expect(sess.Err).To(gbytes.Say(`unknown field "maximun"`))
+				})
 			})
 		})
 
@@ -311,6 +332,29 @@ var _ = Describe("update", func() {
 				Expect(generatedDeps[2].Object["kind"]).To(Equal("Deployment"))
 			})
 
+			It("rewrites the apiVersions with --rewrite-apiversion", func() {
+				Expect(os.WriteFile(filepath.Join(depDir, "deps.yaml"), slices.Concat(
+					namespaceBytes(ns1),
+					deploymentBytes(deployment1)), 0644)).To(Succeed())
+
+				r.run("update", "dependencies", depDir, "--dir", promiseDir, "--rewrite-apiversion", "apps/v1=apps/v2")
+				generatedDeps := getDependencies(promiseDir, true)
+				Expect(generatedDeps).To(HaveLen(2))
+				Expect(generatedDeps[0].Object["apiVersion"]).To(Equal("v1"))
+				Expect(generatedDeps[1].Object["apiVersion"]).To(Equal("apps/v2"))
+			})
+
+			It("fails when the dependencies are larger than --max-dependencies-bytes", func() {
+				Expect(os.WriteFile(filepath.Join(depDir, "deps.yaml"), slices.Concat(
+					namespaceBytes(ns1),
+					deploymentBytes(deployment1)), 0644)).To(Succeed())
+				before := cat(filepath.Join(promiseDir, "dependencies.yaml"))
+
+				session := withExitCode(1).run("update", "dependencies", depDir, "--dir", promiseDir, "--max-dependencies-bytes", "100")
+				Expect(session.Err).To(gbytes.Say(`the 2 dependencies are \d+ bytes, over the --max-dependencies-bytes limit of 100`))
+				Expect(cat(filepath.Join(promiseDir, "dependencies.yaml"))).To(Equal(before))
+			})
+
 			When("argument is path to a file not a directory", func() {
 				It("works", func() {
 					Expect(os.WriteFile(filepath.Join(depDir, "deps.yaml"), namespaceBytes(ns1), 0644)).To(Succeed())